@@ -19,12 +19,16 @@ package agcache
 
 //CacheInterface 自定义缓存组件接口
 type CacheInterface interface {
+	//Set 设置缓存，key 已存在时覆盖，expireSeconds 小于等于0时永不过期
 	Set(key string, value interface{}, expireSeconds int) (err error)
 
+	//EntryCount 获取未过期的缓存数量
 	EntryCount() (entryCount int64)
 
+	//Get 获取缓存，不存在或已过期时返回错误
 	Get(key string) (value interface{}, err error)
 
+	//Del 删除缓存，key 不存在时返回 false
 	Del(key string) (affected bool)
 
 	Range(f func(key, value interface{}) bool)
@@ -0,0 +1,180 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lru
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/snailzed/agollo/v4/agcache"
+)
+
+//entry 缓存项，expireAt 为零值时表示永不过期
+type entry struct {
+	key      string
+	value    interface{}
+	expireAt time.Time
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return !e.expireAt.IsZero() && !now.Before(e.expireAt)
+}
+
+//Cache 带容量上限及过期时间的 LRU 缓存
+//超过容量时淘汰最久未访问的缓存项，被淘汰或过期的 key 视为不存在
+type Cache struct {
+	maxEntries int
+	items      map[string]*list.Element
+	ll         *list.List
+	lock       sync.Mutex
+	now        func() time.Time
+}
+
+//CreateCache 创建 LRU 缓存，maxEntries 小于等于0时不限制容量
+func CreateCache(maxEntries int) *Cache {
+	return &Cache{
+		maxEntries: maxEntries,
+		items:      make(map[string]*list.Element),
+		ll:         list.New(),
+		now:        time.Now,
+	}
+}
+
+//Set 设置缓存，expireSeconds 小于等于0时永不过期
+func (c *Cache) Set(key string, value interface{}, expireSeconds int) (err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := c.now()
+	var expireAt time.Time
+	if expireSeconds > 0 {
+		expireAt = now.Add(time.Duration(expireSeconds) * time.Second)
+	}
+
+	if element, ok := c.items[key]; ok {
+		e := element.Value.(*entry)
+		e.value = value
+		e.expireAt = expireAt
+		c.ll.MoveToFront(element)
+		return nil
+	}
+
+	c.items[key] = c.ll.PushFront(&entry{key: key, value: value, expireAt: expireAt})
+	if c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+		c.removeExpired(now)
+		for c.ll.Len() > c.maxEntries {
+			c.removeElement(c.ll.Back())
+		}
+	}
+	return nil
+}
+
+//EntryCount 获取未过期的缓存数量
+func (c *Cache) EntryCount() (entryCount int64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.removeExpired(c.now())
+	return int64(c.ll.Len())
+}
+
+//Get 获取缓存，命中时更新访问顺序
+func (c *Cache) Get(key string) (value interface{}, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	element, ok := c.items[key]
+	if !ok {
+		return nil, errors.New("load lru cache fail")
+	}
+	e := element.Value.(*entry)
+	if e.expired(c.now()) {
+		c.removeElement(element)
+		return nil, errors.New("load lru cache fail, key is expired")
+	}
+	c.ll.MoveToFront(element)
+	return e.value, nil
+}
+
+//Del 删除缓存
+func (c *Cache) Del(key string) (affected bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	element, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	c.removeElement(element)
+	return !element.Value.(*entry).expired(c.now())
+}
+
+//Range 按最近访问顺序遍历未过期的缓存，不影响访问顺序
+//遍历的是调用时的快照，f 中可以安全地调用缓存的其他方法
+func (c *Cache) Range(f func(key, value interface{}) bool) {
+	c.lock.Lock()
+	now := c.now()
+	entries := make([]entry, 0, c.ll.Len())
+	for element := c.ll.Front(); element != nil; element = element.Next() {
+		if e := element.Value.(*entry); !e.expired(now) {
+			entries = append(entries, *e)
+		}
+	}
+	c.lock.Unlock()
+
+	for _, e := range entries {
+		if !f(e.key, e.value) {
+			return
+		}
+	}
+}
+
+//Clear 清除所有缓存
+func (c *Cache) Clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.items = make(map[string]*list.Element)
+	c.ll.Init()
+}
+
+func (c *Cache) removeElement(element *list.Element) {
+	c.ll.Remove(element)
+	delete(c.items, element.Value.(*entry).key)
+}
+
+func (c *Cache) removeExpired(now time.Time) {
+	for element := c.ll.Front(); element != nil; {
+		next := element.Next()
+		if element.Value.(*entry).expired(now) {
+			c.removeElement(element)
+		}
+		element = next
+	}
+}
+
+//CacheFactory 构造 LRU 缓存组件工厂类
+type CacheFactory struct {
+	//MaxEntries 每个 namespace 缓存的容量上限，小于等于0时不限制
+	MaxEntries int
+}
+
+//Create 创建 LRU 缓存组件
+func (f *CacheFactory) Create() agcache.CacheInterface {
+	return CreateCache(f.MaxEntries)
+}
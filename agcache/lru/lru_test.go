@@ -0,0 +1,110 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/snailzed/agollo/v4/agcache"
+	"github.com/snailzed/agollo/v4/agollotest/conformance"
+	. "github.com/tevid/gohamcrest"
+)
+
+func TestCacheConformance(t *testing.T) {
+	conformance.TestCache(t, &CacheFactory{})
+	conformance.TestCache(t, &CacheFactory{MaxEntries: 1000})
+}
+
+func TestCacheFactory(t *testing.T) {
+	var factory agcache.CacheFactory = &CacheFactory{MaxEntries: 2}
+	cache := factory.Create()
+	Assert(t, cache, NotNilVal())
+	Assert(t, cache.(*Cache).maxEntries, Equal(2))
+}
+
+func TestCacheEvict(t *testing.T) {
+	cache := CreateCache(2)
+	_ = cache.Set("a", 1, 0)
+	_ = cache.Set("b", 2, 0)
+
+	// 访问 a 后 b 为最久未访问
+	_, err := cache.Get("a")
+	Assert(t, err, NilVal())
+	_ = cache.Set("c", 3, 0)
+
+	Assert(t, cache.EntryCount(), Equal(int64(2)))
+	_, err = cache.Get("b")
+	Assert(t, err, NotNilVal())
+	v, err := cache.Get("a")
+	Assert(t, err, NilVal())
+	Assert(t, v, Equal(1))
+
+	// 覆盖已存在的 key 不触发淘汰
+	_ = cache.Set("c", 4, 0)
+	Assert(t, cache.EntryCount(), Equal(int64(2)))
+	_, err = cache.Get("a")
+	Assert(t, err, NilVal())
+}
+
+func TestCacheEvictExpiredFirst(t *testing.T) {
+	now := time.Now()
+	cache := CreateCache(2)
+	cache.now = func() time.Time {
+		return now
+	}
+	_ = cache.Set("a", 1, 0)
+	_ = cache.Set("b", 2, 10)
+	_, _ = cache.Get("b")
+	_, _ = cache.Get("a")
+
+	now = now.Add(11 * time.Second)
+	_ = cache.Set("c", 3, 0)
+
+	// 已过期的 b 优先被清除，a 保留
+	Assert(t, cache.EntryCount(), Equal(int64(2)))
+	_, err := cache.Get("a")
+	Assert(t, err, NilVal())
+	_, err = cache.Get("c")
+	Assert(t, err, NilVal())
+}
+
+func TestCacheExpire(t *testing.T) {
+	now := time.Now()
+	cache := CreateCache(0)
+	cache.now = func() time.Time {
+		return now
+	}
+	_ = cache.Set("a", 1, 10)
+	_ = cache.Set("b", 2, 0)
+
+	now = now.Add(9 * time.Second)
+	_, err := cache.Get("a")
+	Assert(t, err, NilVal())
+
+	now = now.Add(time.Second)
+	_, err = cache.Get("a")
+	Assert(t, err, NotNilVal())
+	Assert(t, cache.EntryCount(), Equal(int64(1)))
+
+	// 重新设置后使用新的过期时间
+	_ = cache.Set("b", 3, 1)
+	now = now.Add(time.Second)
+	Assert(t, cache.Del("b"), Equal(false))
+	Assert(t, cache.EntryCount(), Equal(int64(0)))
+}
@@ -21,25 +21,51 @@ import (
 	"errors"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/snailzed/agollo/v4/agcache"
 )
 
+//entry 缓存项，expireAt 为零值时表示永不过期
+type entry struct {
+	value    interface{}
+	expireAt time.Time
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return !e.expireAt.IsZero() && !now.Before(e.expireAt)
+}
+
 //DefaultCache 默认缓存
 type DefaultCache struct {
 	defaultCache sync.Map
 	count        int64
+	// 串行化写操作，保证 count 与实际数量一致
+	lock sync.Mutex
+	// 是否存在设置了过期时间的缓存项
+	expirable int32
 }
 
-//Set 获取缓存
+//Set 设置缓存，expireSeconds 小于等于0时永不过期
 func (d *DefaultCache) Set(key string, value interface{}, expireSeconds int) (err error) {
-	d.defaultCache.Store(key, value)
-	atomic.AddInt64(&d.count, int64(1))
+	e := &entry{value: value}
+	if expireSeconds > 0 {
+		e.expireAt = time.Now().Add(time.Duration(expireSeconds) * time.Second)
+		atomic.StoreInt32(&d.expirable, 1)
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if _, loaded := d.defaultCache.Load(key); !loaded {
+		atomic.AddInt64(&d.count, int64(1))
+	}
+	d.defaultCache.Store(key, e)
 	return nil
 }
 
 //EntryCount 获取实体数量
 func (d *DefaultCache) EntryCount() (entryCount int64) {
+	d.removeExpired()
 	c := atomic.LoadInt64(&d.count)
 	return c
 }
@@ -50,27 +76,74 @@ func (d *DefaultCache) Get(key string) (value interface{}, err error) {
 	if !ok {
 		return nil, errors.New("load default cache fail")
 	}
-	return v, nil
+	e := v.(*entry)
+	if e.expired(time.Now()) {
+		d.delete(key, e)
+		return nil, errors.New("load default cache fail, key is expired")
+	}
+	return e.value, nil
 }
 
 //Range 遍历缓存
 func (d *DefaultCache) Range(f func(key, value interface{}) bool) {
-	d.defaultCache.Range(f)
+	now := time.Now()
+	d.defaultCache.Range(func(key, value interface{}) bool {
+		e := value.(*entry)
+		if e.expired(now) {
+			return true
+		}
+		return f(key, e.value)
+	})
 }
 
 //Del 删除缓存
 func (d *DefaultCache) Del(key string) (affected bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	v, ok := d.defaultCache.Load(key)
+	if !ok {
+		return false
+	}
 	d.defaultCache.Delete(key)
 	atomic.AddInt64(&d.count, int64(-1))
-	return true
+	return !v.(*entry).expired(time.Now())
 }
 
 //Clear 清除所有缓存
 func (d *DefaultCache) Clear() {
-	d.defaultCache = sync.Map{}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.defaultCache.Range(func(key, value interface{}) bool {
+		d.defaultCache.Delete(key)
+		return true
+	})
 	atomic.StoreInt64(&d.count, int64(0))
 }
 
+//delete 删除过期的缓存项，缓存项已被替换时不删除
+func (d *DefaultCache) delete(key interface{}, e *entry) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if v, ok := d.defaultCache.Load(key); ok && v == e {
+		d.defaultCache.Delete(key)
+		atomic.AddInt64(&d.count, int64(-1))
+	}
+}
+
+//removeExpired 清除所有已过期的缓存项
+func (d *DefaultCache) removeExpired() {
+	if atomic.LoadInt32(&d.expirable) == 0 {
+		return
+	}
+	now := time.Now()
+	d.defaultCache.Range(func(key, value interface{}) bool {
+		if e := value.(*entry); e.expired(now) {
+			d.delete(key, e)
+		}
+		return true
+	})
+}
+
 //DefaultCacheFactory 构造默认缓存组件工厂类
 type DefaultCacheFactory struct {
 }
@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	"github.com/snailzed/agollo/v4/agcache"
+	"github.com/snailzed/agollo/v4/agollotest/conformance"
 	. "github.com/tevid/gohamcrest"
 )
 
@@ -64,3 +65,17 @@ func TestDefaultCache_Clear(t *testing.T) {
 	testDefaultCache.Clear()
 	Assert(t, int64(0), Equal(testDefaultCache.EntryCount()))
 }
+
+func TestDefaultCacheConformance(t *testing.T) {
+	conformance.TestCache(t, &DefaultCacheFactory{})
+}
+
+func TestDefaultCache_CountDrift(t *testing.T) {
+	cache := (&DefaultCacheFactory{}).Create()
+	_ = cache.Set("a", "b", 0)
+	_ = cache.Set("a", "c", 0)
+	Assert(t, int64(1), Equal(cache.EntryCount()))
+
+	Assert(t, false, Equal(cache.Del("missing")))
+	Assert(t, int64(1), Equal(cache.EntryCount()))
+}
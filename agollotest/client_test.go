@@ -0,0 +1,157 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agollotest_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/snailzed/agollo/v4"
+	"github.com/snailzed/agollo/v4/agollotest"
+	"github.com/snailzed/agollo/v4/env/config"
+	jsonFile "github.com/snailzed/agollo/v4/env/file/json"
+	"github.com/snailzed/agollo/v4/storage"
+	. "github.com/tevid/gohamcrest"
+)
+
+type changeListener struct {
+	changes chan *storage.ChangeEvent
+}
+
+func (c *changeListener) OnChange(event *storage.ChangeEvent) {
+	c.changes <- event
+}
+
+func (c *changeListener) OnNewestChange(event *storage.FullChangeEvent) {
+}
+
+func TestClientReload(t *testing.T) {
+	server := agollotest.CreateServer()
+	defer server.Close()
+	server.SetLongPollTimeout(time.Second)
+	server.SetSecret("agollotest", "secret")
+	server.Publish("application", map[string]string{"key": "value"})
+
+	client, err := agollo.New(&config.AppConfig{
+		AppID:         "agollotest",
+		Cluster:       "default",
+		NamespaceName: "application",
+		IP:            server.URL(),
+		Secret:        "secret",
+	})
+	Assert(t, err, NilVal())
+	defer client.Close(context.Background())
+	Assert(t, client.GetValue("key"), Equal("value"))
+
+	listener := &changeListener{changes: make(chan *storage.ChangeEvent, 10)}
+	client.AddChangeListener(listener)
+
+	server.Set("application", "key", "value2")
+	select {
+	case event := <-listener.changes:
+		Assert(t, event.Namespace, Equal("application"))
+		Assert(t, event.Changes["key"].NewValue, Equal("value2"))
+	case <-time.After(10 * time.Second):
+		t.Fatal("change event must be received after publish")
+	}
+	Assert(t, client.GetValue("key"), Equal("value2"))
+	Assert(t, server.RequestCount(agollotest.EndpointNotifications) > 0, Equal(true))
+}
+
+func TestClientRawBackup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agollo-raw-backup")
+	Assert(t, err, NilVal())
+	defer os.RemoveAll(dir)
+
+	server := agollotest.CreateServer()
+	defer server.Close()
+	server.PublishContent("application.yaml", "a:\n  b: value\n")
+
+	client, err := agollo.New(&config.AppConfig{
+		AppID:            "agollotest",
+		Cluster:          "default",
+		NamespaceName:    "application.yaml",
+		IP:               server.URL(),
+		IsBackupConfig:   true,
+		BackupConfigPath: dir,
+	}, agollo.WithRawBackup())
+	Assert(t, err, NilVal())
+	defer client.Close(context.Background())
+	Assert(t, client.GetConfig("application.yaml").GetValue("a.b"), Equal("value"))
+
+	//原始内容在 json 备份之后写入
+	var loaded *config.ApolloConfig
+	for i := 0; i < 100; i++ {
+		if loaded, err = jsonFile.GetRawFileHandler().LoadConfigFile(dir, "agollotest", "application.yaml"); err == nil && loaded.Content != "" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	Assert(t, err, NilVal())
+	Assert(t, loaded.Content, Equal("a:\n  b: value\n"))
+	Assert(t, loaded.Configurations["a.b"], Equal("value"))
+
+	//原始内容备份可直接作为 yaml 文件打开
+	b, err := ioutil.ReadFile(jsonFile.GetRawFile(dir, "agollotest", "application.yaml"))
+	Assert(t, err, NilVal())
+	Assert(t, string(b), Equal("a:\n  b: value\n"))
+}
+
+func TestClientLocalMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agollo-local-mode")
+	Assert(t, err, NilVal())
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "application.properties")
+	Assert(t, ioutil.WriteFile(fileName, []byte("key=value\n"), 0644), NilVal())
+
+	server := agollotest.CreateServer()
+	defer server.Close()
+
+	client, err := agollo.New(&config.AppConfig{
+		AppID:            "agollotest",
+		Cluster:          "default",
+		NamespaceName:    "application",
+		IP:               server.URL(),
+		Mode:             config.ModeLocal,
+		LocalConfigPath:  dir,
+		WatchLocalConfig: true,
+		MustStart:        true,
+	})
+	Assert(t, err, NilVal())
+	defer client.Close(context.Background())
+	Assert(t, client.GetValue("key"), Equal("value"))
+
+	listener := &changeListener{changes: make(chan *storage.ChangeEvent, 10)}
+	client.AddChangeListener(listener)
+	Assert(t, ioutil.WriteFile(fileName, []byte("key=value2\n"), 0644), NilVal())
+	select {
+	case event := <-listener.changes:
+		Assert(t, event.Changes["key"].NewValue, Equal("value2"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("change event must be received after local file changed")
+	}
+	Assert(t, client.GetValue("key"), Equal("value2"))
+
+	for _, endpoint := range []agollotest.Endpoint{agollotest.EndpointServices, agollotest.EndpointConfigs, agollotest.EndpointNotifications} {
+		Assert(t, server.RequestCount(endpoint), Equal(0))
+	}
+}
@@ -0,0 +1,184 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conformance
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/snailzed/agollo/v4/agcache"
+)
+
+//TestCache 验证 agcache.CacheInterface 实现，每个子测试通过 factory 创建新的缓存
+func TestCache(t *testing.T, factory agcache.CacheFactory) {
+	t.Run("SetGet", func(t *testing.T) {
+		cache := factory.Create()
+		if err := cache.Set("key", "value", 0); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+		value, err := cache.Get("key")
+		if err != nil || value != "value" {
+			t.Fatalf("Get() = %v, %v, want value, nil", value, err)
+		}
+		if _, err := cache.Get("missing"); err == nil {
+			t.Fatalf("Get() of missing key must return an error")
+		}
+	})
+
+	t.Run("EntryCount", func(t *testing.T) {
+		cache := factory.Create()
+		assertEntryCount(t, cache, 0)
+		_ = cache.Set("a", 1, 0)
+		_ = cache.Set("b", 2, 0)
+		assertEntryCount(t, cache, 2)
+
+		// 覆盖已存在的 key 不改变数量
+		_ = cache.Set("a", 3, 0)
+		assertEntryCount(t, cache, 2)
+		if value, _ := cache.Get("a"); value != 3 {
+			t.Fatalf("Get() after overwrite = %v, want 3", value)
+		}
+	})
+
+	t.Run("Del", func(t *testing.T) {
+		cache := factory.Create()
+		_ = cache.Set("a", 1, 0)
+		if !cache.Del("a") {
+			t.Fatalf("Del() of existing key = false, want true")
+		}
+		assertEntryCount(t, cache, 0)
+		if _, err := cache.Get("a"); err == nil {
+			t.Fatalf("Get() after Del() must return an error")
+		}
+
+		// 删除不存在的 key 不改变数量
+		_ = cache.Set("b", 2, 0)
+		if cache.Del("missing") {
+			t.Fatalf("Del() of missing key = true, want false")
+		}
+		assertEntryCount(t, cache, 1)
+	})
+
+	t.Run("Range", func(t *testing.T) {
+		cache := factory.Create()
+		want := map[string]interface{}{"a": 1, "b": "2", "c": true}
+		for key, value := range want {
+			_ = cache.Set(key, value, 0)
+		}
+
+		got := make(map[string]interface{})
+		cache.Range(func(key, value interface{}) bool {
+			got[key.(string)] = value
+			return true
+		})
+		if len(got) != len(want) {
+			t.Fatalf("Range() visited %v, want %v", got, want)
+		}
+		for key, value := range want {
+			if got[key] != value {
+				t.Fatalf("Range() visited %v, want %v", got, want)
+			}
+		}
+
+		visited := 0
+		cache.Range(func(key, value interface{}) bool {
+			visited++
+			return false
+		})
+		if visited != 1 {
+			t.Fatalf("Range() must stop when f returns false, visited %d", visited)
+		}
+	})
+
+	t.Run("Clear", func(t *testing.T) {
+		cache := factory.Create()
+		_ = cache.Set("a", 1, 0)
+		_ = cache.Set("b", 2, 0)
+		cache.Clear()
+		assertEntryCount(t, cache, 0)
+		if _, err := cache.Get("a"); err == nil {
+			t.Fatalf("Get() after Clear() must return an error")
+		}
+		_ = cache.Set("a", 1, 0)
+		assertEntryCount(t, cache, 1)
+	})
+
+	t.Run("Expire", func(t *testing.T) {
+		cache := factory.Create()
+		_ = cache.Set("expire", 1, 1)
+		_ = cache.Set("forever", 2, 0)
+		_ = cache.Set("negative", 3, -1)
+		time.Sleep(1100 * time.Millisecond)
+
+		if _, err := cache.Get("expire"); err == nil {
+			t.Fatalf("Get() of expired key must return an error")
+		}
+		if value, err := cache.Get("forever"); err != nil || value != 2 {
+			t.Fatalf("Get() = %v, %v, want 2, nil", value, err)
+		}
+		if value, err := cache.Get("negative"); err != nil || value != 3 {
+			t.Fatalf("Get() = %v, %v, want 3, nil", value, err)
+		}
+		_ = cache.Set("expire2", 4, 1)
+		time.Sleep(1100 * time.Millisecond)
+		cache.Range(func(key, value interface{}) bool {
+			if key == "expire2" {
+				t.Fatalf("Range() must skip expired key")
+			}
+			return true
+		})
+		assertEntryCount(t, cache, 2)
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		cache := factory.Create()
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					key := fmt.Sprintf("key%d", j)
+					_ = cache.Set(key, i, 0)
+					_, _ = cache.Get(key)
+					if j%2 == 0 {
+						cache.Del(key)
+					}
+					cache.Range(func(key, value interface{}) bool {
+						return true
+					})
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		for j := 0; j < 100; j += 2 {
+			cache.Del(fmt.Sprintf("key%d", j))
+		}
+		assertEntryCount(t, cache, 50)
+	})
+}
+
+func assertEntryCount(t *testing.T, cache agcache.CacheInterface, want int64) {
+	t.Helper()
+	if got := cache.EntryCount(); got != want {
+		t.Fatalf("EntryCount() = %d, want %d", got, want)
+	}
+}
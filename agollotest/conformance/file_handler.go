@@ -0,0 +1,160 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conformance
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/snailzed/agollo/v4/env/config"
+	"github.com/snailzed/agollo/v4/env/file"
+)
+
+//TestFileHandler 验证 file.FileHandler 实现，备份文件写入临时目录并在测试结束后删除
+func TestFileHandler(t *testing.T, handler file.FileHandler) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T, handler file.FileHandler, dir string)
+	}{
+		{"RoundTrip", testFileHandlerRoundTrip},
+		{"Overwrite", testFileHandlerOverwrite},
+		{"GetConfigFile", testFileHandlerGetConfigFile},
+		{"LoadMissing", testFileHandlerLoadMissing},
+		{"Concurrent", testFileHandlerConcurrent},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "agollo-conformance")
+			if err != nil {
+				t.Fatalf("create temp dir error = %v", err)
+			}
+			defer os.RemoveAll(dir)
+			c.run(t, handler, dir)
+		})
+	}
+}
+
+func createApolloConfig(appID string, namespace string, releaseKey string, configurations map[string]interface{}) *config.ApolloConfig {
+	apolloConfig := &config.ApolloConfig{}
+	apolloConfig.AppID = appID
+	apolloConfig.Cluster = "default"
+	apolloConfig.NamespaceName = namespace
+	apolloConfig.ReleaseKey = releaseKey
+	apolloConfig.Configurations = configurations
+	return apolloConfig
+}
+
+func assertApolloConfig(t *testing.T, got *config.ApolloConfig, want *config.ApolloConfig) {
+	t.Helper()
+	if got == nil {
+		t.Fatalf("LoadConfigFile() = nil, want %+v", want)
+	}
+	if got.AppID != want.AppID || got.NamespaceName != want.NamespaceName || got.ReleaseKey != want.ReleaseKey {
+		t.Fatalf("LoadConfigFile() = %s/%s/%s, want %s/%s/%s",
+			got.AppID, got.NamespaceName, got.ReleaseKey, want.AppID, want.NamespaceName, want.ReleaseKey)
+	}
+	if !reflect.DeepEqual(got.Configurations, want.Configurations) {
+		t.Fatalf("LoadConfigFile() configurations = %v, want %v", got.Configurations, want.Configurations)
+	}
+}
+
+func testFileHandlerRoundTrip(t *testing.T, handler file.FileHandler, dir string) {
+	want := createApolloConfig("conformance", "application", "release-1", map[string]interface{}{
+		"key":     "value",
+		"unicode": "中文",
+		"empty":   "",
+	})
+	if err := handler.WriteConfigFile(want, dir); err != nil {
+		t.Fatalf("WriteConfigFile() error = %v", err)
+	}
+	got, err := handler.LoadConfigFile(dir, want.AppID, want.NamespaceName)
+	if err != nil {
+		t.Fatalf("LoadConfigFile() error = %v", err)
+	}
+	assertApolloConfig(t, got, want)
+}
+
+func testFileHandlerOverwrite(t *testing.T, handler file.FileHandler, dir string) {
+	first := createApolloConfig("conformance", "overwrite", "release-1", map[string]interface{}{"key": "value1", "old": "old"})
+	second := createApolloConfig("conformance", "overwrite", "release-2", map[string]interface{}{"key": "value2"})
+	if err := handler.WriteConfigFile(first, dir); err != nil {
+		t.Fatalf("WriteConfigFile() error = %v", err)
+	}
+	if err := handler.WriteConfigFile(second, dir); err != nil {
+		t.Fatalf("WriteConfigFile() error = %v", err)
+	}
+	got, err := handler.LoadConfigFile(dir, second.AppID, second.NamespaceName)
+	if err != nil {
+		t.Fatalf("LoadConfigFile() error = %v", err)
+	}
+	assertApolloConfig(t, got, second)
+}
+
+func testFileHandlerGetConfigFile(t *testing.T, handler file.FileHandler, dir string) {
+	a := handler.GetConfigFile(dir, "conformance", "a")
+	if a == "" {
+		t.Fatalf("GetConfigFile() must not be empty")
+	}
+	if again := handler.GetConfigFile(dir, "conformance", "a"); again != a {
+		t.Fatalf("GetConfigFile() = %s, want stable result %s", again, a)
+	}
+	if b := handler.GetConfigFile(dir, "conformance", "b"); b == a {
+		t.Fatalf("GetConfigFile() of different namespaces must differ, both %s", a)
+	}
+}
+
+func testFileHandlerLoadMissing(t *testing.T, handler file.FileHandler, dir string) {
+	got, err := handler.LoadConfigFile(dir, "conformance", "missing")
+	if err == nil {
+		t.Fatalf("LoadConfigFile() of missing file must return an error")
+	}
+	if got != nil {
+		t.Fatalf("LoadConfigFile() of missing file = %+v, want nil", got)
+	}
+}
+
+func testFileHandlerConcurrent(t *testing.T, handler file.FileHandler, dir string) {
+	var wg sync.WaitGroup
+	configs := make([]*config.ApolloConfig, 10)
+	for i := range configs {
+		configs[i] = createApolloConfig("conformance", fmt.Sprintf("concurrent%d", i), "release", map[string]interface{}{
+			"index": fmt.Sprint(i),
+		})
+		wg.Add(1)
+		go func(c *config.ApolloConfig) {
+			defer wg.Done()
+			if err := handler.WriteConfigFile(c, dir); err != nil {
+				t.Errorf("WriteConfigFile() error = %v", err)
+			}
+		}(configs[i])
+	}
+	wg.Wait()
+
+	for _, want := range configs {
+		got, err := handler.LoadConfigFile(dir, want.AppID, want.NamespaceName)
+		if err != nil {
+			t.Fatalf("LoadConfigFile() error = %v", err)
+		}
+		assertApolloConfig(t, got, want)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conformance
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/snailzed/agollo/v4/protocol/auth"
+)
+
+//HTTPAuthVerifier 校验生成的请求头是否正确，例如使用服务端算法重新计算签名
+type HTTPAuthVerifier func(url string, appID string, secret string, headers map[string][]string) error
+
+//TestHTTPAuth 验证 auth.HTTPAuth 实现，verify 为 nil 时只做通用校验
+func TestHTTPAuth(t *testing.T, httpAuth auth.HTTPAuth, verify HTTPAuthVerifier) {
+	cases := []struct {
+		name   string
+		url    string
+		appID  string
+		secret string
+	}{
+		{"Configs", "http://localhost:8080/configs/app/default/application?ip=127.0.0.1", "app", "secret"},
+		{"Notifications", "http://localhost:8080/notifications/v2?appId=app&cluster=default&notifications=%5B%5D", "app", "secret"},
+		{"NoQuery", "http://localhost:8080/services/config", "app", "secret"},
+		{"Unicode", "http://localhost:8080/configs/应用/default/命名空间", "应用", "密钥"},
+		{"InvalidURL", "://invalid", "app", "secret"},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			headers := httpAuth.HTTPHeaders(c.url, c.appID, c.secret)
+			assertHeaders(t, headers)
+			if verify != nil {
+				if err := verify(c.url, c.appID, c.secret, headers); err != nil {
+					t.Fatalf("HTTPHeaders(%s) verify error = %v", c.url, err)
+				}
+			}
+		})
+	}
+
+	t.Run("Concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					url := "http://localhost:8080/configs/app/default/application"
+					headers := httpAuth.HTTPHeaders(url, "app", "secret")
+					if verify != nil {
+						if err := verify(url, "app", "secret", headers); err != nil {
+							t.Errorf("HTTPHeaders(%s) verify error = %v", url, err)
+						}
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func assertHeaders(t *testing.T, headers map[string][]string) {
+	t.Helper()
+	for name, values := range headers {
+		if name == "" {
+			t.Fatalf("HTTPHeaders() must not contain empty header name")
+		}
+		if len(values) == 0 {
+			t.Fatalf("HTTPHeaders() header %s must have at least one value", name)
+		}
+	}
+}
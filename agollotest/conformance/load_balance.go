@@ -0,0 +1,112 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conformance
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/snailzed/agollo/v4/cluster"
+	"github.com/snailzed/agollo/v4/env/config"
+)
+
+//createServers 创建 n 个节点，down 中的节点模拟熔断打开，与 GetAvailableServers 一样不出现在结果中
+func createServers(n int, down ...int) map[string]*config.ServerInfo {
+	servers := make(map[string]*config.ServerInfo, n)
+	for i := 0; i < n; i++ {
+		host := fmt.Sprintf("http://10.0.0.%d:8080/", i)
+		servers[host] = &config.ServerInfo{
+			AppName:     "APOLLO-CONFIGSERVICE",
+			InstanceID:  fmt.Sprintf("10.0.0.%d:apollo-configservice:8080", i),
+			HomepageURL: host,
+		}
+	}
+	for _, i := range down {
+		delete(servers, fmt.Sprintf("http://10.0.0.%d:8080/", i))
+	}
+	return servers
+}
+
+//TestLoadBalance 验证 cluster.LoadBalance 实现
+//熔断打开的节点在调用 Load 前已被过滤，servers 中的节点均可用
+func TestLoadBalance(t *testing.T, loadBalance cluster.LoadBalance) {
+	cases := []struct {
+		name    string
+		servers map[string]*config.ServerInfo
+		// wantNil 为 true 时必须返回 nil，否则必须返回 servers 中的节点
+		wantNil bool
+	}{
+		{"Nil", nil, true},
+		{"Empty", map[string]*config.ServerInfo{}, true},
+		{"AllDown", createServers(3, 0, 1, 2), true},
+		{"Single", createServers(1), false},
+		{"SkipDown", createServers(5, 0, 1, 3, 4), false},
+		{"AllUp", createServers(5), false},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				assertLoad(t, loadBalance.Load(c.servers), c.servers, c.wantNil)
+			}
+		})
+	}
+
+	//可用节点随熔断状态变化，有状态的实现也只能返回本次传入的节点
+	t.Run("Changing", func(t *testing.T) {
+		all := createServers(5)
+		for i := 0; i < 20; i++ {
+			assertLoad(t, loadBalance.Load(all), all, false)
+			down := createServers(5, i%5, (i+1)%5, (i+2)%5)
+			assertLoad(t, loadBalance.Load(down), down, false)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		servers := createServers(5, 1)
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					assertLoad(t, loadBalance.Load(servers), servers, false)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func assertLoad(t *testing.T, got *config.ServerInfo, servers map[string]*config.ServerInfo, wantNil bool) {
+	t.Helper()
+	if wantNil {
+		if got != nil {
+			t.Errorf("Load() = %+v, want nil", got)
+		}
+		return
+	}
+	if got == nil {
+		t.Errorf("Load() = nil, want an available server")
+		return
+	}
+	if servers[got.HomepageURL] != got {
+		t.Errorf("Load() = %s, must be one of the given servers", got.HomepageURL)
+	}
+}
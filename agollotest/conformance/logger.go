@@ -0,0 +1,76 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conformance
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/snailzed/agollo/v4/component/log"
+)
+
+//TestLogger 验证 log.LoggerInterface 实现，任意参数组合及并发调用都不能 panic
+func TestLogger(t *testing.T, logger log.LoggerInterface) {
+	cases := []struct {
+		name   string
+		format string
+		params []interface{}
+	}{
+		{"NoParams", "message", nil},
+		{"Params", "namespace:%s, count:%d", []interface{}{"application", 1}},
+		{"Nil", "%v %s", []interface{}{nil, nil}},
+		{"Error", "error:%s", []interface{}{errors.New("error")}},
+		{"MissingParams", "%s %s %d", []interface{}{"a"}},
+		{"ExtraParams", "%s", []interface{}{"a", "b", 1}},
+		{"Percent", "100%", nil},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			callLogger(logger, c.format, c.params)
+		})
+	}
+
+	t.Run("Concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for _, c := range cases {
+					callLogger(logger, c.format, c.params)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func callLogger(logger log.LoggerInterface, format string, params []interface{}) {
+	logger.Debugf(format, params...)
+	logger.Infof(format, params...)
+	logger.Warnf(format, params...)
+	logger.Errorf(format, params...)
+	logger.Debug(append([]interface{}{format}, params...)...)
+	logger.Info(append([]interface{}{format}, params...)...)
+	logger.Warn(append([]interface{}{format}, params...)...)
+	logger.Error(append([]interface{}{format}, params...)...)
+	logger.Debug()
+	logger.Error()
+}
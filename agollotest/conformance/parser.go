@@ -0,0 +1,103 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conformance
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/snailzed/agollo/v4/utils/parse"
+)
+
+//ParserCase ContentParser 的测试用例
+type ParserCase struct {
+	Name    string
+	Content string
+	// Want 解析结果中必须包含的 key 及对应的值
+	Want map[string]interface{}
+	// WantErr 为 true 时必须返回错误
+	WantErr bool
+}
+
+//TestContentParser 验证 parse.ContentParser 实现，cases 为该格式特有的用例
+//通用约定：非 string 内容及空字符串返回 nil, nil，解析失败返回 nil 结果及错误，可并发调用
+func TestContentParser(t *testing.T, parser parse.ContentParser, cases []ParserCase) {
+	t.Run("NotString", func(t *testing.T) {
+		for _, content := range []interface{}{nil, 0, []byte("a=b"), struct{}{}} {
+			m, err := parser.Parse(content)
+			if m != nil || err != nil {
+				t.Fatalf("Parse(%T) = %v, %v, want nil, nil", content, m, err)
+			}
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		m, err := parser.Parse("")
+		if m != nil || err != nil {
+			t.Fatalf("Parse(\"\") = %v, %v, want nil, nil", m, err)
+		}
+	})
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			assertParse(t, parser, c)
+		})
+	}
+
+	t.Run("Concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 10; j++ {
+					for _, c := range cases {
+						assertParse(t, parser, c)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func assertParse(t *testing.T, parser parse.ContentParser, c ParserCase) {
+	t.Helper()
+	m, err := parser.Parse(c.Content)
+	if c.WantErr {
+		if err == nil {
+			t.Errorf("Parse(%s) must return an error", c.Name)
+		}
+		if m != nil {
+			t.Errorf("Parse(%s) = %v, want nil on error", c.Name, m)
+		}
+		return
+	}
+	if err != nil {
+		t.Errorf("Parse(%s) error = %v", c.Name, err)
+		return
+	}
+	for key, want := range c.Want {
+		got, ok := m[key]
+		if !ok || !reflect.DeepEqual(got, want) {
+			t.Errorf("Parse(%s)[%s] = %v (%T), want %v (%T)", c.Name, key, got, got, want, want)
+		}
+	}
+}
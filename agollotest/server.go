@@ -0,0 +1,504 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+//Package agollotest 提供进程内的 apollo config service 模拟服务，用于测试及本地开发
+//
+//	server := agollotest.CreateServer()
+//	defer server.Close()
+//	server.Publish("application", map[string]string{"key": "value"})
+//
+//	client, err := agollo.New(&config.AppConfig{
+//		AppID:         "app",
+//		Cluster:       "default",
+//		NamespaceName: "application",
+//		IP:            server.URL(),
+//	})
+package agollotest
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Endpoint 模拟服务的接口
+type Endpoint string
+
+const (
+	//EndpointServices 获取 config service 列表 /services/config
+	EndpointServices Endpoint = "services"
+	//EndpointConfigs 带缓存的配置接口 /configs/{appId}/{cluster}/{namespace}
+	EndpointConfigs Endpoint = "configs"
+	//EndpointConfigFiles 不带缓存的配置接口 /configfiles/json/{appId}/{cluster}/{namespace}
+	EndpointConfigFiles Endpoint = "configfiles"
+	//EndpointNotifications 长轮询接口 /notifications/v2
+	EndpointNotifications Endpoint = "notifications"
+)
+
+const (
+	defaultLongPollTimeout = 60 * time.Second
+	// 签名中的时间戳与服务端时间允许的最大误差，与 apollo 一致
+	signatureTimeout = time.Minute
+
+	httpHeaderAuthorization = "Authorization"
+	httpHeaderTimestamp     = "Timestamp"
+	authorizationPrefix     = "Apollo "
+)
+
+//Fault 模拟的异常响应
+type Fault struct {
+	//StatusCode 返回的状态码，为0时正常处理请求，可用于仅模拟慢响应
+	StatusCode int
+	//Delay 响应前的等待时间
+	Delay time.Duration
+	//Times 生效次数，小于等于0时一直生效直到 ClearFaults
+	Times int
+}
+
+type namespace struct {
+	configurations map[string]string
+	releaseKey     string
+	notificationID int64
+}
+
+type notification struct {
+	NamespaceName  string `json:"namespaceName"`
+	NotificationID int64  `json:"notificationId"`
+}
+
+type apolloConfig struct {
+	AppID          string            `json:"appId"`
+	Cluster        string            `json:"cluster"`
+	NamespaceName  string            `json:"namespaceName"`
+	ReleaseKey     string            `json:"releaseKey"`
+	Configurations map[string]string `json:"configurations"`
+}
+
+type serverInfo struct {
+	AppName     string `json:"appName"`
+	InstanceID  string `json:"instanceId"`
+	HomepageURL string `json:"homepageUrl"`
+}
+
+//Server apollo config service 模拟服务
+//配置按 namespace 保存，不区分请求中的 appId 及 cluster
+type Server struct {
+	server *httptest.Server
+
+	lock            sync.Mutex
+	namespaces      map[string]*namespace
+	secrets         map[string]string
+	faults          map[Endpoint][]*Fault
+	requests        map[Endpoint]int
+	longPollTimeout time.Duration
+	release         int64
+	notificationID  int64
+	// changed 在配置发布时关闭并重新创建，用于唤醒长轮询
+	changed chan struct{}
+	closed  chan struct{}
+	once    sync.Once
+}
+
+//CreateServer 创建并启动模拟服务
+func CreateServer() *Server {
+	s := &Server{
+		namespaces:      make(map[string]*namespace),
+		secrets:         make(map[string]string),
+		faults:          make(map[Endpoint][]*Fault),
+		requests:        make(map[Endpoint]int),
+		longPollTimeout: defaultLongPollTimeout,
+		changed:         make(chan struct{}),
+		closed:          make(chan struct{}),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+//URL 模拟服务的地址，用于 AppConfig.IP
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+//Close 关闭模拟服务，正在等待的长轮询立即返回
+func (s *Server) Close() {
+	s.once.Do(func() {
+		close(s.closed)
+	})
+	s.server.Close()
+}
+
+//SetLongPollTimeout 设置长轮询无变更时返回 304 前的等待时间，默认 60s
+func (s *Server) SetLongPollTimeout(timeout time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.longPollTimeout = timeout
+}
+
+//SetSecret 开启 appID 的签名校验，签名错误或过期时返回 401，secret 为空时关闭校验
+func (s *Server) SetSecret(appID string, secret string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if secret == "" {
+		delete(s.secrets, appID)
+		return
+	}
+	s.secrets[appID] = secret
+}
+
+//Publish 发布 namespace，替换全部配置项
+func (s *Server) Publish(namespaceName string, configurations map[string]string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n := s.getOrCreateNamespace(namespaceName)
+	n.configurations = make(map[string]string, len(configurations))
+	for key, value := range configurations {
+		n.configurations[key] = value
+	}
+	s.releaseLocked(namespaceName, n)
+}
+
+//PublishContent 发布非 properties 格式（如 a.json、a.yml）的 namespace 内容
+func (s *Server) PublishContent(namespaceName string, content string) {
+	s.Publish(namespaceName, map[string]string{"content": content})
+}
+
+//Set 新增或修改 namespace 中的配置项并发布
+func (s *Server) Set(namespaceName string, key string, value string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n := s.getOrCreateNamespace(namespaceName)
+	n.configurations[key] = value
+	s.releaseLocked(namespaceName, n)
+}
+
+//Delete 删除 namespace 中的配置项并发布
+func (s *Server) Delete(namespaceName string, key string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n := s.getOrCreateNamespace(namespaceName)
+	delete(n.configurations, key)
+	s.releaseLocked(namespaceName, n)
+}
+
+//DeleteNamespace 删除 namespace，之后的配置请求返回 404
+func (s *Server) DeleteNamespace(namespaceName string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.namespaces, namespaceName)
+}
+
+//BumpNotification 只增加 namespace 的通知 ID 而不发布新的配置，客户端拉取时得到 304
+func (s *Server) BumpNotification(namespaceName string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n := s.getOrCreateNamespace(namespaceName)
+	s.notifyLocked(n)
+}
+
+//GetReleaseKey 获取 namespace 当前的 releaseKey，namespace 不存在时返回空字符串
+func (s *Server) GetReleaseKey(namespaceName string) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if n, ok := s.namespaces[namespaceName]; ok {
+		return n.releaseKey
+	}
+	return ""
+}
+
+//InjectFault 为接口添加异常响应，多个异常按添加顺序依次生效
+func (s *Server) InjectFault(endpoint Endpoint, fault Fault) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	f := fault
+	s.faults[endpoint] = append(s.faults[endpoint], &f)
+}
+
+//ClearFaults 清除所有异常响应
+func (s *Server) ClearFaults() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faults = make(map[Endpoint][]*Fault)
+}
+
+//RequestCount 获取接口收到的请求数量
+func (s *Server) RequestCount(endpoint Endpoint) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.requests[endpoint]
+}
+
+func (s *Server) getOrCreateNamespace(namespaceName string) *namespace {
+	n, ok := s.namespaces[namespaceName]
+	if !ok {
+		n = &namespace{configurations: make(map[string]string)}
+		s.namespaces[namespaceName] = n
+	}
+	return n
+}
+
+func (s *Server) releaseLocked(namespaceName string, n *namespace) {
+	s.release++
+	n.releaseKey = fmt.Sprintf("%s-%s-%d", time.Now().Format("20060102150405"), namespaceName, s.release)
+	s.notifyLocked(n)
+}
+
+func (s *Server) notifyLocked(n *namespace) {
+	s.notificationID++
+	n.notificationID = s.notificationID
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *Server) serveHTTP(rw http.ResponseWriter, req *http.Request) {
+	path := strings.Trim(req.URL.Path, "/")
+	var endpoint Endpoint
+	switch {
+	case path == "services/config":
+		endpoint = EndpointServices
+	case strings.HasPrefix(path, "configs/"):
+		endpoint = EndpointConfigs
+	case strings.HasPrefix(path, "configfiles/json/"):
+		endpoint = EndpointConfigFiles
+	case path == "notifications/v2":
+		endpoint = EndpointNotifications
+	default:
+		http.NotFound(rw, req)
+		return
+	}
+
+	fault := s.takeFault(endpoint)
+	if fault != nil && fault.Delay > 0 {
+		select {
+		case <-time.After(fault.Delay):
+		case <-req.Context().Done():
+			return
+		case <-s.closed:
+			return
+		}
+	}
+	if fault != nil && fault.StatusCode != 0 {
+		rw.WriteHeader(fault.StatusCode)
+		return
+	}
+
+	switch endpoint {
+	case EndpointServices:
+		s.serveServices(rw, req)
+		return
+	case EndpointNotifications:
+		if !s.verifySignature(rw, req, req.URL.Query().Get("appId")) {
+			return
+		}
+		s.serveNotifications(rw, req)
+		return
+	}
+
+	// configs/{appId}/{cluster}/{namespace} 或 configfiles/json/{appId}/{cluster}/{namespace}
+	segments := strings.Split(path, "/")
+	if endpoint == EndpointConfigFiles {
+		segments = segments[1:]
+	}
+	if len(segments) != 4 {
+		http.NotFound(rw, req)
+		return
+	}
+	appID, cluster, namespaceName := segments[1], segments[2], segments[3]
+	if !s.verifySignature(rw, req, appID) {
+		return
+	}
+	if endpoint == EndpointConfigs {
+		s.serveConfigs(rw, req, appID, cluster, namespaceName)
+		return
+	}
+	s.serveConfigFiles(rw, namespaceName)
+}
+
+func (s *Server) takeFault(endpoint Endpoint) *Fault {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.requests[endpoint]++
+
+	faults := s.faults[endpoint]
+	if len(faults) == 0 {
+		return nil
+	}
+	fault := *faults[0]
+	if faults[0].Times > 0 {
+		faults[0].Times--
+		if faults[0].Times == 0 {
+			s.faults[endpoint] = faults[1:]
+		}
+	}
+	return &fault
+}
+
+//verifySignature 校验签名，与 apollo config service 的算法一致
+func (s *Server) verifySignature(rw http.ResponseWriter, req *http.Request, appID string) bool {
+	s.lock.Lock()
+	secret, ok := s.secrets[appID]
+	s.lock.Unlock()
+	if !ok {
+		return true
+	}
+
+	timestamp := req.Header.Get(httpHeaderTimestamp)
+	ms, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		rw.WriteHeader(http.StatusUnauthorized)
+		return false
+	}
+	if d := time.Since(time.Unix(0, ms*int64(time.Millisecond))); d > signatureTimeout || d < -signatureTimeout {
+		rw.WriteHeader(http.StatusUnauthorized)
+		return false
+	}
+
+	pathWithQuery := req.URL.Path
+	if req.URL.RawQuery != "" {
+		pathWithQuery += "?" + req.URL.RawQuery
+	}
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + pathWithQuery))
+	want := authorizationPrefix + appID + ":" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(req.Header.Get(httpHeaderAuthorization)), []byte(want)) {
+		rw.WriteHeader(http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+func (s *Server) serveServices(rw http.ResponseWriter, req *http.Request) {
+	host := strings.TrimPrefix(s.server.URL, "http://")
+	writeJSON(rw, []*serverInfo{
+		{
+			AppName:     "APOLLO-CONFIGSERVICE",
+			InstanceID:  host + ":apollo-configservice",
+			HomepageURL: s.server.URL + "/",
+		},
+	})
+}
+
+func (s *Server) serveConfigs(rw http.ResponseWriter, req *http.Request, appID string, cluster string, namespaceName string) {
+	s.lock.Lock()
+	n, ok := s.namespaces[namespaceName]
+	var c *apolloConfig
+	if ok {
+		c = &apolloConfig{
+			AppID:          appID,
+			Cluster:        cluster,
+			NamespaceName:  namespaceName,
+			ReleaseKey:     n.releaseKey,
+			Configurations: copyConfigurations(n.configurations),
+		}
+	}
+	s.lock.Unlock()
+
+	if !ok {
+		http.NotFound(rw, req)
+		return
+	}
+	if req.URL.Query().Get("releaseKey") == c.ReleaseKey {
+		rw.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeJSON(rw, c)
+}
+
+func (s *Server) serveConfigFiles(rw http.ResponseWriter, namespaceName string) {
+	s.lock.Lock()
+	n, ok := s.namespaces[namespaceName]
+	var configurations map[string]string
+	if ok {
+		configurations = copyConfigurations(n.configurations)
+	}
+	s.lock.Unlock()
+
+	if !ok {
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	}
+	writeJSON(rw, configurations)
+}
+
+//serveNotifications 存在通知 ID 大于客户端的 namespace 时立即返回，否则等待发布或超时后返回 304
+func (s *Server) serveNotifications(rw http.ResponseWriter, req *http.Request) {
+	var clientNotifications []*notification
+	if err := json.Unmarshal([]byte(req.URL.Query().Get("notifications")), &clientNotifications); err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.lock.Lock()
+	timer := time.NewTimer(s.longPollTimeout)
+	s.lock.Unlock()
+	defer timer.Stop()
+
+	for {
+		s.lock.Lock()
+		changes := make([]*notification, 0)
+		for _, c := range clientNotifications {
+			if n, ok := s.namespaces[c.NamespaceName]; ok && n.notificationID > c.NotificationID {
+				changes = append(changes, &notification{NamespaceName: c.NamespaceName, NotificationID: n.notificationID})
+			}
+		}
+		changed := s.changed
+		s.lock.Unlock()
+
+		if len(changes) > 0 {
+			writeJSON(rw, changes)
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			rw.WriteHeader(http.StatusNotModified)
+			return
+		case <-req.Context().Done():
+			return
+		case <-s.closed:
+			rw.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+}
+
+func copyConfigurations(configurations map[string]string) map[string]string {
+	m := make(map[string]string, len(configurations))
+	for key, value := range configurations {
+		m[key] = value
+	}
+	return m
+}
+
+func writeJSON(rw http.ResponseWriter, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	rw.WriteHeader(http.StatusOK)
+	_, _ = rw.Write(b)
+}
@@ -0,0 +1,225 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agollotest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/snailzed/agollo/v4/protocol/auth/sign"
+	. "github.com/tevid/gohamcrest"
+)
+
+func get(t *testing.T, s *Server, uri string, headers map[string][]string) (int, []byte) {
+	req, err := http.NewRequest(http.MethodGet, s.URL()+uri, nil)
+	Assert(t, err, NilVal())
+	for key, values := range headers {
+		req.Header[key] = values
+	}
+	res, err := http.DefaultClient.Do(req)
+	Assert(t, err, NilVal())
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	Assert(t, err, NilVal())
+	return res.StatusCode, body
+}
+
+func notificationsURI(namespace string, id int64) string {
+	b, _ := json.Marshal([]*notification{{NamespaceName: namespace, NotificationID: id}})
+	return "/notifications/v2?appId=app&cluster=default&notifications=" + url.QueryEscape(string(b))
+}
+
+func TestServerServices(t *testing.T) {
+	s := CreateServer()
+	defer s.Close()
+
+	code, body := get(t, s, "/services/config?appId=app&ip=127.0.0.1", nil)
+	Assert(t, code, Equal(http.StatusOK))
+	var servers []*serverInfo
+	Assert(t, json.Unmarshal(body, &servers), NilVal())
+	Assert(t, len(servers), Equal(1))
+	Assert(t, servers[0].HomepageURL, Equal(s.URL()+"/"))
+	Assert(t, s.RequestCount(EndpointServices), Equal(1))
+}
+
+func TestServerConfigs(t *testing.T) {
+	s := CreateServer()
+	defer s.Close()
+
+	code, _ := get(t, s, "/configs/app/default/application", nil)
+	Assert(t, code, Equal(http.StatusNotFound))
+
+	s.Publish("application", map[string]string{"a": "1", "b": "2"})
+	code, body := get(t, s, "/configs/app/default/application?releaseKey=&ip=127.0.0.1", nil)
+	Assert(t, code, Equal(http.StatusOK))
+	c := &apolloConfig{}
+	Assert(t, json.Unmarshal(body, c), NilVal())
+	Assert(t, c.AppID, Equal("app"))
+	Assert(t, c.NamespaceName, Equal("application"))
+	Assert(t, c.ReleaseKey, Equal(s.GetReleaseKey("application")))
+	Assert(t, c.Configurations, Equal(map[string]string{"a": "1", "b": "2"}))
+
+	// releaseKey 未变化时返回 304
+	code, _ = get(t, s, "/configs/app/default/application?releaseKey="+url.QueryEscape(c.ReleaseKey), nil)
+	Assert(t, code, Equal(http.StatusNotModified))
+
+	s.Set("application", "a", "3")
+	s.Delete("application", "b")
+	code, body = get(t, s, "/configs/app/default/application?releaseKey="+url.QueryEscape(c.ReleaseKey), nil)
+	Assert(t, code, Equal(http.StatusOK))
+	c = &apolloConfig{}
+	Assert(t, json.Unmarshal(body, c), NilVal())
+	Assert(t, c.Configurations, Equal(map[string]string{"a": "3"}))
+
+	s.DeleteNamespace("application")
+	code, _ = get(t, s, "/configs/app/default/application", nil)
+	Assert(t, code, Equal(http.StatusNotFound))
+	Assert(t, s.GetReleaseKey("application"), Equal(""))
+}
+
+func TestServerConfigFiles(t *testing.T) {
+	s := CreateServer()
+	defer s.Close()
+
+	s.PublishContent("a.json", `{"a":1}`)
+	code, body := get(t, s, "/configfiles/json/app/default/a.json?&ip=127.0.0.1", nil)
+	Assert(t, code, Equal(http.StatusOK))
+	Assert(t, string(body), Equal(`{"content":"{\"a\":1}"}`))
+
+	code, _ = get(t, s, "/configfiles/json/app/default/missing", nil)
+	Assert(t, code, Equal(http.StatusNotFound))
+}
+
+func TestServerNotifications(t *testing.T) {
+	s := CreateServer()
+	defer s.Close()
+	s.SetLongPollTimeout(100 * time.Millisecond)
+
+	// 无变更时超时返回 304
+	code, _ := get(t, s, notificationsURI("application", -1), nil)
+	Assert(t, code, Equal(http.StatusNotModified))
+
+	s.Publish("application", map[string]string{"a": "1"})
+	code, body := get(t, s, notificationsURI("application", -1), nil)
+	Assert(t, code, Equal(http.StatusOK))
+	var notifications []*notification
+	Assert(t, json.Unmarshal(body, &notifications), NilVal())
+	Assert(t, len(notifications), Equal(1))
+	id := notifications[0].NotificationID
+
+	// 长轮询等待期间发布时立即返回
+	s.SetLongPollTimeout(5 * time.Second)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		s.BumpNotification("application")
+	}()
+	start := time.Now()
+	code, body = get(t, s, notificationsURI("application", id), nil)
+	Assert(t, code, Equal(http.StatusOK))
+	Assert(t, time.Since(start) < 5*time.Second, Equal(true))
+	Assert(t, json.Unmarshal(body, &notifications), NilVal())
+	Assert(t, notifications[0].NotificationID > id, Equal(true))
+
+	code, _ = get(t, s, "/notifications/v2?notifications=invalid", nil)
+	Assert(t, code, Equal(http.StatusBadRequest))
+}
+
+func TestServerCloseReleasesLongPoll(t *testing.T) {
+	s := CreateServer()
+	done := make(chan int)
+	go func() {
+		code, _ := get(t, s, notificationsURI("application", -1), nil)
+		done <- code
+	}()
+	time.Sleep(100 * time.Millisecond)
+	s.Close()
+
+	select {
+	case code := <-done:
+		Assert(t, code, Equal(http.StatusNotModified))
+	case <-time.After(5 * time.Second):
+		t.Fatal("long poll must return after Close")
+	}
+}
+
+func TestServerFaults(t *testing.T) {
+	s := CreateServer()
+	defer s.Close()
+	s.Publish("application", map[string]string{"a": "1"})
+
+	s.InjectFault(EndpointConfigs, Fault{StatusCode: http.StatusServiceUnavailable, Times: 2})
+	s.InjectFault(EndpointConfigs, Fault{Delay: 200 * time.Millisecond, Times: 1})
+	s.InjectFault(EndpointConfigs, Fault{StatusCode: http.StatusNotModified})
+
+	code, _ := get(t, s, "/configs/app/default/application", nil)
+	Assert(t, code, Equal(http.StatusServiceUnavailable))
+	code, _ = get(t, s, "/configs/app/default/application", nil)
+	Assert(t, code, Equal(http.StatusServiceUnavailable))
+
+	start := time.Now()
+	code, _ = get(t, s, "/configs/app/default/application", nil)
+	Assert(t, code, Equal(http.StatusOK))
+	Assert(t, time.Since(start) >= 200*time.Millisecond, Equal(true))
+
+	for i := 0; i < 3; i++ {
+		code, _ = get(t, s, "/configs/app/default/application", nil)
+		Assert(t, code, Equal(http.StatusNotModified))
+	}
+
+	s.ClearFaults()
+	code, _ = get(t, s, "/configs/app/default/application", nil)
+	Assert(t, code, Equal(http.StatusOK))
+	Assert(t, s.RequestCount(EndpointConfigs), Equal(7))
+}
+
+func TestServerSignature(t *testing.T) {
+	s := CreateServer()
+	defer s.Close()
+	s.Publish("application", map[string]string{"a": "1"})
+	s.SetSecret("app", "secret")
+
+	uri := "/configs/app/default/application?releaseKey=&ip=127.0.0.1"
+	code, _ := get(t, s, uri, nil)
+	Assert(t, code, Equal(http.StatusUnauthorized))
+
+	auth := &sign.AuthSignature{}
+	code, _ = get(t, s, uri, auth.HTTPHeaders(s.URL()+uri, "app", "wrong"))
+	Assert(t, code, Equal(http.StatusUnauthorized))
+
+	code, _ = get(t, s, uri, auth.HTTPHeaders(s.URL()+uri, "app", "secret"))
+	Assert(t, code, Equal(http.StatusOK))
+
+	s.SetLongPollTimeout(10 * time.Millisecond)
+	notifyURI := notificationsURI("application", -1)
+	code, _ = get(t, s, notifyURI, nil)
+	Assert(t, code, Equal(http.StatusUnauthorized))
+	code, _ = get(t, s, notifyURI, auth.HTTPHeaders(s.URL()+notifyURI, "app", "secret"))
+	Assert(t, code, Equal(http.StatusOK))
+
+	// 其他 appId 不校验
+	code, _ = get(t, s, "/configs/other/default/application", nil)
+	Assert(t, code, Equal(http.StatusOK))
+
+	s.SetSecret("app", "")
+	code, _ = get(t, s, uri, nil)
+	Assert(t, code, Equal(http.StatusOK))
+}
@@ -19,12 +19,20 @@ package agollo
 
 import (
 	"container/list"
+	"context"
 	"errors"
+	"fmt"
+	"os"
+	"sync"
 
 	"github.com/snailzed/agollo/v4/agcache"
 	"github.com/snailzed/agollo/v4/agcache/memory"
+	"github.com/snailzed/agollo/v4/cluster"
+	"github.com/snailzed/agollo/v4/cluster/random"
 	"github.com/snailzed/agollo/v4/cluster/roundrobin"
+	"github.com/snailzed/agollo/v4/cluster/weighted"
 	"github.com/snailzed/agollo/v4/component"
+	"github.com/snailzed/agollo/v4/component/local"
 	"github.com/snailzed/agollo/v4/component/log"
 	"github.com/snailzed/agollo/v4/component/notify"
 	"github.com/snailzed/agollo/v4/component/remote"
@@ -32,13 +40,20 @@ import (
 	"github.com/snailzed/agollo/v4/constant"
 	"github.com/snailzed/agollo/v4/env"
 	"github.com/snailzed/agollo/v4/env/config"
+	"github.com/snailzed/agollo/v4/env/file"
 	jsonFile "github.com/snailzed/agollo/v4/env/file/json"
+	"github.com/snailzed/agollo/v4/env/server"
 	"github.com/snailzed/agollo/v4/extension"
 	"github.com/snailzed/agollo/v4/protocol/auth/sign"
+	"github.com/snailzed/agollo/v4/protocol/httpclient"
 	"github.com/snailzed/agollo/v4/storage"
 	"github.com/snailzed/agollo/v4/utils"
+	"github.com/snailzed/agollo/v4/utils/parse/hcl"
+	jsonParser "github.com/snailzed/agollo/v4/utils/parse/json"
 	"github.com/snailzed/agollo/v4/utils/parse/normal"
 	"github.com/snailzed/agollo/v4/utils/parse/properties"
+	"github.com/snailzed/agollo/v4/utils/parse/toml"
+	"github.com/snailzed/agollo/v4/utils/parse/xml"
 	"github.com/snailzed/agollo/v4/utils/parse/yaml"
 	"github.com/snailzed/agollo/v4/utils/parse/yml"
 )
@@ -54,18 +69,22 @@ func init() {
 	extension.AddFormatParser(constant.Properties, &properties.Parser{})
 	extension.AddFormatParser(constant.YML, &yml.Parser{})
 	extension.AddFormatParser(constant.YAML, &yaml.Parser{})
+	extension.AddFormatParser(constant.JSON, &jsonParser.Parser{})
+	extension.AddFormatParser(constant.XML, &xml.Parser{})
+	extension.AddFormatParser(constant.TOML, &toml.Parser{})
+	extension.AddFormatParser(constant.HCL, &hcl.Parser{})
 }
 
-var syncApolloConfig = remote.CreateSyncApolloConfig()
-
 //Client apollo 客户端接口
 type Client interface {
 	GetConfig(namespace string) *storage.Config
 	GetConfigAndInit(namespace string) *storage.Config
+	GetConfigAndInitContext(ctx context.Context, namespace string) *storage.Config
 	GetConfigCache(namespace string) agcache.CacheInterface
 	GetDefaultConfigCache() agcache.CacheInterface
 	GetApolloConfigCache() agcache.CacheInterface
 	GetValue(key string) string
+	LookupValue(key string) (value string, namespace string)
 	GetStringValue(key string, defaultValue string) string
 	GetIntValue(key string, defaultValue int) int
 	GetFloatValue(key string, defaultValue float64) float64
@@ -73,10 +92,23 @@ type Client interface {
 	GetStringSliceValue(key string, defaultValue []string) []string
 	GetIntSliceValue(key string, defaultValue []int) []int
 	Unmarshal(key string, defaultValue interface{}) error
+	BindNamespace(namespace string, v interface{}) error
+	Watch(namespace string, target interface{}) (*Snapshot, error)
+	ListLocalReleases(namespace string) ([]*file.Release, error)
+	RollbackToLocalRelease(namespace string, releaseKey string) error
+	ReloadOverrides() error
+	GetEffectiveValues(namespace string) []*storage.EffectiveValue
+	GetServerHealth() []*server.NodeHealth
 	AddChangeListener(listener storage.ChangeListener)
 	RemoveChangeListener(listener storage.ChangeListener)
 	GetChangeListeners() *list.List
 	UseEventDispatch()
+	Close(ctx context.Context) error
+}
+
+//loggerSetter 支持设置 logger 的 FileHandler
+type loggerSetter interface {
+	SetLogger(logger log.LoggerInterface)
 }
 
 // internalClient apollo 客户端实例
@@ -84,19 +116,44 @@ type internalClient struct {
 	initAppConfigFunc func() (*config.AppConfig, error)
 	appConfig         *config.AppConfig
 	cache             *storage.Cache
+	components        *extension.Components
+	syncApolloConfig  remote.ApolloConfig
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
 }
 
 func (c *internalClient) getAppConfig() config.AppConfig {
 	return *c.appConfig
 }
 
+//create 创建使用全局组件的客户端，components 中未设置的组件均使用全局组件
 func create() *internalClient {
 	appConfig := env.InitFileConfig()
+	return newClient(appConfig, &extension.Components{})
+}
+
+func newClient(appConfig *config.AppConfig, components *extension.Components) *internalClient {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &internalClient{
-		appConfig: appConfig,
+		appConfig:        appConfig,
+		components:       components,
+		syncApolloConfig: remote.CreateSyncApolloConfigWithComponents(components),
+		ctx:              ctx,
+		cancel:           cancel,
 	}
 }
 
+// startComponent 启动后台组件，Close 时等待其退出
+func (c *internalClient) startComponent(absComponent component.AbsComponent) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		component.StartRefreshConfig(absComponent)
+	}()
+}
+
 // Start 根据默认文件启动
 func Start() (Client, error) {
 	return StartWithConfig(nil)
@@ -115,32 +172,151 @@ func StartWithConfig(loadAppConfig func() (*config.AppConfig, error)) (Client, e
 		c.appConfig = appConfig
 	}
 
-	c.cache = storage.CreateNamespaceConfig(appConfig.NamespaceName, appConfig.MustStart)
+	if err := c.start(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// New 根据配置创建并启动客户端，options 中设置的组件只对当前客户端生效，未设置的使用全局组件
+// 每个客户端持有独立的 config server 节点信息，可在同一进程中连接多个 apollo 集群
+func New(appConfig *config.AppConfig, options ...Option) (Client, error) {
+	if appConfig == nil {
+		return nil, errors.New("appConfig can not be nil")
+	}
+
+	o := &clientOptions{
+		components: &extension.Components{
+			ServerManager: server.CreateManager(),
+		},
+	}
+	for _, option := range options {
+		option(o)
+	}
+	if o.rawBackup {
+		if err := o.initRawBackup(); err != nil {
+			return nil, err
+		}
+	}
+	//客户端单独设置的备份文件组件使用客户端的 logger
+	if setter, ok := o.components.FileHandler.(loggerSetter); ok && o.components.Logger != nil {
+		setter.SetLogger(o.components.Logger)
+	}
+	//未设置 http.Client 工厂时使用客户端独立的默认工厂，Close 时释放其连接
+	if o.components.HTTPClientFactory == nil && extension.GetHTTPClientFactory() == nil {
+		o.components.HTTPClientFactory = &httpclient.DefaultClientFactory{}
+	}
+
+	c := newClient(appConfig, o.components)
+	if err := c.start(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *internalClient) start() error {
+	appConfig := c.appConfig
+	c.cache = storage.CreateNamespaceConfigWithComponents(appConfig.NamespaceName, c.components, appConfig.MustStart)
 	appConfig.Init()
 
-	serverlist.InitSyncServerIPList(c.getAppConfig)
+	if err := c.initLoadBalance(); err != nil {
+		_ = c.Close(context.Background())
+		return err
+	}
+
+	if err := c.ReloadOverrides(); err != nil {
+		_ = c.Close(context.Background())
+		return err
+	}
+
+	if appConfig.IsLocalMode() {
+		return c.startLocal()
+	}
+
+	c.startComponent(serverlist.CreateSyncServerIPListComponent(c.ctx, c.getAppConfig, c.components))
 
 	//first sync
-	configs := syncApolloConfig.Sync(c.getAppConfig)
-	if len(configs) == 0 && appConfig != nil && appConfig.MustStart {
-		return nil, errors.New("start failed cause no config was read")
+	configs := c.syncApolloConfig.SyncContext(c.ctx, c.getAppConfig)
+	if len(configs) == 0 && appConfig.MustStart {
+		_ = c.Close(context.Background())
+		return errors.New("start failed cause no config was read")
 	}
 
 	for _, apolloConfig := range configs {
 		c.cache.UpdateApolloConfig(apolloConfig, c.getAppConfig)
 	}
 
-	log.Debug("init notifySyncConfigServices finished")
+	c.logger().Debug("init notifySyncConfigServices finished")
 
 	//start long poll sync config
 	configComponent := &notify.ConfigComponent{}
 	configComponent.SetAppConfig(c.getAppConfig)
 	configComponent.SetCache(c.cache)
-	go component.StartRefreshConfig(configComponent)
+	configComponent.SetComponents(c.components)
+	configComponent.SetContext(c.ctx)
+	c.startComponent(configComponent)
 
-	log.Info("agollo start finished ! ")
+	c.logger().Info("agollo start finished ! ")
 
-	return c, nil
+	return nil
+}
+
+//initLoadBalance 根据 AppConfig.LoadBalance 创建负载均衡器，WithLoadBalance 设置的优先
+func (c *internalClient) initLoadBalance() error {
+	name := c.appConfig.LoadBalance
+	if name == "" || c.components.LoadBalance != nil {
+		return nil
+	}
+	loadBalance, err := createLoadBalance(name, c.appConfig.ServerWeights)
+	if err != nil {
+		return err
+	}
+	c.components.LoadBalance = loadBalance
+	return nil
+}
+
+//createLoadBalance 根据名称创建内置的负载均衡器
+func createLoadBalance(name string, weights map[string]int) (cluster.LoadBalance, error) {
+	switch name {
+	case roundrobin.Name:
+		return &roundrobin.RoundRobin{}, nil
+	case random.Name:
+		return &random.Random{}, nil
+	case weighted.Name:
+		return weighted.CreateWeighted(weights), nil
+	}
+	return nil, fmt.Errorf("unknown load balance %s", name)
+}
+
+//startLocal 本地模式启动，只从本地文件加载配置，不访问网络
+func (c *internalClient) startLocal() error {
+	appConfig := c.appConfig
+	configs := local.LoadAll(c.components, c.getAppConfig())
+	if len(configs) == 0 && appConfig.MustStart {
+		_ = c.Close(context.Background())
+		return errors.New("start failed cause no local config was read")
+	}
+
+	for _, apolloConfig := range configs {
+		c.cache.UpdateApolloConfig(apolloConfig, c.getAppConfig)
+	}
+
+	if appConfig.WatchLocalConfig {
+		configComponent := &local.ConfigComponent{}
+		configComponent.SetAppConfig(c.getAppConfig)
+		configComponent.SetCache(c.cache)
+		configComponent.SetComponents(c.components)
+		configComponent.SetContext(c.ctx)
+		c.startComponent(configComponent)
+	}
+
+	c.logger().Info("agollo start in local mode finished ! ")
+
+	return nil
+}
+
+func (c *internalClient) logger() log.LoggerInterface {
+	return c.components.GetLogger()
 }
 
 //GetConfig 根据namespace获取apollo配置
@@ -150,6 +326,11 @@ func (c *internalClient) GetConfig(namespace string) *storage.Config {
 
 //GetConfigAndInit 根据namespace获取apollo配置
 func (c *internalClient) GetConfigAndInit(namespace string) *storage.Config {
+	return c.GetConfigAndInitContext(context.Background(), namespace)
+}
+
+//GetConfigAndInitContext 根据namespace获取apollo配置，本地不存在时同步远端配置，ctx 取消或超时时中断同步
+func (c *internalClient) GetConfigAndInitContext(ctx context.Context, namespace string) *storage.Config {
 	if namespace == "" {
 		return nil
 	}
@@ -157,11 +338,11 @@ func (c *internalClient) GetConfigAndInit(namespace string) *storage.Config {
 	config := c.cache.GetConfig(namespace)
 
 	if config == nil {
-		//init cache
-		storage.CreateNamespaceConfig(namespace, c.getAppConfig().MustStart)
-
 		//sync config
-		syncApolloConfig.SyncWithNamespace(namespace, c.getAppConfig)
+		apolloConfig := c.syncNamespace(ctx, namespace)
+		if apolloConfig != nil {
+			c.cache.UpdateApolloConfig(apolloConfig, c.getAppConfig)
+		}
 	}
 
 	config = c.cache.GetConfig(namespace)
@@ -169,6 +350,19 @@ func (c *internalClient) GetConfigAndInit(namespace string) *storage.Config {
 	return config
 }
 
+//syncNamespace 获取本地不存在的 namespace，本地模式下从本地文件加载
+func (c *internalClient) syncNamespace(ctx context.Context, namespace string) *config.ApolloConfig {
+	if !c.appConfig.IsLocalMode() {
+		return c.syncApolloConfig.SyncWithNamespaceContext(ctx, namespace, c.getAppConfig)
+	}
+	apolloConfig, err := local.Load(c.components, c.getAppConfig(), namespace)
+	if err != nil {
+		c.logger().Errorf("load local config fail, namespace:%s, error:%s", namespace, err)
+		return nil
+	}
+	return apolloConfig
+}
+
 //GetConfigCache 根据namespace获取apollo配置的缓存
 func (c *internalClient) GetConfigCache(namespace string) agcache.CacheInterface {
 	config := c.GetConfigAndInit(namespace)
@@ -195,37 +389,68 @@ func (c *internalClient) GetApolloConfigCache() agcache.CacheInterface {
 
 //GetValue 获取配置
 func (c *internalClient) GetValue(key string) string {
-	return c.GetConfig(storage.GetDefaultNamespace()).GetValue(key)
+	return c.lookupConfig(key).GetValue(key)
 }
 
 //GetStringValue 获取string配置值
 func (c *internalClient) GetStringValue(key string, defaultValue string) string {
-	return c.GetConfig(storage.GetDefaultNamespace()).GetStringValue(key, defaultValue)
+	return c.lookupConfig(key).GetStringValue(key, defaultValue)
 }
 
 //GetIntValue 获取int配置值
 func (c *internalClient) GetIntValue(key string, defaultValue int) int {
-	return c.GetConfig(storage.GetDefaultNamespace()).GetIntValue(key, defaultValue)
+	return c.lookupConfig(key).GetIntValue(key, defaultValue)
 }
 
 //GetFloatValue 获取float配置值
 func (c *internalClient) GetFloatValue(key string, defaultValue float64) float64 {
-	return c.GetConfig(storage.GetDefaultNamespace()).GetFloatValue(key, defaultValue)
+	return c.lookupConfig(key).GetFloatValue(key, defaultValue)
 }
 
 //GetBoolValue 获取bool 配置值
 func (c *internalClient) GetBoolValue(key string, defaultValue bool) bool {
-	return c.GetConfig(storage.GetDefaultNamespace()).GetBoolValue(key, defaultValue)
+	return c.lookupConfig(key).GetBoolValue(key, defaultValue)
 }
 
 //GetStringSliceValue 获取[]string 配置值
 func (c *internalClient) GetStringSliceValue(key string, defaultValue []string) []string {
-	return c.GetConfig(storage.GetDefaultNamespace()).GetStringSliceValue(key, defaultValue)
+	return c.lookupConfig(key).GetStringSliceValue(key, defaultValue)
 }
 
 //GetIntSliceValue 获取[]int 配置值
 func (c *internalClient) GetIntSliceValue(key string, defaultValue []int) []int {
-	return c.GetConfig(storage.GetDefaultNamespace()).GetIntSliceValue(key, defaultValue)
+	return c.lookupConfig(key).GetIntSliceValue(key, defaultValue)
+}
+
+//LookupValue 按 LookupNamespaces 顺序查找配置值，同时返回提供该值的 namespace，不存在时 namespace 为空
+func (c *internalClient) LookupValue(key string) (string, string) {
+	for _, namespace := range c.lookupNamespaces() {
+		if config := c.cache.GetConfig(namespace); config.HasKey(key) {
+			return config.GetValue(key), namespace
+		}
+	}
+	return utils.Empty, utils.Empty
+}
+
+//lookupConfig 返回按 LookupNamespaces 顺序第一个包含 key 的 namespace 配置，都不包含时返回第一个 namespace 的配置
+func (c *internalClient) lookupConfig(key string) *storage.Config {
+	namespaces := c.lookupNamespaces()
+	if len(namespaces) > 1 {
+		for _, namespace := range namespaces {
+			if config := c.cache.GetConfig(namespace); config.HasKey(key) {
+				return config
+			}
+		}
+	}
+	return c.GetConfig(namespaces[0])
+}
+
+func (c *internalClient) lookupNamespaces() []string {
+	namespaces := c.appConfig.GetLookupNamespaces()
+	if len(namespaces) == 0 {
+		return []string{storage.GetDefaultNamespace()}
+	}
+	return namespaces
 }
 
 func (c *internalClient) getConfigValue(key string) interface{} {
@@ -236,7 +461,7 @@ func (c *internalClient) getConfigValue(key string) interface{} {
 
 	value, err := cache.Get(key)
 	if err != nil {
-		log.Errorf("get config value fail!key:%s,err:%s", key, err)
+		c.logger().Errorf("get config value fail!key:%s,err:%s", key, err)
 		return utils.Empty
 	}
 
@@ -245,7 +470,73 @@ func (c *internalClient) getConfigValue(key string) interface{} {
 
 //Unmarshal 反解析到defaultValue interface{}
 func (c *internalClient) Unmarshal(key string, defaultValue interface{}) error {
-	return c.GetConfig(storage.GetDefaultNamespace()).Unmarshal(key, defaultValue)
+	return c.lookupConfig(key).Unmarshal(key, defaultValue)
+}
+
+//BindNamespace 将整个namespace的配置绑定到结构体指针 v 上，tag 规则见 storage.Config.Bind
+func (c *internalClient) BindNamespace(namespace string, v interface{}) error {
+	config := c.GetConfig(namespace)
+	if config == nil {
+		return fmt.Errorf("bind fail ! namespace:%s is not exist", namespace)
+	}
+	return config.Bind(v)
+}
+
+//ListLocalReleases 获取 namespace 本地备份的历史版本，按写入时间从新到旧排列
+func (c *internalClient) ListLocalReleases(namespace string) ([]*file.Release, error) {
+	history, err := c.releaseHistory()
+	if err != nil {
+		return nil, err
+	}
+	return history.ListReleases(c.appConfig.GetBackupConfigPath(), c.appConfig.AppID, namespace)
+}
+
+//RollbackToLocalRelease 使用本地备份的历史版本覆盖当前配置，并照常触发变更事件
+//远端后续发布新版本时仍会覆盖回滚后的配置
+func (c *internalClient) RollbackToLocalRelease(namespace string, releaseKey string) error {
+	history, err := c.releaseHistory()
+	if err != nil {
+		return err
+	}
+	apolloConfig, err := history.LoadRelease(c.appConfig.GetBackupConfigPath(), c.appConfig.AppID, namespace, releaseKey)
+	if err != nil {
+		return err
+	}
+	apolloConfig.NamespaceName = namespace
+	c.cache.UpdateApolloConfig(apolloConfig, c.getAppConfig)
+	return nil
+}
+
+func (c *internalClient) releaseHistory() (file.ReleaseHistory, error) {
+	history, ok := c.components.GetFileHandler().(file.ReleaseHistory)
+	if !ok {
+		return nil, errors.New("file handler does not support release history")
+	}
+	return history, nil
+}
+
+//ReloadOverrides 重新读取本地覆盖文件及 APOLLO_OVERRIDE_ 环境变量，生效值变化时触发变更事件
+func (c *internalClient) ReloadOverrides() error {
+	overrides, err := storage.LoadOverrides(c.appConfig.OverrideConfigPath, c.appConfig.NamespaceName, os.Environ())
+	if err != nil {
+		return err
+	}
+	c.cache.SetOverrides(overrides)
+	return nil
+}
+
+//GetEffectiveValues 列出 namespace 所有配置项的生效值及 apollo 上的值
+func (c *internalClient) GetEffectiveValues(namespace string) []*storage.EffectiveValue {
+	config := c.GetConfig(namespace)
+	if config == nil {
+		return nil
+	}
+	return config.GetEffectiveValues()
+}
+
+//GetServerHealth 获取 config server 节点的熔断状态，未同步到服务列表时返回 nil
+func (c *internalClient) GetServerHealth() []*server.NodeHealth {
+	return c.components.GetServerManager().GetNodeHealth(c.appConfig.GetHost())
 }
 
 // AddChangeListener 增加变更监控
@@ -267,3 +558,32 @@ func (c *internalClient) GetChangeListeners() *list.List {
 func (c *internalClient) UseEventDispatch() {
 	c.AddChangeListener(storage.UseEventDispatch())
 }
+
+// Close 停止长轮询、服务器列表同步等后台任务，中断进行中的请求并等待其退出及备份文件写入完成
+// ctx 结束前后台任务仍未退出时返回错误，可重复调用
+func (c *internalClient) Close(ctx context.Context) error {
+	if c.cancel != nil {
+		c.cancel()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		//后台任务退出后不再有新的备份，等待已提交的备份写入完成
+		if c.cache != nil {
+			c.cache.WaitBackup()
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		if closer, ok := c.components.GetHTTPClientFactory().(httpclient.IdleConnectionsCloser); ok {
+			closer.CloseIdleConnections()
+		}
+		c.logger().Info("agollo client closed")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("close agollo client fail, background tasks still running: %w", ctx.Err())
+	}
+}
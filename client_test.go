@@ -18,18 +18,24 @@
 package agollo
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/snailzed/agollo/v4/agcache/memory"
+	"github.com/snailzed/agollo/v4/cluster/random"
+	"github.com/snailzed/agollo/v4/cluster/roundrobin"
+	"github.com/snailzed/agollo/v4/cluster/weighted"
 	"github.com/snailzed/agollo/v4/env/config"
 	"github.com/snailzed/agollo/v4/env/server"
 
-	_ "github.com/snailzed/agollo/v4/env/file/json"
+	jsonFile "github.com/snailzed/agollo/v4/env/file/json"
 	"github.com/snailzed/agollo/v4/extension"
 	"github.com/snailzed/agollo/v4/storage"
 	. "github.com/tevid/gohamcrest"
@@ -352,3 +358,246 @@ func TestUseEventDispatch(t *testing.T) {
 	l := cache.GetChangeListeners()
 	Assert(t, l.Len(), Equal(1))
 }
+
+func TestGetConfigAndInitContext(t *testing.T) {
+	client := createMockApolloConfig(120)
+	handlerMap := make(map[string]func(http.ResponseWriter, *http.Request), 1)
+	handlerMap["abc1"] = onlyNormalSecondConfigResponse
+	server := runMockConfigFilesServer(handlerMap, nil, client.appConfig)
+	client.appConfig.IP = server.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	Assert(t, client.GetConfigAndInitContext(ctx, "abc1"), NilVal())
+
+	config := client.GetConfigAndInitContext(context.Background(), "abc1")
+	Assert(t, config, NotNilVal())
+	Assert(t, config.GetValue("key1-1"), Equal("value1-1"))
+}
+
+func TestBindNamespace(t *testing.T) {
+	client := createMockApolloConfig(120)
+
+	cfg := &struct {
+		String string `apollo:"string"`
+		Int    int    `apollo:"int"`
+		Float  float64
+		Bool   bool   `apollo:"bool"`
+		Joe    string `apollo:"joe,default=j"`
+	}{}
+	err := client.BindNamespace(storage.GetDefaultNamespace(), cfg)
+	Assert(t, err, NilVal())
+	Assert(t, cfg.String, Equal("value"))
+	Assert(t, cfg.Int, Equal(1))
+	Assert(t, cfg.Float, Equal(float64(0)))
+	Assert(t, cfg.Bool, Equal(true))
+	Assert(t, cfg.Joe, Equal("j"))
+}
+
+func TestWatch(t *testing.T) {
+	client := createMockApolloConfig(120)
+
+	type watchConfig struct {
+		String string `apollo:"string"`
+		Int    int    `apollo:"int"`
+	}
+	cfg := &watchConfig{}
+	snapshot, err := client.Watch(storage.GetDefaultNamespace(), cfg)
+	Assert(t, err, NilVal())
+	Assert(t, snapshot.Load(), Equal(cfg))
+	Assert(t, cfg.String, Equal("value"))
+
+	update := func(configurations map[string]interface{}) {
+		apolloConfig := &config.ApolloConfig{}
+		apolloConfig.NamespaceName = storage.GetDefaultNamespace()
+		apolloConfig.Configurations = configurations
+		client.cache.UpdateApolloConfig(apolloConfig, client.getAppConfig)
+	}
+	update(map[string]interface{}{"string": "value2", "int": 2})
+
+	var current *watchConfig
+	for i := 0; i < 100; i++ {
+		current = snapshot.Load().(*watchConfig)
+		if current != cfg {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	Assert(t, current.String, Equal("value2"))
+	Assert(t, current.Int, Equal(2))
+	//首次绑定的 target 不会被修改
+	Assert(t, cfg.String, Equal("value"))
+
+	//绑定失败时保留旧的快照
+	update(map[string]interface{}{"string": "value3", "int": "abc"})
+	time.Sleep(100 * time.Millisecond)
+	Assert(t, snapshot.Load(), Equal(current))
+
+	snapshot.Stop()
+	update(map[string]interface{}{"string": "value4", "int": 4})
+	time.Sleep(100 * time.Millisecond)
+	Assert(t, snapshot.Load(), Equal(current))
+
+	_, err = client.Watch(storage.GetDefaultNamespace(), watchConfig{})
+	Assert(t, err, NotNilVal())
+}
+
+type rollbackListener struct {
+	changes chan *storage.ChangeEvent
+}
+
+func (l *rollbackListener) OnChange(event *storage.ChangeEvent) {
+	l.changes <- event
+}
+
+func (l *rollbackListener) OnNewestChange(event *storage.FullChangeEvent) {
+}
+
+func TestRollbackToLocalRelease(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agollo-rollback")
+	Assert(t, err, NilVal())
+	defer os.RemoveAll(dir)
+
+	client := createMockApolloConfig(120)
+	client.appConfig.BackupConfigPath = dir
+	client.appConfig.IsBackupConfig = false
+	client.components = &extension.Components{FileHandler: &jsonFile.FileHandler{}}
+
+	old := &config.ApolloConfig{}
+	old.AppID = client.appConfig.AppID
+	old.NamespaceName = storage.GetDefaultNamespace()
+	old.ReleaseKey = "old-release"
+	old.Configurations = map[string]interface{}{"string": "old"}
+	err = client.components.GetFileHandler().WriteConfigFile(old, dir)
+	Assert(t, err, NilVal())
+
+	releases, err := client.ListLocalReleases(storage.GetDefaultNamespace())
+	Assert(t, err, NilVal())
+	Assert(t, len(releases), Equal(1))
+	Assert(t, releases[0].ReleaseKey, Equal("old-release"))
+
+	listener := &rollbackListener{changes: make(chan *storage.ChangeEvent, 1)}
+	client.AddChangeListener(listener)
+	defer client.RemoveChangeListener(listener)
+
+	err = client.RollbackToLocalRelease(storage.GetDefaultNamespace(), "old-release")
+	Assert(t, err, NilVal())
+	Assert(t, client.GetStringValue("string", ""), Equal("old"))
+
+	select {
+	case event := <-listener.changes:
+		Assert(t, event.Namespace, Equal(storage.GetDefaultNamespace()))
+		Assert(t, event.Changes["string"].NewValue, Equal("old"))
+	case <-time.After(time.Second):
+		t.Fatal("rollback should fire change event")
+	}
+
+	err = client.RollbackToLocalRelease(storage.GetDefaultNamespace(), "missing")
+	Assert(t, err, NotNilVal())
+}
+
+func TestReloadOverrides(t *testing.T) {
+	client := createMockApolloConfig(120)
+	client.appConfig.NamespaceName = storage.GetDefaultNamespace()
+	env := storage.OverrideEnvPrefix + "application_string"
+	os.Setenv(env, "override")
+	defer os.Unsetenv(env)
+
+	err := client.ReloadOverrides()
+	Assert(t, err, NilVal())
+	Assert(t, client.GetStringValue("string", ""), Equal("override"))
+
+	values := client.GetEffectiveValues(storage.GetDefaultNamespace())
+	for _, v := range values {
+		if v.Key == "string" {
+			Assert(t, v.Value, Equal("override"))
+			Assert(t, v.RemoteValue, Equal("value"))
+			Assert(t, v.Source, Equal(storage.OverrideSource))
+		}
+	}
+
+	os.Unsetenv(env)
+	err = client.ReloadOverrides()
+	Assert(t, err, NilVal())
+	Assert(t, client.GetStringValue("string", ""), Equal("value"))
+
+	client.appConfig.OverrideConfigPath = "missing-overrides.json"
+	Assert(t, client.ReloadOverrides(), NotNilVal())
+}
+
+func TestLookupNamespaces(t *testing.T) {
+	client := createMockApolloConfig(120)
+	client.cache.UpdateApolloConfigCache(map[string]interface{}{
+		"string": "common",
+		"port":   "8080",
+		"hosts":  []string{"a", "b"},
+	}, 120, "common.yaml", *client.appConfig)
+	client.appConfig.LookupNamespaces = "application, common.yaml,missing"
+
+	Assert(t, client.GetStringValue("string", ""), Equal("value"))
+	Assert(t, client.GetIntValue("port", 0), Equal(8080))
+	Assert(t, client.GetStringSliceValue("hosts", nil), Equal([]string{"a", "b"}))
+	Assert(t, client.GetStringValue("none", "default"), Equal("default"))
+
+	value, namespace := client.LookupValue("string")
+	Assert(t, value, Equal("value"))
+	Assert(t, namespace, Equal("application"))
+	value, namespace = client.LookupValue("port")
+	Assert(t, value, Equal("8080"))
+	Assert(t, namespace, Equal("common.yaml"))
+	value, namespace = client.LookupValue("none")
+	Assert(t, value, Equal(""))
+	Assert(t, namespace, Equal(""))
+
+	client.appConfig.LookupNamespaces = "common.yaml,application"
+	Assert(t, client.GetStringValue("string", ""), Equal("common"))
+	Assert(t, client.GetIntValue("int", 0), Equal(1))
+}
+
+func TestGetServerHealth(t *testing.T) {
+	client := createMockApolloConfig(120)
+	manager := server.CreateManager()
+	client.components = &extension.Components{ServerManager: manager}
+	Assert(t, client.GetServerHealth(), NilVal())
+
+	host := "http://10.0.0.1:8080/"
+	manager.SetServers(client.appConfig.GetHost(), map[string]*config.ServerInfo{
+		host: {HomepageURL: host},
+	})
+	manager.SetDownNodeWithConfig(client.appConfig.GetHost(), host, client.appConfig.CircuitBreaker)
+	healths := client.GetServerHealth()
+	Assert(t, len(healths), Equal(1))
+	Assert(t, healths[0].HomepageURL, Equal(host))
+	Assert(t, healths[0].State, Equal(server.StateOpen))
+}
+
+func TestInitLoadBalance(t *testing.T) {
+	client := createMockApolloConfig(120)
+	Assert(t, client.initLoadBalance(), NilVal())
+	Assert(t, client.components.LoadBalance, NilVal())
+
+	client.appConfig.LoadBalance = weighted.Name
+	client.appConfig.ServerWeights = map[string]int{"a": 2}
+	Assert(t, client.initLoadBalance(), NilVal())
+	balance := client.components.LoadBalance.(*weighted.Weighted)
+	Assert(t, balance.Weights["a"], Equal(2))
+
+	//WithLoadBalance 设置的优先
+	custom := &random.Random{}
+	client.components.LoadBalance = custom
+	client.appConfig.LoadBalance = roundrobin.Name
+	Assert(t, client.initLoadBalance(), NilVal())
+	Assert(t, client.components.LoadBalance, Equal(custom))
+
+	client.components.LoadBalance = nil
+	Assert(t, client.initLoadBalance(), NilVal())
+	_, ok := client.components.LoadBalance.(*roundrobin.RoundRobin)
+	Assert(t, ok, Equal(true))
+
+	_, err := New(&config.AppConfig{
+		AppID:       "test",
+		IP:          "http://localhost:8080",
+		LoadBalance: "unknown",
+	})
+	Assert(t, err, NotNilVal())
+}
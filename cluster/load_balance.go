@@ -18,11 +18,26 @@
 package cluster
 
 import (
+	"sort"
+
 	"github.com/snailzed/agollo/v4/env/config"
 )
 
 //LoadBalance 负载均衡器
 type LoadBalance interface {
 	//Load 负载均衡，获取对应服务信息
+	//servers 只包含熔断未打开的节点，没有可用节点时返回 nil
 	Load(servers map[string]*config.ServerInfo) *config.ServerInfo
 }
+
+//SortedHosts 按字典序返回节点地址，便于在 map 的随机遍历顺序上实现确定的选择
+func SortedHosts(servers map[string]*config.ServerInfo) []string {
+	hosts := make([]string, 0, len(servers))
+	for host, server := range servers {
+		if server != nil {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
@@ -0,0 +1,53 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package random
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/snailzed/agollo/v4/cluster"
+	"github.com/snailzed/agollo/v4/env/config"
+)
+
+const (
+	//Name AppConfig.LoadBalance 中使用的名称
+	Name = "random"
+)
+
+//Random 随机选择节点
+type Random struct {
+	lock sync.Mutex
+	rand *rand.Rand
+}
+
+//Load 负载均衡
+func (r *Random) Load(servers map[string]*config.ServerInfo) *config.ServerInfo {
+	hosts := cluster.SortedHosts(servers)
+	if len(hosts) == 0 {
+		return nil
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.rand == nil {
+		r.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return servers[hosts[r.rand.Intn(len(hosts))]]
+}
@@ -0,0 +1,46 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package random
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/snailzed/agollo/v4/agollotest/conformance"
+	"github.com/snailzed/agollo/v4/env/config"
+	. "github.com/tevid/gohamcrest"
+)
+
+func TestRandomConformance(t *testing.T) {
+	conformance.TestLoadBalance(t, &Random{})
+}
+
+func TestRandomLoad(t *testing.T) {
+	balance := &Random{}
+	servers := make(map[string]*config.ServerInfo)
+	for i := 0; i < 5; i++ {
+		host := fmt.Sprintf("http://10.0.0.%d:8080/", i)
+		servers[host] = &config.ServerInfo{HomepageURL: host}
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		counts[balance.Load(servers).HomepageURL]++
+	}
+	Assert(t, len(counts), Equal(len(servers)))
+}
@@ -18,23 +18,42 @@
 package roundrobin
 
 import (
+	"strings"
+	"sync"
+
+	"github.com/snailzed/agollo/v4/cluster"
 	"github.com/snailzed/agollo/v4/env/config"
 )
 
-//RoundRobin 轮询调度
+const (
+	//Name AppConfig.LoadBalance 中使用的名称
+	Name = "roundrobin"
+
+	//maxPositions 最多记录的服务器集合数，熔断导致可用节点集合频繁变化时避免无限增长
+	maxPositions = 64
+)
+
+//RoundRobin 轮询调度，按节点地址排序后依次选择，不同的服务器集合分别记录位置
 type RoundRobin struct {
+	lock sync.Mutex
+	//服务器集合 -> 下一次选择的位置
+	positions map[string]int
 }
 
 //Load 负载均衡
 func (r *RoundRobin) Load(servers map[string]*config.ServerInfo) *config.ServerInfo {
-	var returnServer *config.ServerInfo
-	for _, server := range servers {
-		// if some node has down then select next node
-		if server.IsDown {
-			continue
-		}
-		returnServer = server
-		break
+	hosts := cluster.SortedHosts(servers)
+	if len(hosts) == 0 {
+		return nil
+	}
+	key := strings.Join(hosts, ",")
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.positions[key]; !ok && (r.positions == nil || len(r.positions) >= maxPositions) {
+		r.positions = make(map[string]int)
 	}
-	return returnServer
+	position := r.positions[key] % len(hosts)
+	r.positions[key] = (position + 1) % len(hosts)
+	return servers[hosts[position]]
 }
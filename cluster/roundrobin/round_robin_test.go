@@ -20,6 +20,7 @@ package roundrobin
 import (
 	"testing"
 
+	"github.com/snailzed/agollo/v4/agollotest/conformance"
 	"github.com/snailzed/agollo/v4/component/serverlist"
 	"github.com/snailzed/agollo/v4/env/config"
 	"github.com/snailzed/agollo/v4/env/server"
@@ -101,25 +102,25 @@ func TestSelectHost(t *testing.T) {
 
 	//check servers
 	server.SetNextTryConnTime(appConfig.GetHost(), 5)
-	firstHost := balanace.Load(server.GetServers(appConfig.GetHost())).HomepageURL
+	firstHost := balanace.Load(server.GetAvailableServers(appConfig.GetHost())).HomepageURL
 	Assert(t, host, NotEqual(firstHost))
 	server.SetDownNode(appConfig.GetHost(), firstHost)
 
-	secondHost := balanace.Load(server.GetServers(appConfig.GetHost())).HomepageURL
+	secondHost := balanace.Load(server.GetAvailableServers(appConfig.GetHost())).HomepageURL
 	Assert(t, host, NotEqual(secondHost))
 	Assert(t, firstHost, NotEqual(secondHost))
 	server.SetDownNode(appConfig.GetHost(), secondHost)
 
-	thirdHost := balanace.Load(server.GetServers(appConfig.GetHost())).HomepageURL
+	thirdHost := balanace.Load(server.GetAvailableServers(appConfig.GetHost())).HomepageURL
 	Assert(t, host, NotEqual(thirdHost))
 	Assert(t, firstHost, NotEqual(thirdHost))
 	Assert(t, secondHost, NotEqual(thirdHost))
 
-	for _, info := range server.GetServers(appConfig.GetHost()) {
-		info.IsDown = true
+	for host := range server.GetServers(appConfig.GetHost()) {
+		server.SetDownNode(appConfig.GetHost(), host)
 	}
 
-	Assert(t, balanace.Load(server.GetServers(appConfig.GetHost())), NilVal())
+	Assert(t, balanace.Load(server.GetAvailableServers(appConfig.GetHost())), NilVal())
 
 	//no servers
 	//servers = make(map[string]*serverInfo, 0)
@@ -140,3 +141,30 @@ func trySyncServerIPList(appConfig config.AppConfig) {
 	m := serverMap.(map[string]*config.ServerInfo)
 	server.SetServers(appConfig.GetHost(), m)
 }
+
+func TestRoundRobinConformance(t *testing.T) {
+	conformance.TestLoadBalance(t, &RoundRobin{})
+}
+
+func TestRoundRobinLoad(t *testing.T) {
+	balance := &RoundRobin{}
+	servers := map[string]*config.ServerInfo{
+		"a": {HomepageURL: "a"},
+		"b": {HomepageURL: "b"},
+		"c": {HomepageURL: "c"},
+	}
+	subset := map[string]*config.ServerInfo{
+		"a": servers["a"],
+		"c": servers["c"],
+	}
+
+	//按地址顺序依次选择
+	for i := 0; i < 2; i++ {
+		Assert(t, balance.Load(servers).HomepageURL, Equal("a"))
+		Assert(t, balance.Load(servers).HomepageURL, Equal("b"))
+		//不同的服务器集合分别记录位置
+		Assert(t, balance.Load(subset).HomepageURL, Equal("a"))
+		Assert(t, balance.Load(servers).HomepageURL, Equal("c"))
+		Assert(t, balance.Load(subset).HomepageURL, Equal("c"))
+	}
+}
@@ -0,0 +1,88 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package weighted
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/snailzed/agollo/v4/cluster"
+	"github.com/snailzed/agollo/v4/env/config"
+)
+
+const (
+	//Name AppConfig.LoadBalance 中使用的名称
+	Name = "weighted"
+	//DefaultWeight 未配置权重的节点使用的权重
+	DefaultWeight = 1
+)
+
+//Weighted 按权重随机选择节点
+//权重使用 Weights 中的配置，apollo 返回的服务列表不包含权重信息，未配置或不大于0时使用 DefaultWeight
+type Weighted struct {
+	//Weights 节点权重，key 为节点的 HomepageURL
+	Weights map[string]int
+
+	lock sync.Mutex
+	rand *rand.Rand
+}
+
+//CreateWeighted 创建按权重选择节点的负载均衡器
+func CreateWeighted(weights map[string]int) *Weighted {
+	return &Weighted{
+		Weights: weights,
+	}
+}
+
+//GetWeight 获取节点的权重
+func (w *Weighted) GetWeight(server *config.ServerInfo) int {
+	if weight := w.Weights[server.HomepageURL]; weight > 0 {
+		return weight
+	}
+	return DefaultWeight
+}
+
+//Load 负载均衡
+func (w *Weighted) Load(servers map[string]*config.ServerInfo) *config.ServerInfo {
+	hosts := cluster.SortedHosts(servers)
+	if len(hosts) == 0 {
+		return nil
+	}
+	weights := make([]int, len(hosts))
+	total := 0
+	for i, host := range hosts {
+		weights[i] = w.GetWeight(servers[host])
+		total += weights[i]
+	}
+
+	w.lock.Lock()
+	if w.rand == nil {
+		w.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	n := w.rand.Intn(total)
+	w.lock.Unlock()
+
+	for i, host := range hosts {
+		if n < weights[i] {
+			return servers[host]
+		}
+		n -= weights[i]
+	}
+	return servers[hosts[len(hosts)-1]]
+}
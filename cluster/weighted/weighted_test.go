@@ -0,0 +1,54 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package weighted
+
+import (
+	"testing"
+
+	"github.com/snailzed/agollo/v4/agollotest/conformance"
+	"github.com/snailzed/agollo/v4/env/config"
+	. "github.com/tevid/gohamcrest"
+)
+
+func TestWeightedConformance(t *testing.T) {
+	conformance.TestLoadBalance(t, &Weighted{})
+}
+
+func TestGetWeight(t *testing.T) {
+	balance := CreateWeighted(map[string]int{"a": 5, "c": 0})
+	Assert(t, balance.GetWeight(&config.ServerInfo{HomepageURL: "a"}), Equal(5))
+	Assert(t, balance.GetWeight(&config.ServerInfo{HomepageURL: "b"}), Equal(DefaultWeight))
+	Assert(t, balance.GetWeight(&config.ServerInfo{HomepageURL: "c"}), Equal(DefaultWeight))
+	Assert(t, (&Weighted{}).GetWeight(&config.ServerInfo{HomepageURL: "a"}), Equal(DefaultWeight))
+}
+
+func TestWeightedLoad(t *testing.T) {
+	balance := CreateWeighted(map[string]int{"a": 1, "b": 3})
+	servers := map[string]*config.ServerInfo{
+		"a": {HomepageURL: "a"},
+		"b": {HomepageURL: "b"},
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 4000; i++ {
+		counts[balance.Load(servers).HomepageURL]++
+	}
+	//期望约 1000:3000
+	Assert(t, counts["a"] > 700 && counts["a"] < 1300, Equal(true))
+	Assert(t, counts["a"]+counts["b"], Equal(4000))
+}
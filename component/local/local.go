@@ -0,0 +1,234 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package local
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/snailzed/agollo/v4/constant"
+	"github.com/snailzed/agollo/v4/env/config"
+	"github.com/snailzed/agollo/v4/extension"
+	"github.com/snailzed/agollo/v4/storage"
+)
+
+//defaultWatchInterval 检查本地文件变化的间隔
+const defaultWatchInterval = time.Second
+
+//Load 本地模式下加载 namespace 配置，配置了 LocalConfigPath 时读取目录中的配置文件，否则读取备份文件
+func Load(components *extension.Components, appConfig config.AppConfig, namespace string) (*config.ApolloConfig, error) {
+	if appConfig.LocalConfigPath == "" {
+		return components.GetFileHandler().LoadConfigFile(appConfig.BackupConfigPath, appConfig.AppID, namespace)
+	}
+
+	fileName := GetConfigFile(appConfig.LocalConfigPath, namespace)
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	configurations, err := parseContent(components, namespace, string(b))
+	if err != nil {
+		return nil, fmt.Errorf("parse local config file %s fail: %s", fileName, err)
+	}
+
+	apolloConfig := &config.ApolloConfig{}
+	apolloConfig.Init(appConfig.AppID, appConfig.Cluster, namespace)
+	apolloConfig.ReleaseKey = releaseKey(b)
+	apolloConfig.Configurations = configurations
+	if !isProperties(namespace) {
+		apolloConfig.Content = string(b)
+	}
+	return apolloConfig, nil
+}
+
+//LoadAll 本地模式下加载所有 namespace 的配置，加载失败的 namespace 记录日志后跳过
+func LoadAll(components *extension.Components, appConfig config.AppConfig) []*config.ApolloConfig {
+	configs := make([]*config.ApolloConfig, 0)
+	config.SplitNamespaces(appConfig.NamespaceName, func(namespace string) {
+		apolloConfig, err := Load(components, appConfig, namespace)
+		if err != nil {
+			components.GetLogger().Errorf("load local config fail, namespace:%s, error:%s", namespace, err)
+			return
+		}
+		if apolloConfig != nil {
+			configs = append(configs, apolloConfig)
+		}
+	})
+	return configs
+}
+
+//GetConfigFile 本地配置文件路径，没有格式后缀的 namespace 对应 {namespace}.properties
+func GetConfigFile(configDir string, namespace string) string {
+	if path.Ext(namespace) == "" {
+		namespace += string(constant.Properties)
+	}
+	return filepath.Join(configDir, namespace)
+}
+
+func isProperties(namespace string) bool {
+	ext := path.Ext(namespace)
+	return ext == "" || constant.ConfigFileFormat(ext) == constant.Properties
+}
+
+func parseContent(components *extension.Components, namespace string, content string) (map[string]interface{}, error) {
+	format := constant.Properties
+	if !isProperties(namespace) {
+		format = constant.ConfigFileFormat(path.Ext(namespace))
+	}
+	parser := components.GetFormatParser(format)
+	if parser == nil {
+		return nil, fmt.Errorf("no parser for format %s", format)
+	}
+	configurations, err := parser.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	if configurations == nil {
+		configurations = make(map[string]interface{})
+	}
+	return configurations, nil
+}
+
+func releaseKey(b []byte) string {
+	sum := sha1.Sum(b)
+	return "local-" + hex.EncodeToString(sum[:8])
+}
+
+//ConfigComponent 本地模式下定时检查配置文件，文件变化时更新配置并触发变更事件
+type ConfigComponent struct {
+	appConfigFunc func() config.AppConfig
+	cache         *storage.Cache
+	ctx           context.Context
+	components    *extension.Components
+	interval      time.Duration
+	//contents 上次读取的文件内容，内容未变化时不再解析
+	contents map[string][]byte
+	//configurations 上次加载的配置，解析结果相同时不更新
+	configurations map[string]map[string]interface{}
+}
+
+//SetAppConfig nolint
+func (c *ConfigComponent) SetAppConfig(appConfigFunc func() config.AppConfig) {
+	c.appConfigFunc = appConfigFunc
+}
+
+//SetCache nolint
+func (c *ConfigComponent) SetCache(cache *storage.Cache) {
+	c.cache = cache
+}
+
+//SetComponents 设置客户端级别的扩展组件
+func (c *ConfigComponent) SetComponents(components *extension.Components) {
+	c.components = components
+}
+
+//SetContext 设置 ctx，ctx 取消后停止检查
+func (c *ConfigComponent) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+//Start 启动本地配置文件检查
+func (c *ConfigComponent) Start() {
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	interval := c.interval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	//首次检查时重新加载一次，启动后到此期间的修改同样会触发变更事件，未修改时不会产生事件
+	c.contents = make(map[string][]byte)
+	c.configurations = make(map[string]map[string]interface{})
+	c.check()
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			c.check()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+//check 读取所有 namespace 的配置文件，解析后的配置变化时更新，文件被删除时删除已加载的配置
+func (c *ConfigComponent) check() {
+	appConfig := c.appConfigFunc()
+	config.SplitNamespaces(appConfig.NamespaceName, func(namespace string) {
+		fileName := c.getConfigFile(appConfig, namespace)
+		b, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				c.components.GetLogger().Errorf("read local config file %s fail, error:%s", fileName, err)
+				return
+			}
+			c.remove(appConfig, namespace)
+			return
+		}
+		if old, ok := c.contents[namespace]; ok && bytes.Equal(old, b) {
+			return
+		}
+		c.contents[namespace] = b
+
+		apolloConfig, err := Load(c.components, appConfig, namespace)
+		if err != nil {
+			c.components.GetLogger().Errorf("load local config fail, namespace:%s, error:%s", namespace, err)
+			return
+		}
+		//备份文件的校验和等内容变化不代表配置变化
+		if old, ok := c.configurations[namespace]; ok && reflect.DeepEqual(old, apolloConfig.Configurations) {
+			return
+		}
+		c.configurations[namespace] = apolloConfig.Configurations
+		c.cache.UpdateApolloConfig(apolloConfig, c.appConfigFunc)
+	})
+}
+
+//remove 配置文件被删除，已加载的配置全部删除并触发变更事件，文件重新出现时再次加载
+func (c *ConfigComponent) remove(appConfig config.AppConfig, namespace string) {
+	delete(c.contents, namespace)
+	if _, ok := c.configurations[namespace]; !ok {
+		return
+	}
+	delete(c.configurations, namespace)
+
+	apolloConfig := &config.ApolloConfig{}
+	apolloConfig.Init(appConfig.AppID, appConfig.Cluster, namespace)
+	apolloConfig.Configurations = make(map[string]interface{})
+	c.cache.UpdateApolloConfig(apolloConfig, c.appConfigFunc)
+}
+
+func (c *ConfigComponent) getConfigFile(appConfig config.AppConfig, namespace string) string {
+	if appConfig.LocalConfigPath == "" {
+		return c.components.GetFileHandler().GetConfigFile(appConfig.BackupConfigPath, appConfig.AppID, namespace)
+	}
+	return GetConfigFile(appConfig.LocalConfigPath, namespace)
+}
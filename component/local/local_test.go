@@ -0,0 +1,201 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package local
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/snailzed/agollo/v4/agcache/memory"
+	"github.com/snailzed/agollo/v4/constant"
+	"github.com/snailzed/agollo/v4/env/config"
+	jsonFile "github.com/snailzed/agollo/v4/env/file/json"
+	"github.com/snailzed/agollo/v4/extension"
+	"github.com/snailzed/agollo/v4/storage"
+	"github.com/snailzed/agollo/v4/utils/parse/properties"
+	"github.com/snailzed/agollo/v4/utils/parse/yaml"
+	. "github.com/tevid/gohamcrest"
+)
+
+func init() {
+	extension.SetFileHandler(&jsonFile.FileHandler{})
+	extension.SetCacheFactory(&memory.DefaultCacheFactory{})
+	extension.AddFormatParser(constant.Properties, &properties.Parser{})
+	extension.AddFormatParser(constant.YAML, &yaml.Parser{})
+}
+
+type changeListener struct {
+	changes chan *storage.ChangeEvent
+	newest  chan *storage.FullChangeEvent
+}
+
+func createChangeListener() *changeListener {
+	return &changeListener{
+		changes: make(chan *storage.ChangeEvent, 1),
+		newest:  make(chan *storage.FullChangeEvent, 1),
+	}
+}
+
+func (c *changeListener) OnChange(event *storage.ChangeEvent) {
+	c.changes <- event
+}
+
+func (c *changeListener) OnNewestChange(event *storage.FullChangeEvent) {
+	select {
+	case c.newest <- event:
+	default:
+	}
+}
+
+func createLocalDir(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "agollo-local")
+	Assert(t, err, NilVal())
+	for name, content := range files {
+		Assert(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644), NilVal())
+	}
+	return dir
+}
+
+func TestLoadFromDir(t *testing.T) {
+	dir := createLocalDir(t, map[string]string{
+		"application.properties": "a=1\nb=2\n",
+		"app.yaml":               "db:\n  port: 3306\n",
+	})
+	defer os.RemoveAll(dir)
+
+	appConfig := config.AppConfig{
+		AppID:           "local",
+		Cluster:         "default",
+		NamespaceName:   "application,app.yaml,missing.yaml",
+		Mode:            config.ModeLocal,
+		LocalConfigPath: dir,
+	}
+	configs := LoadAll(nil, appConfig)
+	Assert(t, len(configs), Equal(2))
+
+	Assert(t, configs[0].NamespaceName, Equal("application"))
+	Assert(t, configs[0].Configurations["a"], Equal("1"))
+	Assert(t, configs[0].Content, Equal(""))
+	Assert(t, configs[0].ReleaseKey == "", Equal(false))
+
+	Assert(t, configs[1].NamespaceName, Equal("app.yaml"))
+	Assert(t, configs[1].Configurations["db.port"], Equal(3306))
+	Assert(t, configs[1].Content, Equal("db:\n  port: 3306\n"))
+
+	_, err := Load(nil, appConfig, "unknown.toml")
+	Assert(t, err, NotNilVal())
+}
+
+func TestLoadFromBackup(t *testing.T) {
+	dir := createLocalDir(t, nil)
+	defer os.RemoveAll(dir)
+
+	backup := &config.ApolloConfig{}
+	backup.Init("local-backup", "default", "application")
+	backup.ReleaseKey = "release"
+	backup.Configurations = map[string]interface{}{"key": "value"}
+	Assert(t, extension.GetFileHandler().WriteConfigFile(backup, dir), NilVal())
+
+	apolloConfig, err := Load(nil, config.AppConfig{
+		AppID:            "local-backup",
+		NamespaceName:    "application",
+		Mode:             config.ModeLocal,
+		BackupConfigPath: dir,
+	}, "application")
+	Assert(t, err, NilVal())
+	Assert(t, apolloConfig.ReleaseKey, Equal("release"))
+	Assert(t, apolloConfig.Configurations["key"], Equal("value"))
+}
+
+func TestConfigComponent(t *testing.T) {
+	dir := createLocalDir(t, map[string]string{"application.properties": "a=1\n"})
+	defer os.RemoveAll(dir)
+
+	appConfig := &config.AppConfig{
+		AppID:           "local",
+		NamespaceName:   "application",
+		Mode:            config.ModeLocal,
+		LocalConfigPath: dir,
+	}
+	appConfig.Init()
+	appConfigFunc := func() config.AppConfig {
+		return *appConfig
+	}
+	cache := storage.CreateNamespaceConfig(appConfig.NamespaceName)
+	for _, apolloConfig := range LoadAll(nil, *appConfig) {
+		cache.UpdateApolloConfig(apolloConfig, appConfigFunc)
+	}
+	listener := createChangeListener()
+	cache.AddChangeListener(listener)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &ConfigComponent{interval: 10 * time.Millisecond}
+	c.SetAppConfig(appConfigFunc)
+	c.SetCache(cache)
+	c.SetContext(ctx)
+	go c.Start()
+	time.Sleep(50 * time.Millisecond)
+
+	Assert(t, ioutil.WriteFile(filepath.Join(dir, "application.properties"), []byte("a=2\n"), 0644), NilVal())
+	select {
+	case event := <-listener.changes:
+		Assert(t, event.Namespace, Equal("application"))
+		Assert(t, event.Changes["a"].OldValue, Equal("1"))
+		Assert(t, event.Changes["a"].NewValue, Equal("2"))
+	case <-time.After(time.Second):
+		t.Fatal("change event must be received after local file changed")
+	}
+	Assert(t, cache.GetConfig("application").GetValue("a"), Equal("2"))
+
+	//内容变化但配置相同时不更新
+	updates := createChangeListener()
+	cache.AddChangeListener(updates)
+	Assert(t, ioutil.WriteFile(filepath.Join(dir, "application.properties"), []byte("# comment\na=2\n"), 0644), NilVal())
+	select {
+	case event := <-updates.newest:
+		t.Fatalf("unexpected update %+v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+	cache.RemoveChangeListener(updates)
+
+	//文件删除时删除已加载的配置
+	Assert(t, os.Remove(filepath.Join(dir, "application.properties")), NilVal())
+	select {
+	case event := <-listener.changes:
+		Assert(t, event.Changes["a"].ChangeType, Equal(storage.DELETED))
+		Assert(t, event.Changes["a"].OldValue, Equal("2"))
+	case <-time.After(time.Second):
+		t.Fatal("delete event must be received after local file removed")
+	}
+	Assert(t, cache.GetConfig("application").GetValue("a"), Equal(""))
+
+	//文件重新出现时再次加载
+	Assert(t, ioutil.WriteFile(filepath.Join(dir, "application.properties"), []byte("a=3\n"), 0644), NilVal())
+	select {
+	case event := <-listener.changes:
+		Assert(t, event.Changes["a"].ChangeType, Equal(storage.ADDED))
+		Assert(t, event.Changes["a"].NewValue, Equal("3"))
+	case <-time.After(time.Second):
+		t.Fatal("change event must be received after local file recreated")
+	}
+}
@@ -18,9 +18,11 @@
 package notify
 
 import (
+	"context"
 	"time"
 
 	"github.com/snailzed/agollo/v4/component/remote"
+	"github.com/snailzed/agollo/v4/extension"
 	"github.com/snailzed/agollo/v4/storage"
 
 	"github.com/snailzed/agollo/v4/env/config"
@@ -34,6 +36,8 @@ const (
 type ConfigComponent struct {
 	appConfigFunc func() config.AppConfig
 	cache         *storage.Cache
+	ctx           context.Context
+	components    *extension.Components
 }
 
 // SetAppConfig nolint
@@ -46,19 +50,49 @@ func (c *ConfigComponent) SetCache(cache *storage.Cache) {
 	c.cache = cache
 }
 
+// SetComponents 设置客户端级别的扩展组件
+func (c *ConfigComponent) SetComponents(components *extension.Components) {
+	c.components = components
+}
+
+// SetContext 设置 ctx，ctx 取消后长轮询退出
+func (c *ConfigComponent) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
 //Start 启动配置组件定时器
 func (c *ConfigComponent) Start() {
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	t2 := time.NewTimer(longPollInterval)
-	instance := remote.CreateAsyncApolloConfig()
+	defer t2.Stop()
+	instance := remote.CreateAsyncApolloConfigWithComponents(c.components)
 	//long poll for sync
 	for {
 		select {
 		case <-t2.C:
-			configs := instance.Sync(c.appConfigFunc)
+			configs := instance.SyncContext(ctx, c.appConfigFunc)
+			if ctx.Err() != nil {
+				return
+			}
 			for _, apolloConfig := range configs {
 				c.cache.UpdateApolloConfig(apolloConfig, c.appConfigFunc)
 			}
-			t2.Reset(longPollInterval)
+			t2.Reset(c.nextInterval(instance))
+		case <-ctx.Done():
+			return
 		}
 	}
 }
+
+//nextInterval 下一次长轮询的等待时长，连续失败时按重试策略退避，避免 apollo 故障时大量实例同时重连
+func (c *ConfigComponent) nextInterval(instance remote.ApolloConfig) time.Duration {
+	counter, ok := instance.(remote.FailureCounter)
+	if !ok || counter.Failures() == 0 {
+		return longPollInterval
+	}
+	appConfig := c.appConfigFunc()
+	return longPollInterval + appConfig.Retry.Backoff(counter.Failures())
+}
@@ -18,17 +18,21 @@
 package notify
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/snailzed/agollo/v4/cluster/roundrobin"
 	_ "github.com/snailzed/agollo/v4/cluster/roundrobin"
+	"github.com/snailzed/agollo/v4/component/remote"
 	"github.com/snailzed/agollo/v4/env"
 	"github.com/snailzed/agollo/v4/env/config"
 	jsonConfig "github.com/snailzed/agollo/v4/env/config/json"
 	_ "github.com/snailzed/agollo/v4/env/file/json"
 	jsonFile "github.com/snailzed/agollo/v4/env/file/json"
 	"github.com/snailzed/agollo/v4/extension"
+	"github.com/snailzed/agollo/v4/storage"
 	. "github.com/tevid/gohamcrest"
 )
 
@@ -102,3 +106,59 @@ func getTestAppConfig() *config.AppConfig {
 	appConfig.Init()
 	return appConfig
 }
+
+func TestConfigComponentStop(t *testing.T) {
+	appConfig := initNotifications()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c := &ConfigComponent{}
+	c.SetAppConfig(func() config.AppConfig {
+		return *appConfig
+	})
+	c.SetCache(storage.CreateNamespaceConfig(appConfig.NamespaceName))
+	c.SetContext(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		c.Start()
+		close(done)
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("config component not stopped")
+	}
+}
+
+type testFailureCounter struct {
+	remote.ApolloConfig
+	failures int
+}
+
+func (c *testFailureCounter) Failures() int {
+	return c.failures
+}
+
+func TestNextInterval(t *testing.T) {
+	appConfig := initNotifications()
+	appConfig.Retry = &config.RetryPolicy{
+		BaseDelay: 1000,
+		MaxDelay:  1000,
+	}
+	c := &ConfigComponent{}
+	c.SetAppConfig(func() config.AppConfig {
+		return *appConfig
+	})
+
+	counter := &testFailureCounter{}
+	Assert(t, c.nextInterval(counter), Equal(longPollInterval))
+
+	counter.failures = 3
+	for i := 0; i < 100; i++ {
+		interval := c.nextInterval(counter)
+		Assert(t, interval >= longPollInterval, Equal(true))
+		Assert(t, interval <= longPollInterval+time.Second, Equal(true))
+	}
+}
@@ -18,21 +18,31 @@
 package remote
 
 import (
+	"context"
+	"path"
 	"strconv"
 	"time"
 
-	"github.com/snailzed/agollo/v4/component/log"
+	"github.com/snailzed/agollo/v4/constant"
 	"github.com/snailzed/agollo/v4/env"
 	"github.com/snailzed/agollo/v4/env/config"
+	"github.com/snailzed/agollo/v4/extension"
 	"github.com/snailzed/agollo/v4/protocol/http"
 )
 
 // AbsApolloConfig 抽象 apollo 配置
 type AbsApolloConfig struct {
 	remoteApollo ApolloConfig
+	components   *extension.Components
 }
 
+// SyncWithNamespace 通过 namespace 同步 apollo 配置
 func (a *AbsApolloConfig) SyncWithNamespace(namespace string, appConfigFunc func() config.AppConfig) *config.ApolloConfig {
+	return a.SyncWithNamespaceContext(context.Background(), namespace, appConfigFunc)
+}
+
+// SyncWithNamespaceContext 通过 namespace 同步 apollo 配置，ctx 取消或超时时中断请求及重试
+func (a *AbsApolloConfig) SyncWithNamespaceContext(ctx context.Context, namespace string, appConfigFunc func() config.AppConfig) *config.ApolloConfig {
 	if appConfigFunc == nil {
 		panic("can not find apollo config!please confirm!")
 	}
@@ -40,24 +50,27 @@ func (a *AbsApolloConfig) SyncWithNamespace(namespace string, appConfigFunc func
 	urlSuffix := a.remoteApollo.GetSyncURI(appConfig, namespace)
 
 	c := &env.ConnectConfig{
-		URI:     urlSuffix,
-		AppID:   appConfig.AppID,
-		Secret:  appConfig.Secret,
-		Timeout: notifyConnectTimeout,
+		URI:         urlSuffix,
+		AppID:       appConfig.AppID,
+		Secret:      appConfig.Secret,
+		Timeout:     notifyConnectTimeout,
+		Components:  a.components,
+		TLS:         appConfig.TLS,
+		RetryPolicy: appConfig.Retry,
 	}
 	if appConfig.SyncServerTimeout > 0 {
 		duration, err := time.ParseDuration(strconv.Itoa(appConfig.SyncServerTimeout) + "s")
 		if err != nil {
-			log.Errorf("parse sync server timeout %s fail, error:%v", err)
+			a.components.GetLogger().Errorf("parse sync server timeout %s fail, error:%v", err)
 			return nil
 		}
 		c.Timeout = duration
 	}
 
 	callback := a.remoteApollo.CallBack(namespace)
-	apolloConfig, err := http.RequestRecovery(appConfig, c, &callback)
+	apolloConfig, err := http.RequestRecoveryWithContext(ctx, appConfig, c, &callback)
 	if err != nil {
-		log.Errorf("request %s fail, error:%v", urlSuffix, err)
+		a.components.GetLogger().Errorf("request %s fail, error:%v", urlSuffix, err)
 		return nil
 	}
 
@@ -68,3 +81,26 @@ func (a *AbsApolloConfig) SyncWithNamespace(namespace string, appConfigFunc func
 
 	return apolloConfig.(*config.ApolloConfig)
 }
+
+// parseConfigurations 使用 namespace 后缀对应的 formatParser 解析 content
+func parseConfigurations(components *extension.Components, apolloConfig *config.ApolloConfig) {
+	parser := components.GetFormatParser(constant.ConfigFileFormat(path.Ext(apolloConfig.NamespaceName)))
+	if parser == nil {
+		parser = components.GetFormatParser(constant.DEFAULT)
+	}
+
+	if parser == nil {
+		return
+	}
+	m, err := parser.Parse(apolloConfig.Configurations[defaultContentKey])
+	if err != nil {
+		components.GetLogger().Debug("GetContent fail ! error:", err)
+	}
+
+	if len(m) > 0 {
+		if content, ok := apolloConfig.Configurations[defaultContentKey].(string); ok {
+			apolloConfig.Content = content
+		}
+		apolloConfig.Configurations = m
+	}
+}
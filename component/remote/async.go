@@ -18,18 +18,18 @@
 package remote
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
-	"path"
+	"sync/atomic"
 	"time"
 
-	"github.com/snailzed/agollo/v4/component/log"
-	"github.com/snailzed/agollo/v4/constant"
 	"github.com/snailzed/agollo/v4/env"
 	"github.com/snailzed/agollo/v4/env/config"
 	"github.com/snailzed/agollo/v4/extension"
 	"github.com/snailzed/agollo/v4/protocol/http"
+	"github.com/snailzed/agollo/v4/protocol/httpclient"
 	"github.com/snailzed/agollo/v4/utils"
 )
 
@@ -42,13 +42,26 @@ const (
 
 // CreateAsyncApolloConfig 创建异步 apollo 配置
 func CreateAsyncApolloConfig() ApolloConfig {
+	return CreateAsyncApolloConfigWithComponents(nil)
+}
+
+// CreateAsyncApolloConfigWithComponents 创建异步 apollo 配置，使用客户端级别的扩展组件
+func CreateAsyncApolloConfigWithComponents(components *extension.Components) ApolloConfig {
 	a := &asyncApolloConfig{}
 	a.remoteApollo = a
+	a.components = components
 	return a
 }
 
 type asyncApolloConfig struct {
 	AbsApolloConfig
+	//连续长轮询失败的次数
+	failures int32
+}
+
+//Failures 连续长轮询失败的次数
+func (a *asyncApolloConfig) Failures() int {
+	return int(atomic.LoadInt32(&a.failures))
 }
 
 func (*asyncApolloConfig) GetNotifyURLSuffix(notifications string, config config.AppConfig) string {
@@ -69,12 +82,23 @@ func (*asyncApolloConfig) GetSyncURI(config config.AppConfig, namespaceName stri
 }
 
 func (a *asyncApolloConfig) Sync(appConfigFunc func() config.AppConfig) []*config.ApolloConfig {
+	return a.SyncContext(context.Background(), appConfigFunc)
+}
+
+func (a *asyncApolloConfig) SyncContext(ctx context.Context, appConfigFunc func() config.AppConfig) []*config.ApolloConfig {
 	appConfig := appConfigFunc()
-	remoteConfigs, err := a.notifyRemoteConfig(appConfigFunc, utils.Empty)
+	remoteConfigs, err := a.notifyRemoteConfig(ctx, appConfigFunc, utils.Empty)
 
 	var apolloConfigs []*config.ApolloConfig
+	// 主动取消时不加载备份配置
+	if ctx.Err() != nil {
+		return apolloConfigs
+	}
 	if err != nil {
-		apolloConfigs = loadBackupConfig(appConfig.NamespaceName, appConfig)
+		atomic.AddInt32(&a.failures, 1)
+		apolloConfigs = loadBackupConfig(a.components, appConfig.NamespaceName, appConfig)
+	} else {
+		atomic.StoreInt32(&a.failures, 0)
 	}
 
 	if len(remoteConfigs) == 0 || len(apolloConfigs) > 0 {
@@ -82,7 +106,7 @@ func (a *asyncApolloConfig) Sync(appConfigFunc func() config.AppConfig) []*confi
 	}
 	//只是拉去有变化的配置, 并更新拉取成功的namespace的notify ID
 	for _, notifyConfig := range remoteConfigs {
-		apolloConfig := a.SyncWithNamespace(notifyConfig.NamespaceName, appConfigFunc)
+		apolloConfig := a.SyncWithNamespaceContext(ctx, notifyConfig.NamespaceName, appConfigFunc)
 		if apolloConfig != nil {
 			appConfig.GetNotificationsMap().UpdateNotify(notifyConfig.NamespaceName, notifyConfig.NotificationID)
 			apolloConfigs = append(apolloConfigs, apolloConfig)
@@ -91,15 +115,17 @@ func (a *asyncApolloConfig) Sync(appConfigFunc func() config.AppConfig) []*confi
 	return apolloConfigs
 }
 
-func (*asyncApolloConfig) CallBack(namespace string) http.CallBack {
+func (a *asyncApolloConfig) CallBack(namespace string) http.CallBack {
 	return http.CallBack{
-		SuccessCallBack:   createApolloConfigWithJSON,
+		SuccessCallBack: func(responseBody []byte, callback http.CallBack) (interface{}, error) {
+			return createApolloConfigWithComponents(a.components, responseBody)
+		},
 		NotModifyCallBack: touchApolloConfigCache,
 		Namespace:         namespace,
 	}
 }
 
-func (a *asyncApolloConfig) notifyRemoteConfig(appConfigFunc func() config.AppConfig, namespace string) ([]*config.Notification, error) {
+func (a *asyncApolloConfig) notifyRemoteConfig(ctx context.Context, appConfigFunc func() config.AppConfig, namespace string) ([]*config.Notification, error) {
 	if appConfigFunc == nil {
 		panic("can not find apollo config!please confirm!")
 	}
@@ -108,14 +134,21 @@ func (a *asyncApolloConfig) notifyRemoteConfig(appConfigFunc func() config.AppCo
 	urlSuffix := a.GetNotifyURLSuffix(notificationsMap.GetNotifies(namespace), appConfig)
 
 	connectConfig := &env.ConnectConfig{
-		URI:    urlSuffix,
-		AppID:  appConfig.AppID,
-		Secret: appConfig.Secret,
+		URI:         urlSuffix,
+		AppID:       appConfig.AppID,
+		Secret:      appConfig.Secret,
+		Components:  a.components,
+		TLS:         appConfig.TLS,
+		RequestType: httpclient.LongPoll,
+		RetryPolicy: appConfig.Retry,
 	}
 	connectConfig.Timeout = notifyConnectTimeout
-	notifies, err := http.RequestRecovery(appConfig, connectConfig, &http.CallBack{
+	if appConfig.LongPollTimeout > 0 {
+		connectConfig.Timeout = time.Duration(appConfig.LongPollTimeout) * time.Second
+	}
+	notifies, err := http.RequestRecoveryWithContext(ctx, appConfig, connectConfig, &http.CallBack{
 		SuccessCallBack: func(responseBody []byte, callback http.CallBack) (interface{}, error) {
-			return toApolloConfig(responseBody)
+			return toApolloConfig(a.components, responseBody)
 		},
 		NotModifyCallBack: touchApolloConfigCache,
 		Namespace:         namespace,
@@ -132,24 +165,24 @@ func touchApolloConfigCache() error {
 	return nil
 }
 
-func toApolloConfig(resBody []byte) ([]*config.Notification, error) {
+func toApolloConfig(components *extension.Components, resBody []byte) ([]*config.Notification, error) {
 	remoteConfig := make([]*config.Notification, 0)
 
 	err := json.Unmarshal(resBody, &remoteConfig)
 
 	if err != nil {
-		log.Error("Unmarshal Msg Fail,Error:", err)
+		components.GetLogger().Error("Unmarshal Msg Fail,Error:", err)
 		return nil, err
 	}
 	return remoteConfig, nil
 }
 
-func loadBackupConfig(namespace string, appConfig config.AppConfig) []*config.ApolloConfig {
+func loadBackupConfig(components *extension.Components, namespace string, appConfig config.AppConfig) []*config.ApolloConfig {
 	apolloConfigs := make([]*config.ApolloConfig, 0)
 	config.SplitNamespaces(namespace, func(namespace string) {
-		c, err := extension.GetFileHandler().LoadConfigFile(appConfig.BackupConfigPath, appConfig.AppID, namespace)
+		c, err := components.GetFileHandler().LoadConfigFile(appConfig.BackupConfigPath, appConfig.AppID, namespace)
 		if err != nil {
-			log.Error("LoadConfigFile error, error", err)
+			components.GetLogger().Error("LoadConfigFile error, error", err)
 			return
 		}
 		if c == nil {
@@ -161,27 +194,16 @@ func loadBackupConfig(namespace string, appConfig config.AppConfig) []*config.Ap
 }
 
 func createApolloConfigWithJSON(b []byte, callback http.CallBack) (o interface{}, err error) {
+	return createApolloConfigWithComponents(nil, b)
+}
+
+func createApolloConfigWithComponents(components *extension.Components, b []byte) (o interface{}, err error) {
 	apolloConfig := &config.ApolloConfig{}
 	err = json.Unmarshal(b, apolloConfig)
 	if utils.IsNotNil(err) {
 		return nil, err
 	}
 
-	parser := extension.GetFormatParser(constant.ConfigFileFormat(path.Ext(apolloConfig.NamespaceName)))
-	if parser == nil {
-		parser = extension.GetFormatParser(constant.DEFAULT)
-	}
-
-	if parser == nil {
-		return apolloConfig, nil
-	}
-	m, err := parser.Parse(apolloConfig.Configurations[defaultContentKey])
-	if err != nil {
-		log.Debug("GetContent fail ! error:", err)
-	}
-
-	if len(m) > 0 {
-		apolloConfig.Configurations = m
-	}
+	parseConfigurations(components, apolloConfig)
 	return apolloConfig, nil
 }
@@ -18,6 +18,7 @@
 package remote
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -246,9 +247,30 @@ func TestApolloConfig_SyncABC1Error(t *testing.T) {
 	Assert(t, appConfig.GetNotificationsMap().GetNotify("abc1"), Equal(int64(-1)))
 }
 
+func TestApolloConfig_SyncFailures(t *testing.T) {
+	a := CreateAsyncApolloConfig().(*asyncApolloConfig)
+	errorServer := runErrorResponse()
+	defer errorServer.Close()
+	appConfig := initNotifications()
+	appConfig.IP = errorServer.URL
+	appConfigFunc := func() config.AppConfig {
+		return *appConfig
+	}
+
+	a.Sync(appConfigFunc)
+	a.Sync(appConfigFunc)
+	Assert(t, a.Failures(), Equal(2))
+
+	server := initMockNotifyAndConfigServer()
+	defer server.Close()
+	appConfig.IP = server.URL
+	a.Sync(appConfigFunc)
+	Assert(t, a.Failures(), Equal(0))
+}
+
 func TestToApolloConfigError(t *testing.T) {
 
-	notified, err := toApolloConfig([]byte("jaskldfjaskl"))
+	notified, err := toApolloConfig(nil, []byte("jaskldfjaskl"))
 	Assert(t, notified, NilVal())
 	Assert(t, err, NotNilVal())
 }
@@ -262,7 +284,7 @@ func TestGetRemoteConfig(t *testing.T) {
 	var err error
 	appConfig := initNotifications()
 	appConfig.IP = server.URL
-	remoteConfigs, err = asyncApollo.notifyRemoteConfig(func() config.AppConfig {
+	remoteConfigs, err = asyncApollo.notifyRemoteConfig(context.Background(), func() config.AppConfig {
 		return *appConfig
 	}, EMPTY)
 
@@ -293,7 +315,7 @@ func TestErrorGetRemoteConfig(t *testing.T) {
 	var remoteConfigs []*config.Notification
 	var err error
 
-	remoteConfigs, err = asyncApollo.notifyRemoteConfig(func() config.AppConfig {
+	remoteConfigs, err = asyncApollo.notifyRemoteConfig(context.Background(), func() config.AppConfig {
 		return *appConfig
 	}, EMPTY)
 
@@ -303,7 +325,8 @@ func TestErrorGetRemoteConfig(t *testing.T) {
 	t.Log("remoteConfigs:", remoteConfigs)
 	t.Log("remoteConfigs size:", len(remoteConfigs))
 
-	Assert(t, "over Max Retry Still Error", Equal(err.Error()))
+	//404 不在可重试的状态码中，不再重试
+	Assert(t, "apollo server returns status code 404", Equal(err.Error()))
 }
 
 func TestCreateApolloConfigWithJson(t *testing.T) {
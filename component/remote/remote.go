@@ -18,6 +18,8 @@
 package remote
 
 import (
+	"context"
+
 	"github.com/snailzed/agollo/v4/env/config"
 	"github.com/snailzed/agollo/v4/protocol/http"
 )
@@ -30,8 +32,18 @@ type ApolloConfig interface {
 	GetSyncURI(config config.AppConfig, namespaceName string) string
 	// Sync 同步获取 Apollo 配置
 	Sync(appConfigFunc func() config.AppConfig) []*config.ApolloConfig
+	// SyncContext 同步获取 Apollo 配置，ctx 取消时中断正在进行的请求
+	SyncContext(ctx context.Context, appConfigFunc func() config.AppConfig) []*config.ApolloConfig
 	// CallBack 根据 namespace 获取 callback 方法
 	CallBack(namespace string) http.CallBack
 	// SyncWithNamespace 通过 namespace 同步 apollo 配置
 	SyncWithNamespace(namespace string, appConfigFunc func() config.AppConfig) *config.ApolloConfig
+	// SyncWithNamespaceContext 通过 namespace 同步 apollo 配置，ctx 取消或超时时中断请求
+	SyncWithNamespaceContext(ctx context.Context, namespace string, appConfigFunc func() config.AppConfig) *config.ApolloConfig
+}
+
+// FailureCounter 可选接口，记录连续同步失败的次数，用于长轮询失败后退避
+type FailureCounter interface {
+	// Failures 连续失败的次数，同步成功后清零
+	Failures() int
 }
@@ -18,13 +18,11 @@
 package remote
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
-	"path"
 
-	"github.com/snailzed/agollo/v4/component/log"
-	"github.com/snailzed/agollo/v4/constant"
 	"github.com/snailzed/agollo/v4/env/config"
 	"github.com/snailzed/agollo/v4/extension"
 	"github.com/snailzed/agollo/v4/protocol/http"
@@ -33,8 +31,14 @@ import (
 
 // CreateSyncApolloConfig 创建同步获取 Apollo 配置
 func CreateSyncApolloConfig() ApolloConfig {
+	return CreateSyncApolloConfigWithComponents(nil)
+}
+
+// CreateSyncApolloConfigWithComponents 创建同步获取 Apollo 配置，使用客户端级别的扩展组件
+func CreateSyncApolloConfigWithComponents(components *extension.Components) ApolloConfig {
 	a := &syncApolloConfig{}
 	a.remoteApollo = a
+	a.components = components
 	return a
 }
 
@@ -55,15 +59,21 @@ func (*syncApolloConfig) GetSyncURI(config config.AppConfig, namespaceName strin
 		url.QueryEscape(config.Label))
 }
 
-func (*syncApolloConfig) CallBack(namespace string) http.CallBack {
+func (a *syncApolloConfig) CallBack(namespace string) http.CallBack {
 	return http.CallBack{
-		SuccessCallBack:   processJSONFiles,
+		SuccessCallBack: func(responseBody []byte, callback http.CallBack) (interface{}, error) {
+			return processJSONFilesWithComponents(a.components, responseBody, callback)
+		},
 		NotModifyCallBack: touchApolloConfigCache,
 		Namespace:         namespace,
 	}
 }
 
 func processJSONFiles(b []byte, callback http.CallBack) (o interface{}, err error) {
+	return processJSONFilesWithComponents(nil, b, callback)
+}
+
+func processJSONFilesWithComponents(components *extension.Components, b []byte, callback http.CallBack) (o interface{}, err error) {
 	apolloConfig := &config.ApolloConfig{}
 	apolloConfig.NamespaceName = callback.Namespace
 
@@ -75,35 +85,30 @@ func processJSONFiles(b []byte, callback http.CallBack) (o interface{}, err erro
 		return nil, err
 	}
 
-	parser := extension.GetFormatParser(constant.ConfigFileFormat(path.Ext(apolloConfig.NamespaceName)))
-	if parser == nil {
-		parser = extension.GetFormatParser(constant.DEFAULT)
-	}
-
-	if parser == nil {
-		return apolloConfig, nil
-	}
-	m, err := parser.Parse(configurations[defaultContentKey])
-	if err != nil {
-		log.Debug("GetContent fail ! error:", err)
-	}
-
-	if len(m) > 0 {
-		apolloConfig.Configurations = m
-	}
+	parseConfigurations(components, apolloConfig)
 	return apolloConfig, nil
 }
 
 func (a *syncApolloConfig) Sync(appConfigFunc func() config.AppConfig) []*config.ApolloConfig {
+	return a.SyncContext(context.Background(), appConfigFunc)
+}
+
+func (a *syncApolloConfig) SyncContext(ctx context.Context, appConfigFunc func() config.AppConfig) []*config.ApolloConfig {
 	appConfig := appConfigFunc()
 	configs := make([]*config.ApolloConfig, 0, 8)
 	config.SplitNamespaces(appConfig.NamespaceName, func(namespace string) {
-		apolloConfig := a.SyncWithNamespace(namespace, appConfigFunc)
+		if ctx.Err() != nil {
+			return
+		}
+		apolloConfig := a.SyncWithNamespaceContext(ctx, namespace, appConfigFunc)
 		if apolloConfig != nil {
 			configs = append(configs, apolloConfig)
 			return
 		}
-		configs = append(configs, loadBackupConfig(appConfig.NamespaceName, appConfig)...)
+		if ctx.Err() != nil {
+			return
+		}
+		configs = append(configs, loadBackupConfig(a.components, appConfig.NamespaceName, appConfig)...)
 	})
 	return configs
 }
@@ -18,6 +18,7 @@
 package remote
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -29,6 +30,8 @@ import (
 	"github.com/snailzed/agollo/v4/env/config"
 	"github.com/snailzed/agollo/v4/env/server"
 	"github.com/snailzed/agollo/v4/extension"
+	http2 "github.com/snailzed/agollo/v4/protocol/http"
+	jsonParser "github.com/snailzed/agollo/v4/utils/parse/json"
 	"github.com/snailzed/agollo/v4/utils/parse/normal"
 	"github.com/snailzed/agollo/v4/utils/parse/properties"
 	"github.com/snailzed/agollo/v4/utils/parse/yaml"
@@ -51,6 +54,7 @@ func init() {
 	extension.AddFormatParser(constant.Properties, &properties.Parser{})
 	extension.AddFormatParser(constant.YML, &yml.Parser{})
 	extension.AddFormatParser(constant.YAML, &yaml.Parser{})
+	extension.AddFormatParser(constant.JSON, &jsonParser.Parser{})
 }
 
 //Normal response
@@ -183,3 +187,42 @@ func TestClientLabelConfigService(t *testing.T) {
 	Assert(t, "gray_value1", Equal(apolloConfig.Configurations["key1"]))
 	Assert(t, "gray_value2", Equal(apolloConfig.Configurations["key2"]))
 }
+
+func TestSyncWithNamespaceContextCancel(t *testing.T) {
+	server := runNormalConfigResponse()
+	newAppConfig := initNotifications()
+	newAppConfig.IP = server.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	apolloConfig := syncApollo.SyncWithNamespaceContext(ctx, "application", func() config.AppConfig {
+		return *newAppConfig
+	})
+	Assert(t, apolloConfig, NilVal())
+}
+
+func TestProcessPropertiesJSONFiles(t *testing.T) {
+	o, err := processJSONFiles([]byte(`{"content":"a=1\nb:2\n# c=3"}`), http2.CallBack{
+		Namespace: "test.properties",
+	})
+	Assert(t, err, NilVal())
+
+	c := o.(*config.ApolloConfig)
+	Assert(t, len(c.Configurations), Equal(2))
+	Assert(t, c.Configurations["a"], Equal("1"))
+	Assert(t, c.Configurations["b"], Equal("2"))
+}
+
+func TestProcessJSONNamespaceJSONFiles(t *testing.T) {
+	o, err := processJSONFiles([]byte(`{"content":"{\"db\":{\"port\":3306},\"servers\":[\"a\"]}"}`), http2.CallBack{
+		Namespace: "test.json",
+	})
+	Assert(t, err, NilVal())
+
+	c := o.(*config.ApolloConfig)
+	Assert(t, len(c.Configurations), Equal(2))
+	Assert(t, c.Configurations["db.port"], Equal(3306))
+	Assert(t, c.Configurations["servers[0]"], Equal("a"))
+	Assert(t, c.Content, Equal(`{"db":{"port":3306},"servers":["a"]}`))
+}
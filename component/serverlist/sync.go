@@ -18,16 +18,15 @@
 package serverlist
 
 import (
+	"context"
 	"encoding/json"
 	"strconv"
 	"time"
 
-	"github.com/snailzed/agollo/v4/env/server"
-
 	"github.com/snailzed/agollo/v4/component"
-	"github.com/snailzed/agollo/v4/component/log"
 	"github.com/snailzed/agollo/v4/env"
 	"github.com/snailzed/agollo/v4/env/config"
+	"github.com/snailzed/agollo/v4/extension"
 	"github.com/snailzed/agollo/v4/protocol/http"
 )
 
@@ -42,26 +41,45 @@ func init() {
 
 //InitSyncServerIPList 初始化同步服务器信息列表
 func InitSyncServerIPList(appConfig func() config.AppConfig) {
-	go component.StartRefreshConfig(&SyncServerIPListComponent{appConfig})
+	go component.StartRefreshConfig(CreateSyncServerIPListComponent(context.Background(), appConfig, nil))
+}
+
+//CreateSyncServerIPListComponent 创建同步服务器列表组件，ctx 取消后组件退出
+//components 为客户端级别的扩展组件，为空时使用全局组件
+func CreateSyncServerIPListComponent(ctx context.Context, appConfig func() config.AppConfig, components *extension.Components) *SyncServerIPListComponent {
+	return &SyncServerIPListComponent{
+		appConfig:  appConfig,
+		ctx:        ctx,
+		components: components,
+	}
 }
 
 //SyncServerIPListComponent set timer for update ip list
 //interval : 20m
 type SyncServerIPListComponent struct {
-	appConfig func() config.AppConfig
+	appConfig  func() config.AppConfig
+	ctx        context.Context
+	components *extension.Components
 }
 
 //Start 启动同步服务器列表
 func (s *SyncServerIPListComponent) Start() {
-	SyncServerIPList(s.appConfig)
-	log.Debug("syncServerIpList started")
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	syncServerIPList(ctx, s.appConfig, s.components)
+	s.components.GetLogger().Debug("syncServerIpList started")
 
 	t2 := time.NewTimer(refreshIPListInterval)
+	defer t2.Stop()
 	for {
 		select {
 		case <-t2.C:
-			SyncServerIPList(s.appConfig)
+			syncServerIPList(ctx, s.appConfig, s.components)
 			t2.Reset(refreshIPListInterval)
+		case <-ctx.Done():
+			return
 		}
 	}
 }
@@ -71,14 +89,26 @@ func (s *SyncServerIPListComponent) Start() {
 //1.update agcache
 //2.store in disk
 func SyncServerIPList(appConfigFunc func() config.AppConfig) (map[string]*config.ServerInfo, error) {
+	return SyncServerIPListContext(context.Background(), appConfigFunc)
+}
+
+//SyncServerIPListContext 同步服务器列表，ctx 取消时中断请求
+func SyncServerIPListContext(ctx context.Context, appConfigFunc func() config.AppConfig) (map[string]*config.ServerInfo, error) {
+	return syncServerIPList(ctx, appConfigFunc, nil)
+}
+
+func syncServerIPList(ctx context.Context, appConfigFunc func() config.AppConfig, components *extension.Components) (map[string]*config.ServerInfo, error) {
 	if appConfigFunc == nil {
 		panic("can not find apollo config!please confirm!")
 	}
 
 	appConfig := appConfigFunc()
 	c := &env.ConnectConfig{
-		AppID:  appConfig.AppID,
-		Secret: appConfig.Secret,
+		AppID:       appConfig.AppID,
+		Secret:      appConfig.Secret,
+		Components:  components,
+		TLS:         appConfig.TLS,
+		RetryPolicy: appConfig.Retry,
 	}
 	if appConfigFunc().SyncServerTimeout > 0 {
 		duration, err := time.ParseDuration(strconv.Itoa(appConfigFunc().SyncServerTimeout) + "s")
@@ -87,34 +117,41 @@ func SyncServerIPList(appConfigFunc func() config.AppConfig) (map[string]*config
 		}
 		c.Timeout = duration
 	}
-	serverMap, err := http.Request(appConfig.GetServicesConfigURL(), appConfig.GetHeader(), c, &http.CallBack{
-		SuccessCallBack: SyncServerIPListSuccessCallBack,
-		AppConfigFunc:   appConfigFunc,
+	serverMap, err := http.RequestWithContext(ctx, appConfig.GetServicesConfigURL(), appConfig.GetHeader(), c, &http.CallBack{
+		SuccessCallBack: func(responseBody []byte, callback http.CallBack) (interface{}, error) {
+			return syncServerIPListSuccessCallBack(components, responseBody)
+		},
+		AppConfigFunc: appConfigFunc,
 	})
 	if serverMap == nil {
 		return nil, err
 	}
 
 	m := serverMap.(map[string]*config.ServerInfo)
-	server.SetServers(appConfig.GetHost(), m)
+	components.GetServerManager().SetServers(appConfig.GetHost(), m)
 	return m, err
 }
 
 //SyncServerIPListSuccessCallBack 同步服务器列表成功后的回调
 func SyncServerIPListSuccessCallBack(responseBody []byte, callback http.CallBack) (o interface{}, err error) {
-	log.Debug("get all server info:", string(responseBody))
+	return syncServerIPListSuccessCallBack(nil, responseBody)
+}
+
+func syncServerIPListSuccessCallBack(components *extension.Components, responseBody []byte) (o interface{}, err error) {
+	logger := components.GetLogger()
+	logger.Debug("get all server info:", string(responseBody))
 
 	tmpServerInfo := make([]*config.ServerInfo, 0)
 
 	err = json.Unmarshal(responseBody, &tmpServerInfo)
 
 	if err != nil {
-		log.Error("Unmarshal json Fail,Error:", err)
+		logger.Error("Unmarshal json Fail,Error:", err)
 		return
 	}
 
 	if len(tmpServerInfo) == 0 {
-		log.Info("get no real server!")
+		logger.Info("get no real server!")
 		return
 	}
 
@@ -81,7 +81,8 @@ func TestSetDownNode(t *testing.T) {
 	downNode := "10.15.128.102:8080"
 	server.SetDownNode(appConfig.GetHost(), downNode)
 
-	info, ok := server.GetServers(appConfig.IP)["http://10.15.128.102:8080/"]
+	_, ok := server.GetServers(appConfig.IP)["http://10.15.128.102:8080/"]
 	Assert(t, ok, Equal(true))
-	Assert(t, info.IsDown, Equal(true))
+	_, ok = server.GetAvailableServers(appConfig.IP)["http://10.15.128.102:8080/"]
+	Assert(t, ok, Equal(false))
 }
@@ -31,6 +31,10 @@ const (
 	YML ConfigFileFormat = ".yml"
 	//YAML YAML
 	YAML ConfigFileFormat = ".yaml"
+	//TOML TOML
+	TOML ConfigFileFormat = ".toml"
+	//HCL HCL
+	HCL ConfigFileFormat = ".hcl"
 	// DEFAULT DEFAULT
 	DEFAULT ConfigFileFormat = ""
 )
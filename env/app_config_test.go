@@ -157,7 +157,7 @@ func TestLoadEnvConfig(t *testing.T) {
 	os.Remove(envConfigFile)
 }
 
-func getNotifyLen(s sync.Map) int {
+func getNotifyLen(s *sync.Map) int {
 	l := 0
 	s.Range(func(k, v interface{}) bool {
 		l++
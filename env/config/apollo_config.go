@@ -77,6 +77,8 @@ type ApolloConnConfig struct {
 type ApolloConfig struct {
 	ApolloConnConfig
 	Configurations map[string]interface{} `json:"configurations"`
+	// Content 非 properties 格式 namespace 解析前的原始内容，不写入 json 备份
+	Content string `json:"-"`
 }
 
 //Init 初始化
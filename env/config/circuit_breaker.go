@@ -0,0 +1,83 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"time"
+)
+
+const (
+	//DefaultBreakerWindowSize 默认统计失败率的最近请求数
+	DefaultBreakerWindowSize = 10
+	//DefaultBreakerMinRequests 默认计算失败率需要的最少请求数
+	DefaultBreakerMinRequests = 3
+	//DefaultBreakerFailureRate 默认打开熔断的失败率
+	DefaultBreakerFailureRate = 0.5
+	//DefaultBreakerCoolDown 默认熔断打开后的冷却时长（秒）
+	DefaultBreakerCoolDown = 30
+)
+
+//CircuitBreakerConfig config server 节点的熔断配置
+//最近 WindowSize 次请求中失败率达到 FailureRateThreshold 时打开熔断，冷却 CoolDown 秒后进入半开状态，
+//半开状态下请求成功则关闭熔断，失败则重新打开
+type CircuitBreakerConfig struct {
+	//WindowSize 统计失败率的最近请求数，为0时使用 DefaultBreakerWindowSize
+	WindowSize int `json:"windowSize"`
+	//MinRequests 窗口内请求数达到该值才计算失败率，为0时使用 DefaultBreakerMinRequests
+	MinRequests int `json:"minRequests"`
+	//FailureRateThreshold 打开熔断的失败率，取值 (0, 1]，为0时使用 DefaultBreakerFailureRate
+	FailureRateThreshold float64 `json:"failureRateThreshold"`
+	//CoolDown 熔断打开后的冷却时长（秒），为0时使用 DefaultBreakerCoolDown
+	CoolDown int `json:"coolDown"`
+}
+
+//GetWindowSize 获取统计失败率的最近请求数
+func (c *CircuitBreakerConfig) GetWindowSize() int {
+	if c == nil || c.WindowSize <= 0 {
+		return DefaultBreakerWindowSize
+	}
+	return c.WindowSize
+}
+
+//GetMinRequests 获取计算失败率需要的最少请求数，不超过 WindowSize
+func (c *CircuitBreakerConfig) GetMinRequests() int {
+	minRequests := DefaultBreakerMinRequests
+	if c != nil && c.MinRequests > 0 {
+		minRequests = c.MinRequests
+	}
+	if windowSize := c.GetWindowSize(); minRequests > windowSize {
+		return windowSize
+	}
+	return minRequests
+}
+
+//GetFailureRateThreshold 获取打开熔断的失败率
+func (c *CircuitBreakerConfig) GetFailureRateThreshold() float64 {
+	if c == nil || c.FailureRateThreshold <= 0 || c.FailureRateThreshold > 1 {
+		return DefaultBreakerFailureRate
+	}
+	return c.FailureRateThreshold
+}
+
+//GetCoolDown 获取熔断打开后的冷却时长
+func (c *CircuitBreakerConfig) GetCoolDown() time.Duration {
+	if c == nil || c.CoolDown <= 0 {
+		return DefaultBreakerCoolDown * time.Second
+	}
+	return time.Duration(c.CoolDown) * time.Second
+}
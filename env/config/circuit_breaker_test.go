@@ -0,0 +1,46 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/tevid/gohamcrest"
+)
+
+func TestCircuitBreakerConfig(t *testing.T) {
+	var empty *CircuitBreakerConfig
+	Assert(t, empty.GetWindowSize(), Equal(DefaultBreakerWindowSize))
+	Assert(t, empty.GetMinRequests(), Equal(DefaultBreakerMinRequests))
+	Assert(t, empty.GetFailureRateThreshold(), Equal(DefaultBreakerFailureRate))
+	Assert(t, empty.GetCoolDown(), Equal(DefaultBreakerCoolDown*time.Second))
+
+	c := &CircuitBreakerConfig{
+		WindowSize:           2,
+		MinRequests:          5,
+		FailureRateThreshold: 2,
+		CoolDown:             1,
+	}
+	Assert(t, c.GetWindowSize(), Equal(2))
+	//最少请求数不超过窗口大小
+	Assert(t, c.GetMinRequests(), Equal(2))
+	//超出 (0, 1] 时使用默认值
+	Assert(t, c.GetFailureRateThreshold(), Equal(DefaultBreakerFailureRate))
+	Assert(t, c.GetCoolDown(), Equal(time.Second))
+}
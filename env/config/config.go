@@ -32,6 +32,9 @@ var (
 	comma                 = ","
 )
 
+//ModeLocal 本地模式，不访问 apollo，只从本地备份或 LocalConfigPath 加载配置
+const ModeLocal = "local"
+
 //File 读写配置文件
 type File interface {
 	Load(fileName string, unmarshal func([]byte) (interface{}, error)) (interface{}, error)
@@ -52,6 +55,29 @@ type AppConfig struct {
 	SyncServerTimeout int               `json:"syncServerTimeout"`
 	Label             string            `json:"label"`
 
+	// Mode 运行模式，为 ModeLocal 时不访问网络
+	Mode string `json:"mode"`
+	// LocalConfigPath 本地模式下存放 .properties/.yaml/.json 等配置文件的目录，为空时从 BackupConfigPath 的备份加载
+	LocalConfigPath string `json:"localConfigPath"`
+	// WatchLocalConfig 本地模式下监听配置文件变化并触发变更事件
+	WatchLocalConfig bool `json:"watchLocalConfig"`
+	// OverrideConfigPath 本地覆盖配置文件，格式见 storage.LoadOverrides
+	OverrideConfigPath string `json:"overrideConfigPath"`
+	// TLS 访问 https 的 apollo 服务时使用的 TLS 配置，为空时使用系统证书校验服务端
+	TLS *TLSConfig `json:"tls"`
+	// LookupNamespaces Client 的 GetValue 等方法按顺序查找的 namespace，逗号分隔，为空时只查找 application
+	LookupNamespaces string `json:"lookupNamespaces"`
+	// LongPollTimeout 长轮询请求的超时时间（秒），应大于服务端挂起的 60s，为0时使用默认的10分钟
+	LongPollTimeout int `json:"longPollTimeout"`
+	// Retry 访问 apollo 失败时的重试策略，为空时只有 IsRetry 的请求按默认策略重试
+	Retry *RetryPolicy `json:"retry"`
+	// CircuitBreaker config server 节点的熔断配置，为空时使用默认配置
+	CircuitBreaker *CircuitBreakerConfig `json:"circuitBreaker"`
+	// LoadBalance 选择 config server 节点的负载均衡器，可选 roundrobin、random、weighted，为空时使用 SetLoadBalance 设置的全局负载均衡器
+	LoadBalance string `json:"loadBalance"`
+	// ServerWeights weighted 负载均衡使用的节点权重，key 为节点的 HomepageURL，优先于节点 metadata 中的 weight
+	ServerWeights map[string]int `json:"serverWeights"`
+
 	// MustStart 可用于控制第一次同步必须成功
 	MustStart               bool `default:"false"`
 	notificationsMap        *notificationsMap
@@ -63,14 +89,30 @@ type ServerInfo struct {
 	AppName     string `json:"appName"`
 	InstanceID  string `json:"instanceId"`
 	HomepageURL string `json:"homepageUrl"`
-	IsDown      bool   `json:"-"`
 }
 
 //GetIsBackupConfig whether backup config after fetch config from apollo
 //false : no
 //true : yes (default)
+//本地模式下配置本身来自本地文件，不再备份
 func (a *AppConfig) GetIsBackupConfig() bool {
-	return a.IsBackupConfig
+	return a.IsBackupConfig && !a.IsLocalMode()
+}
+
+//GetLookupNamespaces 按优先级排列的查找 namespace，未配置时返回 nil
+func (a *AppConfig) GetLookupNamespaces() []string {
+	var namespaces []string
+	for _, namespace := range strings.Split(a.LookupNamespaces, comma) {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+//IsLocalMode 是否为本地模式
+func (a *AppConfig) IsLocalMode() bool {
+	return a.Mode == ModeLocal
 }
 
 //GetBackupConfigPath GetBackupConfigPath
@@ -111,15 +153,21 @@ type Notification struct {
 
 // InitAllNotifications 初始化notificationsMap
 func (a *AppConfig) initAllNotifications(callback func(namespace string)) {
-	ns := SplitNamespaces(a.NamespaceName, callback)
+	ns := &sync.Map{}
+	splitNamespaces(a.NamespaceName, callback, ns)
 	a.notificationsMap = &notificationsMap{
 		notifications: ns,
 	}
 }
 
 //SplitNamespaces 根据namespace字符串分割后，并执行callback函数
-func SplitNamespaces(namespacesStr string, callback func(namespace string)) sync.Map {
-	namespaces := sync.Map{}
+func SplitNamespaces(namespacesStr string, callback func(namespace string)) (namespaces sync.Map) {
+	splitNamespaces(namespacesStr, callback, &namespaces)
+	return
+}
+
+//splitNamespaces 分割namespace字符串并执行callback函数，结果存入 namespaces，避免复制 sync.Map
+func splitNamespaces(namespacesStr string, callback func(namespace string), namespaces *sync.Map) {
 	split := strings.Split(namespacesStr, comma)
 	for _, namespace := range split {
 		if callback != nil {
@@ -127,7 +175,6 @@ func SplitNamespaces(namespacesStr string, callback func(namespace string)) sync
 		}
 		namespaces.Store(namespace, defaultNotificationID)
 	}
-	return namespaces
 }
 
 // GetNotificationsMap 获取notificationsMap
@@ -155,7 +202,7 @@ func (a *AppConfig) GetCurrentApolloConfig() *CurrentApolloConfig {
 
 // map[string]int64
 type notificationsMap struct {
-	notifications sync.Map
+	notifications *sync.Map
 }
 
 func (n *notificationsMap) UpdateAllNotifications(remoteConfigs []*Notification) {
@@ -200,8 +247,13 @@ func (n *notificationsMap) GetNotifyLen() int {
 	return l
 }
 
-func (n *notificationsMap) GetNotifications() sync.Map {
-	return n.notifications
+//GetNotifications 获取所有 namespace 的 notificationID，返回的是当前内容的副本
+func (n *notificationsMap) GetNotifications() (notifications sync.Map) {
+	n.notifications.Range(func(key, value interface{}) bool {
+		notifications.Store(key, value)
+		return true
+	})
+	return
 }
 
 func (n *notificationsMap) GetNotifies(namespace string) string {
@@ -48,6 +48,19 @@ func TestGetIsBackupConfig(t *testing.T) {
 	Assert(t, config, Equal(true))
 }
 
+func TestGetLookupNamespaces(t *testing.T) {
+	Assert(t, len(appConfig.GetLookupNamespaces()), Equal(0))
+	lookup := &AppConfig{LookupNamespaces: "application, common.yaml,,shared-db"}
+	Assert(t, lookup.GetLookupNamespaces(), Equal([]string{"application", "common.yaml", "shared-db"}))
+}
+
+func TestIsLocalMode(t *testing.T) {
+	local := &AppConfig{IsBackupConfig: true, Mode: ModeLocal}
+	Assert(t, appConfig.IsLocalMode(), Equal(false))
+	Assert(t, local.IsLocalMode(), Equal(true))
+	Assert(t, local.GetIsBackupConfig(), Equal(false))
+}
+
 func TestGetBackupConfigPath(t *testing.T) {
 	config := appConfig.GetBackupConfigPath()
 	Assert(t, config, Equal("/app/"))
@@ -110,6 +123,10 @@ func TestNotificationsMap(t *testing.T) {
 	newID := appConfig.GetNotificationsMap().GetNotify("application")
 	Assert(t, newID, Equal(int64(3)))
 
+	notifications := appConfig.GetNotificationsMap().GetNotifications()
+	value, _ := notifications.Load("application")
+	Assert(t, value, Equal(int64(3)))
+
 	appConfig.GetNotificationsMap().UpdateNotify("", 100)
 	noID := appConfig.GetNotificationsMap().GetNotify("")
 	Assert(t, noID, Equal(int64(0)))
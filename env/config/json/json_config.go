@@ -18,27 +18,84 @@
 package json
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/snailzed/agollo/v4/component/log"
 	"github.com/snailzed/agollo/v4/utils"
 )
 
+//checksumPrefix 备份文件中记录内容的 sha256，用于发现损坏的备份文件
+const checksumPrefix = "sha256:"
+
+//envelopePrefix 新版本写入的备份文件均以此开头
+const envelopePrefix = `{"checksum":`
+
+//fileLocks 按文件路径串行化写操作
+var fileLocks sync.Map
+
+//Codec 备份文件内容编解码，写入前 Encode，读取时校验通过后 Decode，可用于加密备份文件
+type Codec interface {
+	Encode(b []byte) ([]byte, error)
+	Decode(b []byte) ([]byte, error)
+}
+
 //ConfigFile json文件读写
 type ConfigFile struct {
+	//Codec 内容编解码，为 nil 时原样读写
+	Codec Codec
+	//Perm 写入文件的权限，为0时使用 0644
+	Perm os.FileMode
+	//Logger 读写失败时使用的 logger，为 nil 时使用全局 logger
+	Logger log.LoggerInterface
+}
+
+func (t *ConfigFile) logger() log.LoggerInterface {
+	if t.Logger == nil {
+		return log.Logger
+	}
+	return t.Logger
+}
+
+//envelope 备份文件格式，校验和与内容保存在同一个文件中，通过一次重命名同时替换
+//内容为 json 时压缩后原样保存在 content 中，其它内容（如加密后的内容）以 base64 保存在 data 中
+type envelope struct {
+	Checksum string          `json:"checksum"`
+	Content  json.RawMessage `json:"content,omitempty"`
+	Data     []byte          `json:"data,omitempty"`
 }
 
 //Load json文件读
+//校验和与内容不一致或缺少校验和时返回错误；旧版本写入的不带校验和的 json 文件直接解析
 func (t *ConfigFile) Load(fileName string, unmarshal func([]byte) (interface{}, error)) (interface{}, error) {
+	lock := getFileLock(fileName)
+	lock.Lock()
 	fs, err := ioutil.ReadFile(fileName)
+	lock.Unlock()
 	if err != nil {
 		return nil, errors.New("Fail to read config file:" + err.Error())
 	}
 
-	config, loadErr := unmarshal(fs)
+	content, err := decodeEnvelope(fs)
+	if err != nil {
+		return nil, fmt.Errorf("Config file %s is corrupt: %s", fileName, err)
+	}
+	if t.Codec != nil {
+		if content, err = t.Codec.Decode(content); err != nil {
+			return nil, fmt.Errorf("Fail to decode config file %s: %s", fileName, err)
+		}
+	}
+
+	config, loadErr := unmarshal(content)
 
 	if utils.IsNotNil(loadErr) {
 		return nil, errors.New("Load Json Config fail:" + loadErr.Error())
@@ -48,17 +105,203 @@ func (t *ConfigFile) Load(fileName string, unmarshal func([]byte) (interface{},
 }
 
 //Write json文件写
+//先写入同目录下的临时文件并 fsync，再重命名为目标文件，同一文件的写操作串行执行
+//内容及其 sha256 一起写入同一个文件
 func (t *ConfigFile) Write(content interface{}, configPath string) error {
 	if content == nil {
-		log.Error("content is null can not write backup file")
+		t.logger().Error("content is null can not write backup file")
 		return errors.New("content is null can not write backup file")
 	}
-	file, e := os.Create(configPath)
-	if e != nil {
-		log.Errorf("writeConfigFile fail,error:", e)
-		return e
+
+	b, err := json.Marshal(content)
+	if err != nil {
+		t.logger().Errorf("writeConfigFile fail,error:%s", err)
+		return err
+	}
+	b = append(b, '\n')
+	return t.WriteBytes(b, configPath)
+}
+
+//WriteBytes 写入编码后的内容及校验和，内容不要求是 json
+func (t *ConfigFile) WriteBytes(b []byte, configPath string) error {
+	if t.Codec != nil {
+		var err error
+		if b, err = t.Codec.Encode(b); err != nil {
+			t.logger().Errorf("encode config file fail,error:%s", err)
+			return err
+		}
+	}
+	b, err := encodeEnvelope(b)
+	if err != nil {
+		t.logger().Errorf("writeConfigFile fail,error:%s", err)
+		return err
+	}
+	perm := t.Perm
+	if perm == 0 {
+		perm = 0644
 	}
-	defer file.Close()
 
-	return json.NewEncoder(file).Encode(content)
+	lock := getFileLock(configPath)
+	lock.Lock()
+	defer lock.Unlock()
+	if err := writeFileAtomic(configPath, b, perm); err != nil {
+		t.logger().Errorf("writeConfigFile fail,error:%s", err)
+		return err
+	}
+	return nil
+}
+
+//WriteContent 写入不带校验和的内容，只经过 Codec 编码，未设置 Codec 时文件内容与 b 完全一致
+func (t *ConfigFile) WriteContent(b []byte, fileName string) error {
+	if t.Codec != nil {
+		var err error
+		if b, err = t.Codec.Encode(b); err != nil {
+			t.logger().Errorf("encode config file fail,error:%s", err)
+			return err
+		}
+	}
+	perm := t.Perm
+	if perm == 0 {
+		perm = 0644
+	}
+	return WriteFileAtomicMode(fileName, b, perm)
+}
+
+//LoadContent 读取 WriteContent 写入的内容
+func (t *ConfigFile) LoadContent(fileName string) ([]byte, error) {
+	lock := getFileLock(fileName)
+	lock.Lock()
+	b, err := ioutil.ReadFile(fileName)
+	lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if t.Codec != nil {
+		if b, err = t.Codec.Decode(b); err != nil {
+			return nil, fmt.Errorf("Fail to decode config file %s: %s", fileName, err)
+		}
+	}
+	return b, nil
+}
+
+//Remove 删除文件
+func (t *ConfigFile) Remove(fileName string) error {
+	lock := getFileLock(fileName)
+	lock.Lock()
+	defer lock.Unlock()
+	return os.Remove(fileName)
+}
+
+func getFileLock(fileName string) *sync.Mutex {
+	lock, _ := fileLocks.LoadOrStore(fileName, &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
+//WriteFileAtomic 原子地写入文件，写入过程中崩溃不会留下不完整的文件
+func WriteFileAtomic(fileName string, data []byte) error {
+	return WriteFileAtomicMode(fileName, data, 0644)
+}
+
+//WriteFileAtomicMode 与 WriteFileAtomic 相同，写入的文件权限为 perm
+func WriteFileAtomicMode(fileName string, data []byte, perm os.FileMode) error {
+	lock := getFileLock(fileName)
+	lock.Lock()
+	defer lock.Unlock()
+	return writeFileAtomic(fileName, data, perm)
+}
+
+func writeFileAtomic(fileName string, data []byte, perm os.FileMode) (err error) {
+	dir, base := filepath.Split(fileName)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := ioutil.TempFile(dir, "."+base+".tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmpName)
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpName, fileName); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+//syncDir 持久化重命名操作，部分平台不支持对目录 fsync，忽略错误
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
+
+//encodeEnvelope 将内容及其校验和编码为备份文件
+func encodeEnvelope(b []byte) ([]byte, error) {
+	e := envelope{}
+	compacted := &bytes.Buffer{}
+	if json.Valid(b) && json.Compact(compacted, b) == nil {
+		b = compacted.Bytes()
+		e.Content = b
+	} else {
+		e.Data = b
+	}
+	sum := sha256.Sum256(b)
+	e.Checksum = checksumPrefix + hex.EncodeToString(sum[:])
+
+	buffer := &bytes.Buffer{}
+	encoder := json.NewEncoder(buffer)
+	//content 需按原样保存，才能与校验和一致
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(e); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+//decodeEnvelope 校验并取出备份文件的内容，旧版本写入的不带校验和的备份文件原样返回
+func decodeEnvelope(b []byte) ([]byte, error) {
+	e := envelope{}
+	if err := json.Unmarshal(b, &e); err != nil {
+		if bytes.HasPrefix(bytes.TrimSpace(b), []byte(envelopePrefix)) {
+			return nil, err
+		}
+		return b, nil
+	}
+	if e.Checksum == "" && e.Content == nil && e.Data == nil {
+		return b, nil
+	}
+	if !strings.HasPrefix(e.Checksum, checksumPrefix) {
+		return nil, errors.New("missing checksum")
+	}
+	content := []byte(e.Content)
+	if e.Data != nil {
+		content = e.Data
+	}
+	sum := sha256.Sum256(content)
+	if e.Checksum[len(checksumPrefix):] != hex.EncodeToString(sum[:]) {
+		return nil, errors.New("checksum mismatch")
+	}
+	return content, nil
 }
@@ -19,7 +19,11 @@ package json
 
 import (
 	"encoding/json"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/snailzed/agollo/v4/env/config"
@@ -154,7 +158,7 @@ func TestJSONConfigFile_Write(t *testing.T) {
 	Assert(t, e, NilVal())
 	Assert(t, file, NotNilVal())
 	file.Close()
-	os.Remove(fileName)
+	jsonConfigFile.Remove(fileName)
 }
 
 func TestJSONConfigFile_Write_error(t *testing.T) {
@@ -169,3 +173,119 @@ func TestJSONConfigFile_Write_error(t *testing.T) {
 	Assert(t, e, NotNilVal())
 	Assert(t, file, NilVal())
 }
+
+func TestWriteChecksum(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agollo-json-config")
+	Assert(t, err, NilVal())
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "backup.json")
+
+	err = jsonConfigFile.Write(map[string]string{"a": "b"}, fileName)
+	Assert(t, err, NilVal())
+
+	// 内容与校验和写在同一个 json 文件中
+	b, err := ioutil.ReadFile(fileName)
+	Assert(t, err, NilVal())
+	e := envelope{}
+	Assert(t, json.Unmarshal(b, &e), NilVal())
+	Assert(t, strings.HasPrefix(e.Checksum, checksumPrefix), Equal(true))
+	Assert(t, string(e.Content), Equal(`{"a":"b"}`))
+
+	c, err := jsonConfigFile.Load(fileName, unmarshalMap)
+	Assert(t, err, NilVal())
+	Assert(t, c, Equal(map[string]string{"a": "b"}))
+
+	// 只留下目标文件，不残留临时文件
+	files, err := ioutil.ReadDir(dir)
+	Assert(t, err, NilVal())
+	Assert(t, len(files), Equal(1))
+
+	// 内容被修改后校验失败
+	err = ioutil.WriteFile(fileName, []byte(strings.Replace(string(b), `"b"`, `"c"`, 1)), 0644)
+	Assert(t, err, NilVal())
+	c, err = jsonConfigFile.Load(fileName, unmarshalMap)
+	Assert(t, err, NotNilVal())
+	Assert(t, c, NilVal())
+	Assert(t, strings.Contains(err.Error(), "checksum mismatch"), Equal(true))
+
+	// 缺少校验和视为损坏
+	err = ioutil.WriteFile(fileName, []byte(`{"content":{"a":"b"}}`), 0644)
+	Assert(t, err, NilVal())
+	c, err = jsonConfigFile.Load(fileName, unmarshalMap)
+	Assert(t, err, NotNilVal())
+	Assert(t, c, NilVal())
+	Assert(t, strings.Contains(err.Error(), "missing checksum"), Equal(true))
+
+	// 截断的文件无法解析
+	err = ioutil.WriteFile(fileName, b[:len(b)/2], 0644)
+	Assert(t, err, NilVal())
+	c, err = jsonConfigFile.Load(fileName, unmarshalMap)
+	Assert(t, err, NotNilVal())
+	Assert(t, c, NilVal())
+
+	Assert(t, jsonConfigFile.Remove(fileName), NilVal())
+	files, err = ioutil.ReadDir(dir)
+	Assert(t, err, NilVal())
+	Assert(t, len(files), Equal(0))
+}
+
+func TestWriteBytesChecksum(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agollo-json-config")
+	Assert(t, err, NilVal())
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "backup.raw")
+
+	// 非 json 内容同样带校验和
+	Assert(t, jsonConfigFile.WriteBytes([]byte("a: b\n"), fileName), NilVal())
+	c, err := jsonConfigFile.Load(fileName, func(b []byte) (interface{}, error) {
+		return string(b), nil
+	})
+	Assert(t, err, NilVal())
+	Assert(t, c, Equal("a: b\n"))
+}
+
+func TestLoadWithoutChecksum(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agollo-json-config")
+	Assert(t, err, NilVal())
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "backup.json")
+
+	// 旧版本写入的备份文件没有校验和
+	err = ioutil.WriteFile(fileName, []byte(`{"a":"b"}`+"\n"), 0644)
+	Assert(t, err, NilVal())
+	c, err := jsonConfigFile.Load(fileName, unmarshalMap)
+	Assert(t, err, NilVal())
+	Assert(t, c, Equal(map[string]string{"a": "b"}))
+}
+
+func TestWriteConcurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agollo-json-config")
+	Assert(t, err, NilVal())
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "backup.json")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			Assert(t, jsonConfigFile.Write(map[string]string{"a": strings.Repeat("b", i*1000)}, fileName), NilVal())
+		}(i)
+	}
+	wg.Wait()
+
+	_, err = jsonConfigFile.Load(fileName, unmarshalMap)
+	Assert(t, err, NilVal())
+	files, err := ioutil.ReadDir(dir)
+	Assert(t, err, NilVal())
+	Assert(t, len(files), Equal(1))
+}
+
+func unmarshalMap(b []byte) (interface{}, error) {
+	m := make(map[string]string)
+	err := json.Unmarshal(b, &m)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
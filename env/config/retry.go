@@ -0,0 +1,114 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	//DefaultRetryMaxAttempts 默认的最大尝试次数
+	DefaultRetryMaxAttempts = 5
+	//DefaultRetryBaseDelay 默认的退避基础时长（毫秒）
+	DefaultRetryBaseDelay = 1000
+	//DefaultRetryMaxDelay 默认的最大退避时长（毫秒）
+	DefaultRetryMaxDelay = 30000
+)
+
+var (
+	//DefaultRetryableStatusCodes 默认可重试的 http 状态码
+	DefaultRetryableStatusCodes = []int{
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	}
+
+	//各实例使用不同的随机种子，避免同时启动的实例退避时长相同
+	jitterRand  = rand.New(rand.NewSource(time.Now().UnixNano()))
+	jitterMutex sync.Mutex
+)
+
+//RetryPolicy 访问 apollo 失败时的重试策略
+//重试间隔使用 full jitter 的指数退避：第 n 次重试等待 [0, min(MaxDelay, BaseDelay*2^(n-1))] 之间的随机时长
+type RetryPolicy struct {
+	//MaxAttempts 单个节点的最大尝试次数（含第一次请求），为0时使用 DefaultRetryMaxAttempts
+	MaxAttempts int `json:"maxAttempts"`
+	//BaseDelay 退避基础时长（毫秒），为0时使用 DefaultRetryBaseDelay
+	BaseDelay int `json:"baseDelay"`
+	//MaxDelay 最大退避时长（毫秒），为0时使用 DefaultRetryMaxDelay
+	MaxDelay int `json:"maxDelay"`
+	//RetryableStatusCodes 可重试的 http 状态码，为空时使用 DefaultRetryableStatusCodes，网络错误总是重试
+	RetryableStatusCodes []int `json:"retryableStatusCodes"`
+}
+
+//GetMaxAttempts 获取最大尝试次数
+func (p *RetryPolicy) GetMaxAttempts() int {
+	if p == nil || p.MaxAttempts <= 0 {
+		return DefaultRetryMaxAttempts
+	}
+	return p.MaxAttempts
+}
+
+func (p *RetryPolicy) getBaseDelay() time.Duration {
+	if p == nil || p.BaseDelay <= 0 {
+		return DefaultRetryBaseDelay * time.Millisecond
+	}
+	return time.Duration(p.BaseDelay) * time.Millisecond
+}
+
+func (p *RetryPolicy) getMaxDelay() time.Duration {
+	if p == nil || p.MaxDelay <= 0 {
+		return DefaultRetryMaxDelay * time.Millisecond
+	}
+	return time.Duration(p.MaxDelay) * time.Millisecond
+}
+
+//IsRetryableStatus 状态码是否可重试
+func (p *RetryPolicy) IsRetryableStatus(statusCode int) bool {
+	codes := DefaultRetryableStatusCodes
+	if p != nil && len(p.RetryableStatusCodes) > 0 {
+		codes = p.RetryableStatusCodes
+	}
+	for _, code := range codes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+//Backoff 第 attempt 次失败后的等待时长，attempt 从1开始
+func (p *RetryPolicy) Backoff(attempt int) time.Duration {
+	maxDelay := p.getMaxDelay()
+	delay := p.getBaseDelay()
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	jitterMutex.Lock()
+	defer jitterMutex.Unlock()
+	return time.Duration(jitterRand.Int63n(int64(delay) + 1))
+}
@@ -0,0 +1,65 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/tevid/gohamcrest"
+)
+
+func TestRetryPolicyDefault(t *testing.T) {
+	var policy *RetryPolicy
+	Assert(t, policy.GetMaxAttempts(), Equal(DefaultRetryMaxAttempts))
+	Assert(t, policy.IsRetryableStatus(http.StatusServiceUnavailable), Equal(true))
+	Assert(t, policy.IsRetryableStatus(http.StatusTooManyRequests), Equal(true))
+	Assert(t, policy.IsRetryableStatus(http.StatusNotFound), Equal(false))
+	for i := 1; i < 100; i++ {
+		Assert(t, policy.Backoff(i) <= DefaultRetryMaxDelay*time.Millisecond, Equal(true))
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := &RetryPolicy{
+		MaxAttempts:          3,
+		BaseDelay:            10,
+		MaxDelay:             40,
+		RetryableStatusCodes: []int{http.StatusBadGateway},
+	}
+	Assert(t, policy.GetMaxAttempts(), Equal(3))
+	Assert(t, policy.IsRetryableStatus(http.StatusBadGateway), Equal(true))
+	Assert(t, policy.IsRetryableStatus(http.StatusServiceUnavailable), Equal(false))
+
+	//full jitter：等待时长在 [0, min(MaxDelay, BaseDelay*2^(n-1))] 之间
+	maxDelays := []time.Duration{10, 20, 40, 40, 40}
+	for i, maxDelay := range maxDelays {
+		var max time.Duration
+		for j := 0; j < 200; j++ {
+			delay := policy.Backoff(i + 1)
+			Assert(t, delay >= 0, Equal(true))
+			Assert(t, delay <= maxDelay*time.Millisecond, Equal(true))
+			if delay > max {
+				max = delay
+			}
+		}
+		//随机值不应总是相同
+		Assert(t, max > 0, Equal(true))
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+//TLSConfig 访问 https 的 apollo 服务时使用的 TLS 配置
+//文件与 PEM 内容同时配置时都会生效，CA 证书会合并到同一个证书池中
+type TLSConfig struct {
+	//CAFile 校验服务端证书的 CA 证书文件，未配置 CA 时使用系统证书
+	CAFile string `json:"caFile"`
+	//CAPEM PEM 格式的 CA 证书内容
+	CAPEM string `json:"caPem"`
+	//CertFile、KeyFile 双向认证使用的客户端证书及私钥文件
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+	//CertPEM、KeyPEM PEM 格式的客户端证书及私钥内容
+	CertPEM string `json:"certPem"`
+	KeyPEM  string `json:"keyPem"`
+	//ServerName 校验服务端证书时使用的域名，为空时使用请求地址中的域名
+	ServerName string `json:"serverName"`
+	//MinVersion 最低 TLS 版本，可选 1.0、1.1、1.2、1.3，为空时使用 go 的默认值
+	MinVersion string `json:"minVersion"`
+	//InsecureSkipVerify 跳过服务端证书校验，仅用于测试环境，必须显式开启
+	InsecureSkipVerify bool `json:"insecureSkipVerify"`
+}
+
+//Build 根据配置生成 tls.Config，配置为 nil 时返回 nil
+func (t *TLSConfig) Build() (*tls.Config, error) {
+	if t == nil {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+
+	if t.MinVersion != "" {
+		version, ok := tlsVersions[t.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported tls min version %s", t.MinVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+
+	if t.CAFile != "" || t.CAPEM != "" {
+		pool := x509.NewCertPool()
+		if t.CAFile != "" {
+			b, err := ioutil.ReadFile(t.CAFile)
+			if err != nil {
+				return nil, err
+			}
+			if !pool.AppendCertsFromPEM(b) {
+				return nil, fmt.Errorf("no certificate found in ca file %s", t.CAFile)
+			}
+		}
+		if t.CAPEM != "" && !pool.AppendCertsFromPEM([]byte(t.CAPEM)) {
+			return nil, errors.New("no certificate found in ca pem")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	switch {
+	case t.CertFile != "" || t.KeyFile != "":
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	case t.CertPEM != "" || t.KeyPEM != "":
+		cert, err := tls.X509KeyPair([]byte(t.CertPEM), []byte(t.KeyPEM))
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
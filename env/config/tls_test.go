@@ -0,0 +1,65 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/tevid/gohamcrest"
+)
+
+func TestTLSConfigBuild(t *testing.T) {
+	var empty *TLSConfig
+	tlsConfig, err := empty.Build()
+	Assert(t, err, NilVal())
+	Assert(t, tlsConfig == nil, Equal(true))
+
+	tlsConfig, err = (&TLSConfig{ServerName: "apollo", MinVersion: "1.2"}).Build()
+	Assert(t, err, NilVal())
+	Assert(t, tlsConfig.ServerName, Equal("apollo"))
+	Assert(t, tlsConfig.MinVersion, Equal(uint16(tls.VersionTLS12)))
+	Assert(t, tlsConfig.InsecureSkipVerify, Equal(false))
+	Assert(t, tlsConfig.RootCAs == nil, Equal(true))
+
+	_, err = (&TLSConfig{MinVersion: "1.4"}).Build()
+	Assert(t, err, NotNilVal())
+	_, err = (&TLSConfig{CAPEM: "invalid"}).Build()
+	Assert(t, err, NotNilVal())
+	_, err = (&TLSConfig{CertPEM: "invalid", KeyPEM: "invalid"}).Build()
+	Assert(t, err, NotNilVal())
+	_, err = (&TLSConfig{CertFile: "missing.crt", KeyFile: "missing.key"}).Build()
+	Assert(t, err, NotNilVal())
+}
+
+func TestTLSConfigBuildCAFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agollo-tls")
+	Assert(t, err, NilVal())
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.pem")
+	_, err = (&TLSConfig{CAFile: caFile}).Build()
+	Assert(t, err, NotNilVal())
+
+	Assert(t, ioutil.WriteFile(caFile, []byte("not a certificate"), 0644), NilVal())
+	_, err = (&TLSConfig{CAFile: caFile}).Build()
+	Assert(t, err, NotNilVal())
+}
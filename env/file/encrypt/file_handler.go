@@ -0,0 +1,189 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package encrypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/snailzed/agollo/v4/component/log"
+	"github.com/snailzed/agollo/v4/env/config"
+	jsonConfig "github.com/snailzed/agollo/v4/env/config/json"
+	"github.com/snailzed/agollo/v4/env/file"
+	jsonFile "github.com/snailzed/agollo/v4/env/file/json"
+)
+
+//magic 加密文件头，完整格式为 "AGOLLO-ENC v1 <keyID>\n" + nonce + 密文
+const magic = "AGOLLO-ENC v1 "
+
+//fileMode 加密备份文件的权限，仅允许所属用户读写
+const fileMode = 0600
+
+//FileHandler 加密备份文件读写，使用 AES-GCM 加密备份文件、历史版本及原始内容备份
+//读写由被装饰的 FileHandler 完成，加密在其写入文件前进行，解密在校验和通过后进行
+type FileHandler struct {
+	file.FileHandler
+	provider KeyProvider
+
+	//AllowPlaintext 是否允许读取未加密的旧备份文件，默认不允许，避免加密备份被替换为明文文件
+	AllowPlaintext bool
+}
+
+//codecSetter 支持设置内容编解码的 FileHandler
+type codecSetter interface {
+	SetCodec(codec jsonConfig.Codec, perm os.FileMode)
+}
+
+//CreateFileHandler 创建加密备份文件读写，handler 为 nil 时使用默认的 json 备份文件
+//handler 需支持 SetCodec（如 json 包中的 FileHandler），创建后其写入的内容均会加密
+func CreateFileHandler(handler file.FileHandler, provider KeyProvider) (*FileHandler, error) {
+	if handler == nil {
+		handler = &jsonFile.FileHandler{}
+	}
+	setter, ok := handler.(codecSetter)
+	if !ok {
+		return nil, fmt.Errorf("file handler %T does not support encryption", handler)
+	}
+	h := &FileHandler{
+		FileHandler: handler,
+		provider:    provider,
+	}
+	setter.SetCodec(h, fileMode)
+	return h, nil
+}
+
+//loggerSetter 支持设置 logger 的 FileHandler
+type loggerSetter interface {
+	SetLogger(logger log.LoggerInterface)
+}
+
+//SetLogger 设置被装饰的 FileHandler 使用的 logger，其不支持时忽略
+func (h *FileHandler) SetLogger(logger log.LoggerInterface) {
+	if setter, ok := h.FileHandler.(loggerSetter); ok {
+		setter.SetLogger(logger)
+	}
+}
+
+//WriteHistory 加密保存历史版本，被装饰的 FileHandler 不支持时写入完整的备份文件
+func (h *FileHandler) WriteHistory(config *config.ApolloConfig, configDir string) error {
+	if history, ok := h.FileHandler.(file.HistoryWriter); ok {
+		return history.WriteHistory(config, configDir)
+	}
+	return h.FileHandler.WriteConfigFile(config, configDir)
+}
+
+//ListReleases 获取本地保存的历史版本，被装饰的 FileHandler 不支持时返回错误
+func (h *FileHandler) ListReleases(configDir string, appID string, namespace string) ([]*file.Release, error) {
+	history, ok := h.FileHandler.(file.ReleaseHistory)
+	if !ok {
+		return nil, errors.New("file handler does not support release history")
+	}
+	return history.ListReleases(configDir, appID, namespace)
+}
+
+//LoadRelease 加载并解密指定 releaseKey 的历史版本，被装饰的 FileHandler 不支持时返回错误
+func (h *FileHandler) LoadRelease(configDir string, appID string, namespace string, releaseKey string) (*config.ApolloConfig, error) {
+	history, ok := h.FileHandler.(file.ReleaseHistory)
+	if !ok {
+		return nil, errors.New("file handler does not support release history")
+	}
+	return history.LoadRelease(configDir, appID, namespace, releaseKey)
+}
+
+//Encode 加密写入文件的内容
+func (h *FileHandler) Encode(plaintext []byte) ([]byte, error) {
+	return h.encrypt(plaintext)
+}
+
+//Decode 解密读取的文件内容，未加密的内容仅在 AllowPlaintext 时原样返回
+func (h *FileHandler) Decode(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(magic)) {
+		if h.AllowPlaintext {
+			return data, nil
+		}
+		return nil, errors.New("file is not encrypted")
+	}
+	plaintext, err := h.decrypt(data)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt fail: %s", err)
+	}
+	return plaintext, nil
+}
+
+func (h *FileHandler) encrypt(plaintext []byte) ([]byte, error) {
+	keyID, key, err := h.provider.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkKeyID(keyID); err != nil {
+		return nil, err
+	}
+	aead, err := createAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	header := []byte(magic + keyID + "\n")
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	data := make([]byte, 0, len(header)+len(nonce)+len(plaintext)+aead.Overhead())
+	data = append(data, header...)
+	data = append(data, nonce...)
+	//文件头作为附加数据参与认证，防止篡改 key ID
+	return aead.Seal(data, nonce, plaintext, header), nil
+}
+
+func (h *FileHandler) decrypt(data []byte) ([]byte, error) {
+	end := bytes.IndexByte(data, '\n')
+	if end < 0 {
+		return nil, errors.New("invalid header")
+	}
+	header := data[:end+1]
+	keyID := string(data[len(magic):end])
+	key, err := h.provider.Key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := createAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	body := data[end+1:]
+	if len(body) < aead.NonceSize() {
+		return nil, errors.New("invalid content")
+	}
+	return aead.Open(nil, body[:aead.NonceSize()], body[aead.NonceSize():], header)
+}
+
+func createAEAD(key []byte) (cipher.AEAD, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
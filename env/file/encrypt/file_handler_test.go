@@ -0,0 +1,227 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package encrypt
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/snailzed/agollo/v4/agollotest/conformance"
+	"github.com/snailzed/agollo/v4/constant"
+	"github.com/snailzed/agollo/v4/env/config"
+	jsonConfig "github.com/snailzed/agollo/v4/env/config/json"
+	"github.com/snailzed/agollo/v4/env/file"
+	jsonFile "github.com/snailzed/agollo/v4/env/file/json"
+	"github.com/snailzed/agollo/v4/utils/parse"
+	"github.com/snailzed/agollo/v4/utils/parse/yaml"
+	. "github.com/tevid/gohamcrest"
+)
+
+var (
+	key1 = bytes.Repeat([]byte{1}, 32)
+	key2 = bytes.Repeat([]byte{2}, 16)
+)
+
+func createTestConfig(namespace string) *config.ApolloConfig {
+	apolloConfig := &config.ApolloConfig{}
+	apolloConfig.AppID = "encrypt"
+	apolloConfig.NamespaceName = namespace
+	apolloConfig.ReleaseKey = "release"
+	apolloConfig.Configurations = map[string]interface{}{"password": "secret-value"}
+	return apolloConfig
+}
+
+func createTempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "agollo-encrypt")
+	Assert(t, err, NilVal())
+	return dir
+}
+
+//readEncrypted 读取备份文件中保存的密文
+func readEncrypted(t *testing.T, fileName string) []byte {
+	b, err := ioutil.ReadFile(fileName)
+	Assert(t, err, NilVal())
+	e := struct {
+		Data []byte `json:"data"`
+	}{}
+	Assert(t, json.Unmarshal(b, &e), NilVal())
+	return e.Data
+}
+
+func createFileHandler(t *testing.T, handler file.FileHandler, provider KeyProvider) *FileHandler {
+	h, err := CreateFileHandler(handler, provider)
+	Assert(t, err, NilVal())
+	return h
+}
+
+func TestFileHandlerConformance(t *testing.T) {
+	ring, err := CreateKeyRing("k1", map[string][]byte{"k1": key1})
+	Assert(t, err, NilVal())
+	conformance.TestFileHandler(t, createFileHandler(t, nil, ring))
+}
+
+func TestWriteEncrypted(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	ring, _ := CreateKeyRing("k1", map[string][]byte{"k1": key1})
+	h := createFileHandler(t, &jsonFile.FileHandler{}, ring)
+	want := createTestConfig("write")
+	Assert(t, h.WriteConfigFile(want, dir), NilVal())
+
+	fileName := h.GetConfigFile(dir, want.AppID, want.NamespaceName)
+	b := readEncrypted(t, fileName)
+	Assert(t, bytes.HasPrefix(b, []byte(magic+"k1\n")), Equal(true))
+	Assert(t, bytes.Contains(b, []byte("secret-value")), Equal(false))
+
+	info, err := os.Stat(fileName)
+	Assert(t, err, NilVal())
+	Assert(t, info.Mode().Perm(), Equal(os.FileMode(fileMode)))
+
+	got, err := h.LoadConfigFile(dir, want.AppID, want.NamespaceName)
+	Assert(t, err, NilVal())
+	Assert(t, got.Configurations["password"], Equal("secret-value"))
+
+	//篡改密文后无法解密
+	b[len(b)-1] ^= 0xff
+	Assert(t, (&jsonConfig.ConfigFile{}).WriteBytes(b, fileName), NilVal())
+	got, err = h.LoadConfigFile(dir, want.AppID, want.NamespaceName)
+	Assert(t, err, NotNilVal())
+	Assert(t, got, NilVal())
+}
+
+func TestKeyRotation(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	ring, _ := CreateKeyRing("k1", map[string][]byte{"k1": key1})
+	h := createFileHandler(t, nil, ring)
+	old := createTestConfig("rotation")
+	Assert(t, h.WriteConfigFile(old, dir), NilVal())
+
+	Assert(t, ring.Rotate("k2", key2), NilVal())
+	got, err := h.LoadConfigFile(dir, old.AppID, old.NamespaceName)
+	Assert(t, err, NilVal())
+	Assert(t, got.Configurations["password"], Equal("secret-value"))
+
+	Assert(t, h.WriteConfigFile(old, dir), NilVal())
+	b := readEncrypted(t, h.GetConfigFile(dir, old.AppID, old.NamespaceName))
+	Assert(t, bytes.HasPrefix(b, []byte(magic+"k2\n")), Equal(true))
+
+	//缺少写入时使用的密钥
+	other, _ := CreateKeyRing("k1", map[string][]byte{"k1": key1})
+	_, err = createFileHandler(t, nil, other).LoadConfigFile(dir, old.AppID, old.NamespaceName)
+	Assert(t, err, NotNilVal())
+
+	Assert(t, ring.Rotate("bad id", key2), NotNilVal())
+	Assert(t, ring.Rotate("k3", []byte("short")), NotNilVal())
+}
+
+func TestLoadPlaintext(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	want := createTestConfig("plaintext")
+	Assert(t, (&jsonFile.FileHandler{MaxHistory: -1}).WriteConfigFile(want, dir), NilVal())
+
+	//默认拒绝读取明文备份
+	ring, _ := CreateKeyRing("k1", map[string][]byte{"k1": key1})
+	h := createFileHandler(t, &jsonFile.FileHandler{MaxHistory: -1}, ring)
+	got, err := h.LoadConfigFile(dir, want.AppID, want.NamespaceName)
+	Assert(t, err, NotNilVal())
+	Assert(t, got, NilVal())
+
+	h.AllowPlaintext = true
+	got, err = h.LoadConfigFile(dir, want.AppID, want.NamespaceName)
+	Assert(t, err, NilVal())
+	Assert(t, got.Configurations["password"], Equal("secret-value"))
+}
+
+func TestEncryptedHistory(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	ring, _ := CreateKeyRing("k1", map[string][]byte{"k1": key1})
+	h := createFileHandler(t, nil, ring)
+	var _ file.ReleaseHistory = h
+
+	old := createTestConfig("history")
+	old.ReleaseKey = "r1"
+	Assert(t, h.WriteConfigFile(old, dir), NilVal())
+	latest := createTestConfig("history")
+	latest.ReleaseKey = "r2"
+	latest.Configurations = map[string]interface{}{"password": "new-value"}
+	Assert(t, h.WriteConfigFile(latest, dir), NilVal())
+
+	releases, err := h.ListReleases(dir, old.AppID, old.NamespaceName)
+	Assert(t, err, NilVal())
+	Assert(t, len(releases), Equal(2))
+	Assert(t, releases[0].ReleaseKey, Equal("r2"))
+
+	got, err := h.LoadRelease(dir, old.AppID, old.NamespaceName, "r1")
+	Assert(t, err, NilVal())
+	Assert(t, got.Configurations["password"], Equal("secret-value"))
+
+	//历史版本同样加密保存
+	files, err := filepath.Glob(filepath.Join(dir, "*.history", "*.json"))
+	Assert(t, err, NilVal())
+	Assert(t, len(files), Equal(2))
+	for _, f := range files {
+		b := readEncrypted(t, f)
+		Assert(t, bytes.HasPrefix(b, []byte(magic+"k1\n")), Equal(true))
+	}
+}
+
+func TestEncryptedRaw(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	ring, _ := CreateKeyRing("k1", map[string][]byte{"k1": key1})
+	h := createFileHandler(t, jsonFile.CreateRawFileHandler(func(format constant.ConfigFileFormat) parse.ContentParser {
+		return &yaml.Parser{}
+	}), ring)
+	want := createTestConfig("raw.yaml")
+	want.Content = "password: secret-value\n"
+	Assert(t, h.WriteConfigFile(want, dir), NilVal())
+
+	//原始内容备份直接保存密文
+	b, err := ioutil.ReadFile(jsonFile.GetRawFile(dir, want.AppID, want.NamespaceName))
+	Assert(t, err, NilVal())
+	Assert(t, bytes.HasPrefix(b, []byte(magic+"k1\n")), Equal(true))
+	Assert(t, bytes.Contains(b, []byte("secret-value")), Equal(false))
+
+	got, err := h.LoadConfigFile(dir, want.AppID, want.NamespaceName)
+	Assert(t, err, NilVal())
+	Assert(t, got.Content, Equal(want.Content))
+	Assert(t, got.Configurations["password"], Equal("secret-value"))
+}
+
+func TestCreateFileHandlerUnsupported(t *testing.T) {
+	ring, _ := CreateKeyRing("k1", map[string][]byte{"k1": key1})
+	h, err := CreateFileHandler(&unsupportedFileHandler{}, ring)
+	Assert(t, err, NotNilVal())
+	Assert(t, h, NilVal())
+}
+
+type unsupportedFileHandler struct {
+	file.FileHandler
+}
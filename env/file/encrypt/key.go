@@ -0,0 +1,211 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package encrypt
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+//DefaultKeyEnv 默认读取密钥的环境变量
+const DefaultKeyEnv = "APOLLO_BACKUP_KEY"
+
+//defaultKeyID 未指定 key ID 时使用的名称
+const defaultKeyID = "default"
+
+//KeyProvider 提供备份文件加解密使用的密钥
+type KeyProvider interface {
+	//CurrentKey 加密新文件使用的密钥及其 ID
+	CurrentKey() (keyID string, key []byte, err error)
+	//Key 根据文件头中的 ID 获取解密使用的密钥
+	Key(keyID string) ([]byte, error)
+}
+
+//KeyRing 内存中的密钥集合，Current 用于加密，其余密钥仅用于解密轮换前写入的文件
+type KeyRing struct {
+	lock    sync.RWMutex
+	current string
+	keys    map[string][]byte
+}
+
+//CreateKeyRing 创建密钥集合，keys 中必须包含 current
+func CreateKeyRing(current string, keys map[string][]byte) (*KeyRing, error) {
+	ring := &KeyRing{keys: make(map[string][]byte, len(keys))}
+	for id, key := range keys {
+		ring.keys[id] = key
+	}
+	if err := ring.Rotate(current, keys[current]); err != nil {
+		return nil, err
+	}
+	return ring, nil
+}
+
+//Rotate 添加新密钥并作为当前加密密钥，旧密钥保留用于解密
+func (k *KeyRing) Rotate(keyID string, key []byte) error {
+	if err := checkKeyID(keyID); err != nil {
+		return err
+	}
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	k.keys[keyID] = key
+	k.current = keyID
+	return nil
+}
+
+//CurrentKey 加密新文件使用的密钥及其 ID
+func (k *KeyRing) CurrentKey() (string, []byte, error) {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+	return k.current, k.keys[k.current], nil
+}
+
+//Key 根据 ID 获取密钥
+func (k *KeyRing) Key(keyID string) ([]byte, error) {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("backup key %s is not found", keyID)
+	}
+	return key, nil
+}
+
+//EnvKeyProvider 从环境变量读取密钥，格式见 ParseKeys，每次使用时重新读取
+type EnvKeyProvider struct {
+	//Env 环境变量名，为空时使用 DefaultKeyEnv
+	Env string
+}
+
+func (e *EnvKeyProvider) ring() (*KeyRing, error) {
+	name := e.Env
+	if name == "" {
+		name = DefaultKeyEnv
+	}
+	value := os.Getenv(name)
+	if value == "" {
+		return nil, fmt.Errorf("backup key env %s is empty", name)
+	}
+	return ParseKeys(value)
+}
+
+//CurrentKey 加密新文件使用的密钥及其 ID
+func (e *EnvKeyProvider) CurrentKey() (string, []byte, error) {
+	ring, err := e.ring()
+	if err != nil {
+		return "", nil, err
+	}
+	return ring.CurrentKey()
+}
+
+//Key 根据 ID 获取密钥
+func (e *EnvKeyProvider) Key(keyID string) ([]byte, error) {
+	ring, err := e.ring()
+	if err != nil {
+		return nil, err
+	}
+	return ring.Key(keyID)
+}
+
+//FileKeyProvider 从文件读取密钥，格式见 ParseKeys，每次使用时重新读取以便不重启即可轮换密钥
+type FileKeyProvider struct {
+	Path string
+}
+
+func (f *FileKeyProvider) ring() (*KeyRing, error) {
+	b, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeys(string(b))
+}
+
+//CurrentKey 加密新文件使用的密钥及其 ID
+func (f *FileKeyProvider) CurrentKey() (string, []byte, error) {
+	ring, err := f.ring()
+	if err != nil {
+		return "", nil, err
+	}
+	return ring.CurrentKey()
+}
+
+//Key 根据 ID 获取密钥
+func (f *FileKeyProvider) Key(keyID string) ([]byte, error) {
+	ring, err := f.ring()
+	if err != nil {
+		return nil, err
+	}
+	return ring.Key(keyID)
+}
+
+//ParseKeys 解析以逗号或换行分隔的 "keyID:base64密钥" 列表，第一个为当前加密密钥
+//只有一个密钥时可以省略 keyID，此时 ID 为 default
+func ParseKeys(value string) (*KeyRing, error) {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	})
+	current := ""
+	keys := make(map[string][]byte, len(fields))
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, encoded := defaultKeyID, field
+		if i := strings.Index(field, ":"); i >= 0 {
+			id, encoded = strings.TrimSpace(field[:i]), strings.TrimSpace(field[i+1:])
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("backup key %s is not valid base64: %s", id, err)
+		}
+		if _, ok := keys[id]; ok {
+			return nil, fmt.Errorf("backup key %s is duplicated", id)
+		}
+		keys[id] = key
+		if current == "" {
+			current = id
+		}
+	}
+	if current == "" {
+		return nil, errors.New("backup key is empty")
+	}
+	return CreateKeyRing(current, keys)
+}
+
+func checkKeyID(keyID string) error {
+	if keyID == "" || strings.ContainsAny(keyID, " \t\r\n:,") {
+		return fmt.Errorf("backup key id %q is invalid", keyID)
+	}
+	return nil
+}
+
+func checkKey(key []byte) error {
+	switch len(key) {
+	case 16, 24, 32:
+		return nil
+	}
+	return fmt.Errorf("backup key must be 16, 24 or 32 bytes, got %d", len(key))
+}
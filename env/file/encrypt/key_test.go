@@ -0,0 +1,92 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package encrypt
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/tevid/gohamcrest"
+)
+
+func TestParseKeys(t *testing.T) {
+	encoded1 := base64.StdEncoding.EncodeToString(key1)
+	encoded2 := base64.StdEncoding.EncodeToString(key2)
+
+	ring, err := ParseKeys(encoded1)
+	Assert(t, err, NilVal())
+	id, key, _ := ring.CurrentKey()
+	Assert(t, id, Equal(defaultKeyID))
+	Assert(t, key, Equal(key1))
+
+	ring, err = ParseKeys("k2:" + encoded2 + ", k1:" + encoded1)
+	Assert(t, err, NilVal())
+	id, key, _ = ring.CurrentKey()
+	Assert(t, id, Equal("k2"))
+	Assert(t, key, Equal(key2))
+	key, err = ring.Key("k1")
+	Assert(t, err, NilVal())
+	Assert(t, key, Equal(key1))
+
+	_, err = ParseKeys("")
+	Assert(t, err, NotNilVal())
+	_, err = ParseKeys("k1:not-base64!")
+	Assert(t, err, NotNilVal())
+	_, err = ParseKeys("k1:" + encoded1 + ",k1:" + encoded2)
+	Assert(t, err, NotNilVal())
+	_, err = ParseKeys("k1:" + base64.StdEncoding.EncodeToString([]byte("short")))
+	Assert(t, err, NotNilVal())
+}
+
+func TestEnvKeyProvider(t *testing.T) {
+	env := "AGOLLO_TEST_BACKUP_KEY"
+	defer os.Unsetenv(env)
+	provider := &EnvKeyProvider{Env: env}
+
+	_, _, err := provider.CurrentKey()
+	Assert(t, err, NotNilVal())
+
+	os.Setenv(env, "k1:"+base64.StdEncoding.EncodeToString(key1))
+	id, key, err := provider.CurrentKey()
+	Assert(t, err, NilVal())
+	Assert(t, id, Equal("k1"))
+	Assert(t, key, Equal(key1))
+}
+
+func TestFileKeyProvider(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "keys")
+	content := "k2:" + base64.StdEncoding.EncodeToString(key2) + "\nk1:" + base64.StdEncoding.EncodeToString(key1) + "\n"
+	Assert(t, ioutil.WriteFile(path, []byte(content), 0600), NilVal())
+
+	provider := &FileKeyProvider{Path: path}
+	id, _, err := provider.CurrentKey()
+	Assert(t, err, NilVal())
+	Assert(t, id, Equal("k2"))
+	key, err := provider.Key("k1")
+	Assert(t, err, NilVal())
+	Assert(t, key, Equal(key1))
+
+	_, err = (&FileKeyProvider{Path: filepath.Join(dir, "missing")}).Key("k1")
+	Assert(t, err, NotNilVal())
+}
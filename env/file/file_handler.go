@@ -18,6 +18,8 @@
 package file
 
 import (
+	"time"
+
 	"github.com/snailzed/agollo/v4/env/config"
 )
 
@@ -27,3 +29,25 @@ type FileHandler interface {
 	GetConfigFile(configDir string, appID string, namespace string) string
 	LoadConfigFile(configDir string, appID string, namespace string) (*config.ApolloConfig, error)
 }
+
+//Release 本地保存的历史版本
+type Release struct {
+	ReleaseKey string
+	//Time 写入备份的时间
+	Time time.Time
+}
+
+//ReleaseHistory 保存历史版本的备份文件读写，FileHandler 可选实现
+type ReleaseHistory interface {
+	//ListReleases 获取 namespace 本地保存的历史版本，按写入时间从新到旧排列
+	ListReleases(configDir string, appID string, namespace string) ([]*Release, error)
+	//LoadRelease 加载 namespace 指定 releaseKey 的历史版本
+	LoadRelease(configDir string, appID string, namespace string, releaseKey string) (*config.ApolloConfig, error)
+}
+
+//HistoryWriter 只写入历史版本的备份文件读写，FileHandler 可选实现
+//连续的多次更新只需写入一次当前备份文件，其余版本通过 WriteHistory 保存
+type HistoryWriter interface {
+	//WriteHistory 保存 config 的历史版本，不修改当前的备份文件
+	WriteHistory(config *config.ApolloConfig, configDir string) error
+}
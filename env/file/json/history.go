@@ -0,0 +1,176 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package json
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snailzed/agollo/v4/env/config"
+	"github.com/snailzed/agollo/v4/env/file"
+)
+
+const (
+	//DefaultMaxHistory 默认每个 namespace 保留的历史版本数量
+	DefaultMaxHistory = 5
+
+	historySuffix    = ".history"
+	historySeparator = "_"
+)
+
+//historyLocks 按历史目录串行化写操作
+var historyLocks sync.Map
+
+type historyEntry struct {
+	release  *file.Release
+	fileName string
+}
+
+func (fileHandler *FileHandler) maxHistory() int {
+	if fileHandler == nil || fileHandler.MaxHistory == 0 {
+		return DefaultMaxHistory
+	}
+	return fileHandler.MaxHistory
+}
+
+//getHistoryDir 历史版本目录 {configDir}/{appID}-{namespace}.history
+func getHistoryDir(configDir string, appID string, namespace string) string {
+	name := fmt.Sprintf("%s-%s%s", appID, namespace, historySuffix)
+	if configDir != "" {
+		return fmt.Sprintf("%s/%s", configDir, name)
+	}
+	return name
+}
+
+//WriteHistory 只保存历史版本，不修改当前的备份文件
+func (fileHandler *FileHandler) WriteHistory(config *config.ApolloConfig, configDir string) error {
+	return fileHandler.writeHistory(config, configDir)
+}
+
+//writeHistory 保存历史版本，同一 releaseKey 只保留最新写入的一份，超出数量时删除最旧的版本
+func (fileHandler *FileHandler) writeHistory(config *config.ApolloConfig, configDir string) error {
+	max := fileHandler.maxHistory()
+	if max < 0 || config.ReleaseKey == "" {
+		return nil
+	}
+
+	dir := getHistoryDir(configDir, config.AppID, config.NamespaceName)
+	lock, _ := historyLocks.LoadOrStore(dir, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	entries, err := listHistory(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.release.ReleaseKey == config.ReleaseKey {
+			_ = fileHandler.configFile().Remove(entry.fileName)
+		}
+	}
+
+	fileName := fmt.Sprintf("%s/%d%s%s%s", dir, time.Now().UnixNano(), historySeparator, url.QueryEscape(config.ReleaseKey), Suffix)
+	if err := fileHandler.configFile().Write(config, fileName); err != nil {
+		return err
+	}
+
+	entries, err = listHistory(dir)
+	if err != nil {
+		return err
+	}
+	for i := max; i < len(entries); i++ {
+		_ = fileHandler.configFile().Remove(entries[i].fileName)
+	}
+	return nil
+}
+
+//listHistory 读取历史目录，按写入时间从新到旧排列
+func listHistory(dir string) ([]*historyEntry, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	entries := make([]*historyEntry, 0, len(files))
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasSuffix(name, Suffix) {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimSuffix(name, Suffix), historySeparator, 2)
+		if len(parts) != 2 {
+			continue
+		}
+		nano, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		releaseKey, err := url.QueryUnescape(parts[1])
+		if err != nil {
+			continue
+		}
+		entries = append(entries, &historyEntry{
+			release:  &file.Release{ReleaseKey: releaseKey, Time: time.Unix(0, nano)},
+			fileName: fmt.Sprintf("%s/%s", dir, name),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].release.Time.After(entries[j].release.Time)
+	})
+	return entries, nil
+}
+
+//ListReleases 获取 namespace 本地保存的历史版本，按写入时间从新到旧排列
+func (fileHandler *FileHandler) ListReleases(configDir string, appID string, namespace string) ([]*file.Release, error) {
+	entries, err := listHistory(getHistoryDir(configDir, appID, namespace))
+	if err != nil {
+		return nil, err
+	}
+	releases := make([]*file.Release, 0, len(entries))
+	for _, entry := range entries {
+		releases = append(releases, entry.release)
+	}
+	return releases, nil
+}
+
+//LoadRelease 加载 namespace 指定 releaseKey 的历史版本
+func (fileHandler *FileHandler) LoadRelease(configDir string, appID string, namespace string, releaseKey string) (*config.ApolloConfig, error) {
+	entries, err := listHistory(getHistoryDir(configDir, appID, namespace))
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.release.ReleaseKey == releaseKey {
+			return fileHandler.loadApolloConfig(entry.fileName)
+		}
+	}
+	return nil, fmt.Errorf("release %s of namespace %s is not found in local history", releaseKey, namespace)
+}
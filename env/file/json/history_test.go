@@ -0,0 +1,105 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package json
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/snailzed/agollo/v4/env/config"
+	. "github.com/tevid/gohamcrest"
+)
+
+func createHistoryConfig(releaseKey string, value string) *config.ApolloConfig {
+	apolloConfig := &config.ApolloConfig{}
+	apolloConfig.AppID = "history"
+	apolloConfig.NamespaceName = "application"
+	apolloConfig.ReleaseKey = releaseKey
+	apolloConfig.Configurations = map[string]interface{}{"key": value}
+	return apolloConfig
+}
+
+func TestReleaseHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agollo-history")
+	Assert(t, err, NilVal())
+	defer os.RemoveAll(dir)
+
+	f := &FileHandler{MaxHistory: 3}
+	for i := 1; i <= 5; i++ {
+		err = f.WriteConfigFile(createHistoryConfig(fmt.Sprintf("release/%d", i), fmt.Sprint(i)), dir)
+		Assert(t, err, NilVal())
+	}
+	//重复的 releaseKey 只保留最新写入的一份
+	err = f.WriteConfigFile(createHistoryConfig("release/4", "4"), dir)
+	Assert(t, err, NilVal())
+
+	releases, err := f.ListReleases(dir, "history", "application")
+	Assert(t, err, NilVal())
+	Assert(t, len(releases), Equal(3))
+	Assert(t, releases[0].ReleaseKey, Equal("release/4"))
+	Assert(t, releases[1].ReleaseKey, Equal("release/5"))
+	Assert(t, releases[2].ReleaseKey, Equal("release/3"))
+
+	apolloConfig, err := f.LoadRelease(dir, "history", "application", "release/3")
+	Assert(t, err, NilVal())
+	Assert(t, apolloConfig.ReleaseKey, Equal("release/3"))
+	Assert(t, apolloConfig.Configurations["key"], Equal("3"))
+
+	_, err = f.LoadRelease(dir, "history", "application", "release/1")
+	Assert(t, err, NotNilVal())
+
+	releases, err = f.ListReleases(dir, "history", "other")
+	Assert(t, err, NilVal())
+	Assert(t, len(releases), Equal(0))
+}
+
+func TestWriteHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agollo-history")
+	Assert(t, err, NilVal())
+	defer os.RemoveAll(dir)
+
+	f := &FileHandler{}
+	Assert(t, f.WriteConfigFile(createHistoryConfig("release/1", "1"), dir), NilVal())
+	Assert(t, f.WriteHistory(createHistoryConfig("release/2", "2"), dir), NilVal())
+
+	//只写入历史版本，当前备份文件不变
+	apolloConfig, err := f.LoadConfigFile(dir, "history", "application")
+	Assert(t, err, NilVal())
+	Assert(t, apolloConfig.ReleaseKey, Equal("release/1"))
+
+	releases, err := f.ListReleases(dir, "history", "application")
+	Assert(t, err, NilVal())
+	Assert(t, len(releases), Equal(2))
+	Assert(t, releases[0].ReleaseKey, Equal("release/2"))
+}
+
+func TestReleaseHistoryDisabled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agollo-history")
+	Assert(t, err, NilVal())
+	defer os.RemoveAll(dir)
+
+	f := &FileHandler{MaxHistory: -1}
+	err = f.WriteConfigFile(createHistoryConfig("release", "value"), dir)
+	Assert(t, err, NilVal())
+
+	releases, err := f.ListReleases(dir, "history", "application")
+	Assert(t, err, NilVal())
+	Assert(t, len(releases), Equal(0))
+}
@@ -48,6 +48,38 @@ var (
 
 // FileHandler 默认备份文件读写
 type FileHandler struct {
+	// MaxHistory 每个 namespace 保留的历史版本数量，为0时使用 DefaultMaxHistory，小于0时不保留历史版本
+	MaxHistory int
+
+	codec  jsonConfig.Codec
+	perm   os.FileMode
+	logger log.LoggerInterface
+}
+
+//SetCodec 设置备份文件、历史版本及原始内容备份的编解码和文件权限，perm 为0时使用 0644
+func (fileHandler *FileHandler) SetCodec(codec jsonConfig.Codec, perm os.FileMode) {
+	fileHandler.codec = codec
+	fileHandler.perm = perm
+}
+
+//SetLogger 设置读写备份文件失败时使用的 logger，为 nil 时使用全局 logger
+func (fileHandler *FileHandler) SetLogger(logger log.LoggerInterface) {
+	fileHandler.logger = logger
+}
+
+func (fileHandler *FileHandler) getLogger() log.LoggerInterface {
+	if fileHandler == nil || fileHandler.logger == nil {
+		return log.Logger
+	}
+	return fileHandler.logger
+}
+
+//configFile 按编解码设置读写文件
+func (fileHandler *FileHandler) configFile() *jsonConfig.ConfigFile {
+	if fileHandler == nil || fileHandler.codec == nil && fileHandler.perm == 0 && fileHandler.logger == nil {
+		return jsonFileConfig
+	}
+	return &jsonConfig.ConfigFile{Codec: fileHandler.codec, Perm: fileHandler.perm, Logger: fileHandler.logger}
 }
 
 // WriteConfigFile write config to file
@@ -61,7 +93,7 @@ func (fileHandler *FileHandler) createDir(configPath string) error {
 	if !configFileDirMap[configPath] {
 		err := os.MkdirAll(configPath, os.ModePerm)
 		if err != nil && !os.IsExist(err) {
-			log.Errorf("Create backup dir:%s fail,error:&s", configPath, err)
+			fileHandler.getLogger().Errorf("Create backup dir:%s fail,error:%s", configPath, err)
 			return err
 		}
 		configFileDirMap[configPath] = true
@@ -75,7 +107,14 @@ func (fileHandler *FileHandler) WriteConfigFile(config *config.ApolloConfig, con
 	if err != nil {
 		return err
 	}
-	return jsonFileConfig.Write(config, fileHandler.GetConfigFile(configPath, config.AppID, config.NamespaceName))
+	err = fileHandler.configFile().Write(config, fileHandler.GetConfigFile(configPath, config.AppID, config.NamespaceName))
+	if err != nil {
+		return err
+	}
+	if err := fileHandler.writeHistory(config, configPath); err != nil {
+		fileHandler.getLogger().Errorf("write backup history fail, namespace:%s, error:%s", config.NamespaceName, err)
+	}
+	return nil
 }
 
 // GetConfigFile get real config file
@@ -98,15 +137,19 @@ func (fileHandler *FileHandler) GetConfigFile(configDir string, appID string, na
 //LoadConfigFile load config from file
 func (fileHandler *FileHandler) LoadConfigFile(configDir string, appID string, namespace string) (*config.ApolloConfig, error) {
 	configFilePath := fileHandler.GetConfigFile(configDir, appID, namespace)
-	log.Info("load config file from :", configFilePath)
-	c, e := jsonFileConfig.Load(configFilePath, func(b []byte) (interface{}, error) {
+	fileHandler.getLogger().Info("load config file from :", configFilePath)
+	return fileHandler.loadApolloConfig(configFilePath)
+}
+
+func (fileHandler *FileHandler) loadApolloConfig(configFilePath string) (*config.ApolloConfig, error) {
+	c, e := fileHandler.configFile().Load(configFilePath, func(b []byte) (interface{}, error) {
 		config := &config.ApolloConfig{}
 		e := json.NewDecoder(bytes.NewBuffer(b)).Decode(config)
 		return config, e
 	})
 
 	if c == nil || e != nil {
-		log.Errorf("loadConfigFile fail,error:%s", e)
+		fileHandler.getLogger().Errorf("loadConfigFile fail,error:%s", e)
 		return nil, e
 	}
 
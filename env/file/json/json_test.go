@@ -21,9 +21,12 @@ import (
 	"encoding/json"
 	"github.com/snailzed/agollo/v4/env/config"
 	"github.com/snailzed/agollo/v4/utils"
+	"io/ioutil"
 	"os"
 	"testing"
 
+	"github.com/snailzed/agollo/v4/agollotest/conformance"
+	"github.com/snailzed/agollo/v4/component/log"
 	"github.com/snailzed/agollo/v4/extension"
 	. "github.com/tevid/gohamcrest"
 )
@@ -72,7 +75,9 @@ func TestJSONFileHandler_WriteConfigDirFile(t *testing.T) {
 
 func TestJSONFileHandler_WriteConfigFile(t *testing.T) {
 	extension.SetFileHandler(&FileHandler{})
-	configPath := ""
+	configPath, err := ioutil.TempDir("", "agollo-json")
+	Assert(t, err, NilVal())
+	defer os.RemoveAll(configPath)
 	jsonStr := `{
   "appId": "100004458",
   "cluster": "default",
@@ -86,7 +91,6 @@ func TestJSONFileHandler_WriteConfigFile(t *testing.T) {
 }`
 
 	config, err := createApolloConfigWithJSON([]byte(jsonStr))
-	os.Remove(extension.GetFileHandler().GetConfigFile(configPath, config.AppID, config.NamespaceName))
 
 	Assert(t, err, NilVal())
 	e := extension.GetFileHandler().WriteConfigFile(config, configPath)
@@ -107,10 +111,15 @@ func TestJSONFileHandler_LoadConfigFile(t *testing.T) {
   "releaseKey": "20170430092936-dee2d58e74515ff3"
 }`
 
+	configPath, err := ioutil.TempDir("", "agollo-json")
+	Assert(t, err, NilVal())
+	defer os.RemoveAll(configPath)
+
 	config, err := createApolloConfigWithJSON([]byte(jsonStr))
 
 	Assert(t, err, NilVal())
-	newConfig, e := extension.GetFileHandler().LoadConfigFile("", config.AppID, config.NamespaceName)
+	Assert(t, extension.GetFileHandler().WriteConfigFile(config, configPath), NilVal())
+	newConfig, e := extension.GetFileHandler().LoadConfigFile(configPath, config.AppID, config.NamespaceName)
 
 	t.Log(newConfig)
 	Assert(t, e, NilVal())
@@ -128,3 +137,39 @@ func createApolloConfigWithJSON(b []byte) (*config.ApolloConfig, error) {
 	}
 	return apolloConfig, nil
 }
+
+//errorLogger 记录 Errorf 的调用次数
+type errorLogger struct {
+	log.DefaultLogger
+	errors int
+}
+
+func (l *errorLogger) Errorf(format string, params ...interface{}) {
+	l.errors++
+}
+
+func TestJSONFileHandler_SetLogger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agollo-logger")
+	Assert(t, err, NilVal())
+	defer os.RemoveAll(dir)
+
+	logger := &errorLogger{}
+	f := &FileHandler{}
+	f.SetLogger(logger)
+	Assert(t, f.configFile().Logger, Equal(log.LoggerInterface(logger)))
+
+	c, err := f.LoadConfigFile(dir, "logger", "application")
+	Assert(t, err, NotNilVal())
+	Assert(t, c, NilVal())
+	Assert(t, logger.errors, Equal(1))
+
+	//备份目录的上级是文件，无法创建目录
+	Assert(t, ioutil.WriteFile(dir+"/file", nil, 0644), NilVal())
+	err = f.WriteConfigFile(&config.ApolloConfig{}, dir+"/file/backup")
+	Assert(t, err, NotNilVal())
+	Assert(t, logger.errors, Equal(2))
+}
+
+func TestJSONFileHandlerConformance(t *testing.T) {
+	conformance.TestFileHandler(t, &FileHandler{})
+}
@@ -18,65 +18,183 @@
 package json
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/snailzed/agollo/v4/component/log"
-	"github.com/snailzed/agollo/v4/env/config"
 	"os"
+	"path"
 	"sync"
 
+	"github.com/snailzed/agollo/v4/constant"
+	"github.com/snailzed/agollo/v4/env/config"
 	"github.com/snailzed/agollo/v4/env/file"
+	"github.com/snailzed/agollo/v4/extension"
+	"github.com/snailzed/agollo/v4/utils/parse"
 )
 
+//rawSuffix 原始内容备份文件后缀，避免与 json 备份文件重名
+const rawSuffix = ".raw"
+
+//rawMetaSuffix 原始内容备份的描述文件后缀，记录原始内容对应的 releaseKey 及校验和
+const rawMetaSuffix = ".meta"
+
 var (
 	raw     file.FileHandler
 	rawOnce sync.Once
 )
 
-//rawFileHandler 写入备份文件时，同时写入原始内容和namespace类型
+//rawFileHandler 写入备份文件时，非 properties 格式的 namespace 同时保存原始内容
+//加载备份时使用对应格式的 ContentParser 重新解析原始内容，与实时拉取的结果保持一致
 type rawFileHandler struct {
 	*FileHandler
+	getParser func(format constant.ConfigFileFormat) parse.ContentParser
+}
+
+//CreateRawFileHandler 创建同时保存原始内容的备份文件读写，getParser 为 nil 时使用全局注册的 ContentParser
+func CreateRawFileHandler(getParser func(format constant.ConfigFileFormat) parse.ContentParser) file.FileHandler {
+	return WrapRawFileHandler(nil, getParser)
+}
+
+//WrapRawFileHandler 在 fileHandler 的基础上同时保存原始内容，fileHandler 为 nil 时使用默认设置
+func WrapRawFileHandler(fileHandler *FileHandler, getParser func(format constant.ConfigFileFormat) parse.ContentParser) file.FileHandler {
+	if fileHandler == nil {
+		fileHandler = &FileHandler{}
+	}
+	return &rawFileHandler{
+		FileHandler: fileHandler,
+		getParser:   getParser,
+	}
+}
+
+//rawMeta 原始内容备份的描述，releaseKey 与 json 备份一致且校验和匹配时才使用原始内容
+type rawMeta struct {
+	ReleaseKey string `json:"releaseKey"`
+	Checksum   string `json:"checksum"`
 }
 
-func writeWithRaw(config *config.ApolloConfig, configDir string) error {
-	filePath := ""
+//GetRawFile 原始内容备份文件路径 {configDir}/{appID}-{namespace}.raw，文件内容即 apollo 中的原始内容
+func GetRawFile(configDir string, appID string, namespace string) string {
+	filePath := fmt.Sprintf("%s-%s%s", appID, namespace, rawSuffix)
 	if configDir != "" {
-		filePath = fmt.Sprintf("%s/%s", configDir, config.NamespaceName)
-	} else {
-		filePath = config.NamespaceName
+		return fmt.Sprintf("%s/%s", configDir, filePath)
 	}
+	return filePath
+}
+
+func rawChecksum(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
 
-	file, e := os.Create(filePath)
-	if e != nil {
-		return e
+//writeWithRaw 先写原始内容再写描述文件，中途失败时描述文件与原始内容不匹配，加载时使用 json 备份
+func (fileHandler *rawFileHandler) writeWithRaw(config *config.ApolloConfig, configDir string) error {
+	if constant.ConfigFileFormat(path.Ext(config.NamespaceName)) == constant.Properties {
+		return nil
 	}
-	defer file.Close()
-	if config.Configurations["content"] != nil {
-		_, e = file.WriteString(config.Configurations["content"].(string))
-		if e != nil {
-			return e
-		}
+	content := config.Content
+	if content == "" {
+		content, _ = config.Configurations["content"].(string)
 	}
-	return nil
+	if content == "" {
+		return nil
+	}
+
+	rawFile := GetRawFile(configDir, config.AppID, config.NamespaceName)
+	if err := fileHandler.configFile().WriteContent([]byte(content), rawFile); err != nil {
+		return err
+	}
+	return fileHandler.configFile().Write(&rawMeta{
+		ReleaseKey: config.ReleaseKey,
+		Checksum:   rawChecksum([]byte(content)),
+	}, rawFile+rawMetaSuffix)
 }
 
 //WriteConfigFile write config to file
 func (fileHandler *rawFileHandler) WriteConfigFile(config *config.ApolloConfig, configPath string) error {
-	err := fileHandler.createDir(configPath)
-	if err != nil {
+	if err := fileHandler.FileHandler.WriteConfigFile(config, configPath); err != nil {
 		return err
 	}
+	//json 备份写入成功后再写原始内容，失败时删除旧的原始内容，避免与 json 备份不一致
+	if err := fileHandler.writeWithRaw(config, configPath); err != nil {
+		fileHandler.getLogger().Errorf("writeWithRaw fail, namespace:%s, error:%s", config.NamespaceName, err)
+		rawFile := GetRawFile(configPath, config.AppID, config.NamespaceName)
+		_ = fileHandler.configFile().Remove(rawFile + rawMetaSuffix)
+		_ = fileHandler.configFile().Remove(rawFile)
+	}
+	return nil
+}
+
+//LoadConfigFile load config from file，存在原始内容备份时重新解析
+func (fileHandler *rawFileHandler) LoadConfigFile(configDir string, appID string, namespace string) (*config.ApolloConfig, error) {
+	apolloConfig, err := fileHandler.FileHandler.LoadConfigFile(configDir, appID, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := fileHandler.parser(constant.ConfigFileFormat(path.Ext(namespace)))
+	if parser == nil {
+		return apolloConfig, nil
+	}
+	rawFile := GetRawFile(configDir, appID, namespace)
+	if _, err := os.Stat(rawFile); os.IsNotExist(err) {
+		return apolloConfig, nil
+	}
+	content, err := fileHandler.loadRaw(rawFile, apolloConfig.ReleaseKey)
+	if err != nil {
+		fileHandler.getLogger().Warnf("load raw config file fail, use json backup, namespace:%s, error:%s", namespace, err)
+		return apolloConfig, nil
+	}
 
-	err = writeWithRaw(config, configPath)
+	m, err := parser.Parse(content)
 	if err != nil {
-		log.Errorf("writeWithRaw fail! ", err)
+		fileHandler.getLogger().Errorf("parse raw config file fail, namespace:%s, error:%s", namespace, err)
+		return apolloConfig, nil
+	}
+	if len(m) > 0 {
+		apolloConfig.Configurations = m
+		apolloConfig.Content = content
+	}
+	return apolloConfig, nil
+}
+
+//loadRaw 读取原始内容，releaseKey 与 json 备份不一致或校验和不匹配时返回错误
+func (fileHandler *rawFileHandler) loadRaw(rawFile string, releaseKey string) (string, error) {
+	c, err := fileHandler.configFile().Load(rawFile+rawMetaSuffix, func(b []byte) (interface{}, error) {
+		meta := &rawMeta{}
+		e := json.Unmarshal(b, meta)
+		return meta, e
+	})
+	if err != nil {
+		return "", err
+	}
+	meta := c.(*rawMeta)
+	if meta.ReleaseKey != releaseKey {
+		return "", fmt.Errorf("raw config file is stale, releaseKey:%s, expect:%s", meta.ReleaseKey, releaseKey)
+	}
+
+	b, err := fileHandler.configFile().LoadContent(rawFile)
+	if err != nil {
+		return "", err
+	}
+	if rawChecksum(b) != meta.Checksum {
+		return "", errors.New("raw config file checksum mismatch")
+	}
+	return string(b), nil
+}
+
+func (fileHandler *rawFileHandler) parser(format constant.ConfigFileFormat) parse.ContentParser {
+	if fileHandler.getParser != nil {
+		return fileHandler.getParser(format)
 	}
-	return jsonFileConfig.Write(config, fileHandler.GetConfigFile(configPath, config.AppID, config.NamespaceName))
+	return extension.GetFormatParser(format)
 }
 
-// GetRawFileHandler 获取 rawFileHandler 实例
+// GetRawFileHandler 获取使用全局 ContentParser 的 rawFileHandler 实例
 func GetRawFileHandler() file.FileHandler {
 	rawOnce.Do(func() {
-		raw = &rawFileHandler{}
+		raw = CreateRawFileHandler(nil)
 	})
 	return raw
 }
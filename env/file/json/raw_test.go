@@ -18,10 +18,16 @@
 package json
 
 import (
+	"io/ioutil"
 	"os"
 	"testing"
 
+	"github.com/snailzed/agollo/v4/agollotest/conformance"
+	"github.com/snailzed/agollo/v4/constant"
+	"github.com/snailzed/agollo/v4/env/config"
 	"github.com/snailzed/agollo/v4/extension"
+	"github.com/snailzed/agollo/v4/utils/parse"
+	"github.com/snailzed/agollo/v4/utils/parse/yaml"
 	. "github.com/tevid/gohamcrest"
 )
 
@@ -51,7 +57,9 @@ func TestRawHandler_WriteConfigDirFile(t *testing.T) {
 
 func TestRawHandler_WriteConfigFile(t *testing.T) {
 	extension.SetFileHandler(&rawFileHandler{})
-	configPath := ""
+	configPath, err := ioutil.TempDir("", "agollo-raw")
+	Assert(t, err, NilVal())
+	defer os.RemoveAll(configPath)
 	jsonStr := `{
   "appId": "100004458",
   "cluster": "default",
@@ -65,7 +73,6 @@ func TestRawHandler_WriteConfigFile(t *testing.T) {
 }`
 
 	config, err := createApolloConfigWithJSON([]byte(jsonStr))
-	os.Remove(extension.GetFileHandler().GetConfigFile(configPath, config.AppID, config.NamespaceName))
 
 	Assert(t, err, NilVal())
 	e := extension.GetFileHandler().WriteConfigFile(config, configPath)
@@ -74,7 +81,9 @@ func TestRawHandler_WriteConfigFile(t *testing.T) {
 
 func TestRawHandler_WriteConfigFileWithContent(t *testing.T) {
 	extension.SetFileHandler(&rawFileHandler{})
-	configPath := ""
+	configPath, err := ioutil.TempDir("", "agollo-raw")
+	Assert(t, err, NilVal())
+	defer os.RemoveAll(configPath)
 	jsonStr := `{
   "appId": "100004458",
   "cluster": "default",
@@ -86,9 +95,6 @@ func TestRawHandler_WriteConfigFileWithContent(t *testing.T) {
 }`
 
 	config, err := createApolloConfigWithJSON([]byte(jsonStr))
-	Assert(t, err, NilVal())
-	os.Remove(extension.GetFileHandler().GetConfigFile(configPath, config.AppID, config.NamespaceName))
-
 	Assert(t, err, NilVal())
 	e := extension.GetFileHandler().WriteConfigFile(config, configPath)
 	Assert(t, e, NilVal())
@@ -101,3 +107,83 @@ func TestGetRawFileHandler(t *testing.T) {
 	fileHandler := GetRawFileHandler()
 	Assert(t, handler, Equal(fileHandler))
 }
+
+func TestRawFileHandlerConformance(t *testing.T) {
+	conformance.TestFileHandler(t, CreateRawFileHandler(nil))
+}
+
+func TestRawHandler_LoadConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agollo-raw")
+	Assert(t, err, NilVal())
+	defer os.RemoveAll(dir)
+
+	getParser := func(format constant.ConfigFileFormat) parse.ContentParser {
+		if format == constant.YAML {
+			return &yaml.Parser{}
+		}
+		return nil
+	}
+	handler := CreateRawFileHandler(getParser)
+
+	apolloConfig := &config.ApolloConfig{}
+	apolloConfig.AppID = "raw"
+	apolloConfig.NamespaceName = "application.yaml"
+	apolloConfig.ReleaseKey = "release"
+	apolloConfig.Content = "a:\n  b: 1\n"
+	apolloConfig.Configurations = map[string]interface{}{"a.b": 1}
+	Assert(t, handler.WriteConfigFile(apolloConfig, dir), NilVal())
+
+	//原始内容按原样保存
+	rawFile := GetRawFile(dir, "raw", "application.yaml")
+	b, err := ioutil.ReadFile(rawFile)
+	Assert(t, err, NilVal())
+	Assert(t, string(b), Equal(apolloConfig.Content))
+
+	writeRaw := func(releaseKey string, content string) {
+		c := &config.ApolloConfig{}
+		c.AppID = "raw"
+		c.NamespaceName = "application.yaml"
+		c.ReleaseKey = releaseKey
+		c.Content = content
+		Assert(t, handler.(*rawFileHandler).writeWithRaw(c, dir), NilVal())
+	}
+
+	//原始内容优先于 json 备份中的解析结果
+	writeRaw("release", "a:\n  b: 2\n")
+	loaded, err := handler.LoadConfigFile(dir, "raw", "application.yaml")
+	Assert(t, err, NilVal())
+	Assert(t, loaded.Content, Equal("a:\n  b: 2\n"))
+	Assert(t, loaded.Configurations["a.b"], Equal(2))
+
+	//原始内容无法解析时使用 json 备份
+	writeRaw("release", "- a\n")
+	loaded, err = handler.LoadConfigFile(dir, "raw", "application.yaml")
+	Assert(t, err, NilVal())
+	Assert(t, loaded.Configurations["a.b"], Equal(float64(1)))
+
+	//原始内容与 json 备份的 releaseKey 不一致时使用 json 备份
+	writeRaw("other", "a:\n  b: 2\n")
+	loaded, err = handler.LoadConfigFile(dir, "raw", "application.yaml")
+	Assert(t, err, NilVal())
+	Assert(t, loaded.Configurations["a.b"], Equal(float64(1)))
+
+	//原始内容与校验和不一致时使用 json 备份
+	writeRaw("release", "a:\n  b: 2\n")
+	Assert(t, ioutil.WriteFile(rawFile, []byte("a:\n  b: 3\n"), 0644), NilVal())
+	loaded, err = handler.LoadConfigFile(dir, "raw", "application.yaml")
+	Assert(t, err, NilVal())
+	Assert(t, loaded.Configurations["a.b"], Equal(float64(1)))
+
+	//缺少描述文件时使用 json 备份
+	writeRaw("release", "a:\n  b: 2\n")
+	Assert(t, os.Remove(rawFile+rawMetaSuffix), NilVal())
+	loaded, err = handler.LoadConfigFile(dir, "raw", "application.yaml")
+	Assert(t, err, NilVal())
+	Assert(t, loaded.Configurations["a.b"], Equal(float64(1)))
+
+	//没有原始内容备份时使用 json 备份
+	Assert(t, os.Remove(rawFile), NilVal())
+	loaded, err = handler.LoadConfigFile(dir, "raw", "application.yaml")
+	Assert(t, err, NilVal())
+	Assert(t, loaded.Configurations["a.b"], Equal(float64(1)))
+}
@@ -19,6 +19,10 @@ package env
 
 import (
 	"time"
+
+	"github.com/snailzed/agollo/v4/env/config"
+	"github.com/snailzed/agollo/v4/extension"
+	"github.com/snailzed/agollo/v4/protocol/httpclient"
 )
 
 //ConnectConfig 网络请求配置
@@ -33,4 +37,28 @@ type ConnectConfig struct {
 	AppID string
 	//密钥
 	Secret string
+	//客户端级别的扩展组件，为空时使用全局组件
+	Components *extension.Components
+	//TLS 配置，为空时使用系统证书校验服务端
+	TLS *config.TLSConfig
+	//请求类型，用于 http.Client 工厂区分长轮询与普通请求
+	RequestType httpclient.RequestType
+	//重试策略，为空时 IsRetry 的请求使用默认策略
+	RetryPolicy *config.RetryPolicy
+}
+
+//GetComponents 获取客户端级别的扩展组件
+func (c *ConnectConfig) GetComponents() *extension.Components {
+	if c == nil {
+		return nil
+	}
+	return c.Components
+}
+
+//GetRetryPolicy 获取重试策略
+func (c *ConnectConfig) GetRetryPolicy() *config.RetryPolicy {
+	if c == nil {
+		return nil
+	}
+	return c.RetryPolicy
 }
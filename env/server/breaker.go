@@ -0,0 +1,187 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"time"
+
+	"github.com/snailzed/agollo/v4/env/config"
+)
+
+//NodeState 节点熔断状态
+type NodeState int
+
+const (
+	//StateClosed 熔断关闭，节点正常提供服务
+	StateClosed NodeState = iota
+	//StateOpen 熔断打开，冷却期内不再请求该节点
+	StateOpen
+	//StateHalfOpen 冷却结束，允许请求探测节点是否恢复
+	StateHalfOpen
+)
+
+var (
+	//now 便于测试替换
+	now = time.Now
+)
+
+//String 状态名称
+func (s NodeState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+//NodeHealth 节点健康状态
+type NodeHealth struct {
+	//HomepageURL 节点地址
+	HomepageURL string
+	//State 熔断状态
+	State NodeState
+	//Requests 统计窗口内的请求数
+	Requests int
+	//Failures 统计窗口内失败的请求数
+	Failures int
+	//OpenedAt 最近一次打开熔断的时间，未打开过时为零值
+	OpenedAt time.Time
+	//RetryAt 熔断打开时允许再次请求的时间
+	RetryAt time.Time
+}
+
+//circuitBreaker 单个节点的熔断器，由 Manager 加锁访问
+type circuitBreaker struct {
+	state NodeState
+	//results 最近请求结果的环形窗口，true 表示失败
+	results  []bool
+	next     int
+	requests int
+	failures int
+	openedAt time.Time
+	retryAt  time.Time
+	//probeUntil 半开状态下探测请求的截止时间，未到期前不允许其他请求
+	probeUntil time.Time
+}
+
+//allow 是否允许请求节点，打开状态冷却结束后转为半开，半开状态下已有探测请求时不允许
+func (b *circuitBreaker) allow() bool {
+	if b.state == StateOpen && !now().Before(b.retryAt) {
+		b.state = StateHalfOpen
+	}
+	switch b.state {
+	case StateOpen:
+		return false
+	case StateHalfOpen:
+		return !b.probing()
+	}
+	return true
+}
+
+//acquire 占用一次请求，半开状态下只允许一个探测请求，结果返回或超过冷却时长后才允许下一个
+func (b *circuitBreaker) acquire(breakerConfig *config.CircuitBreakerConfig) bool {
+	if !b.allow() {
+		return false
+	}
+	if b.state == StateHalfOpen {
+		b.probeUntil = now().Add(breakerConfig.GetCoolDown())
+	}
+	return true
+}
+
+func (b *circuitBreaker) probing() bool {
+	return now().Before(b.probeUntil)
+}
+
+//onSuccess 记录一次成功请求，半开状态下关闭熔断
+func (b *circuitBreaker) onSuccess(breakerConfig *config.CircuitBreakerConfig) {
+	b.probeUntil = time.Time{}
+	if b.state != StateClosed {
+		b.state = StateClosed
+		b.reset()
+	}
+	b.record(false, breakerConfig.GetWindowSize())
+}
+
+//onFailure 记录一次失败请求，失败率达到阈值或半开探测失败时打开熔断
+func (b *circuitBreaker) onFailure(breakerConfig *config.CircuitBreakerConfig) {
+	b.probeUntil = time.Time{}
+	if b.state != StateClosed {
+		b.open(breakerConfig.GetCoolDown())
+		return
+	}
+	b.record(true, breakerConfig.GetWindowSize())
+	if b.requests >= breakerConfig.GetMinRequests() &&
+		float64(b.failures)/float64(b.requests) >= breakerConfig.GetFailureRateThreshold() {
+		b.open(breakerConfig.GetCoolDown())
+	}
+}
+
+func (b *circuitBreaker) open(coolDown time.Duration) {
+	b.state = StateOpen
+	b.openedAt = now()
+	b.retryAt = b.openedAt.Add(coolDown)
+	b.reset()
+}
+
+func (b *circuitBreaker) reset() {
+	b.results = nil
+	b.next = 0
+	b.requests = 0
+	b.failures = 0
+}
+
+func (b *circuitBreaker) record(failed bool, windowSize int) {
+	if len(b.results) != windowSize {
+		b.reset()
+		b.results = make([]bool, windowSize)
+	}
+	if b.requests == windowSize {
+		if b.results[b.next] {
+			b.failures--
+		}
+	} else {
+		b.requests++
+	}
+	b.results[b.next] = failed
+	if failed {
+		b.failures++
+	}
+	b.next = (b.next + 1) % windowSize
+}
+
+func (b *circuitBreaker) health(homepageURL string) *NodeHealth {
+	health := &NodeHealth{
+		HomepageURL: homepageURL,
+		State:       b.state,
+		Requests:    b.requests,
+		Failures:    b.failures,
+		OpenedAt:    b.openedAt,
+	}
+	if b.state == StateOpen {
+		health.RetryAt = b.retryAt
+		if !now().Before(b.retryAt) {
+			health.State = StateHalfOpen
+		}
+	}
+	return health
+}
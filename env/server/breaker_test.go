@@ -0,0 +1,156 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/snailzed/agollo/v4/env/config"
+	. "github.com/tevid/gohamcrest"
+)
+
+func mockNow() *time.Time {
+	current := time.Now()
+	now = func() time.Time {
+		return current
+	}
+	return &current
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	current := mockNow()
+	defer func() {
+		now = time.Now
+	}()
+	breakerConfig := &config.CircuitBreakerConfig{
+		WindowSize:           4,
+		MinRequests:          2,
+		FailureRateThreshold: 0.5,
+		CoolDown:             10,
+	}
+	b := &circuitBreaker{}
+	Assert(t, b.allow(), Equal(true))
+
+	//未达到最少请求数
+	b.onFailure(breakerConfig)
+	Assert(t, b.state, Equal(StateClosed))
+
+	//窗口内 3 次成功 1 次失败，失败率 0.25
+	b.onSuccess(breakerConfig)
+	b.onSuccess(breakerConfig)
+	b.onSuccess(breakerConfig)
+	Assert(t, b.state, Equal(StateClosed))
+	Assert(t, b.requests, Equal(4))
+	Assert(t, b.failures, Equal(1))
+
+	//最早的失败移出窗口后再失败两次，失败率 0.5
+	b.onFailure(breakerConfig)
+	Assert(t, b.failures, Equal(1))
+	b.onFailure(breakerConfig)
+	Assert(t, b.state, Equal(StateOpen))
+	Assert(t, b.allow(), Equal(false))
+	health := b.health("host")
+	Assert(t, health.State, Equal(StateOpen))
+	Assert(t, health.RetryAt, Equal(current.Add(10*time.Second)))
+
+	//冷却结束后半开，探测失败重新打开
+	*current = current.Add(10 * time.Second)
+	Assert(t, b.health("host").State, Equal(StateHalfOpen))
+	Assert(t, b.allow(), Equal(true))
+	Assert(t, b.state, Equal(StateHalfOpen))
+	b.onFailure(breakerConfig)
+	Assert(t, b.state, Equal(StateOpen))
+	Assert(t, b.allow(), Equal(false))
+
+	//探测成功后关闭
+	*current = current.Add(10 * time.Second)
+	Assert(t, b.allow(), Equal(true))
+	b.onSuccess(breakerConfig)
+	Assert(t, b.state, Equal(StateClosed))
+	Assert(t, b.requests, Equal(1))
+	Assert(t, b.failures, Equal(0))
+}
+
+func TestManagerCircuitBreaker(t *testing.T) {
+	current := mockNow()
+	defer func() {
+		now = time.Now
+	}()
+	breakerConfig := &config.CircuitBreakerConfig{
+		MinRequests:          1,
+		FailureRateThreshold: 1,
+		CoolDown:             10,
+	}
+	manager := CreateManager()
+	manager.SetServers(name, map[string]*config.ServerInfo{
+		"a": {HomepageURL: "a"},
+		"b": {HomepageURL: "b"},
+	})
+	Assert(t, len(manager.GetAvailableServers(name)), Equal(2))
+
+	manager.MarkFailure(name, "a", breakerConfig)
+	manager.MarkSuccess(name, "b", breakerConfig)
+	//不在服务列表中的节点忽略
+	manager.MarkFailure(name, "c", breakerConfig)
+	servers := manager.GetAvailableServers(name)
+	Assert(t, len(servers), Equal(1))
+	Assert(t, servers["b"], NotNilVal())
+
+	healths := manager.GetNodeHealth(name)
+	Assert(t, len(healths), Equal(2))
+	Assert(t, healths[0].HomepageURL, Equal("a"))
+	Assert(t, healths[0].State, Equal(StateOpen))
+	Assert(t, healths[1].HomepageURL, Equal("b"))
+	Assert(t, healths[1].State, Equal(StateClosed))
+	Assert(t, healths[1].Requests, Equal(1))
+
+	//刷新服务列表保留熔断状态
+	manager.SetServers(name, map[string]*config.ServerInfo{
+		"a": {HomepageURL: "a"},
+		"b": {HomepageURL: "b"},
+	})
+	Assert(t, len(manager.GetAvailableServers(name)), Equal(1))
+
+	//冷却结束后半开，只允许一个探测请求
+	*current = current.Add(10 * time.Second)
+	Assert(t, len(manager.GetAvailableServers(name)), Equal(2))
+	Assert(t, manager.TryAcquire(name, "a", breakerConfig), Equal(true))
+	Assert(t, manager.TryAcquire(name, "a", breakerConfig), Equal(false))
+	Assert(t, len(manager.GetAvailableServers(name)), Equal(1))
+	Assert(t, manager.TryAcquire(name, "b", breakerConfig), Equal(true))
+	Assert(t, manager.TryAcquire(name, "b", breakerConfig), Equal(true))
+
+	//探测超过冷却时长未返回结果时允许再次探测
+	*current = current.Add(10 * time.Second)
+	Assert(t, manager.TryAcquire(name, "a", breakerConfig), Equal(true))
+	manager.MarkSuccess(name, "a", breakerConfig)
+	Assert(t, manager.GetNodeHealth(name)[0].State, Equal(StateClosed))
+	Assert(t, manager.TryAcquire(name, "a", breakerConfig), Equal(true))
+	Assert(t, manager.TryAcquire(name, "a", breakerConfig), Equal(true))
+
+	//SetDownNodeWithConfig 使用配置的冷却时长
+	manager.SetDownNodeWithConfig(name, "a", &config.CircuitBreakerConfig{CoolDown: 60})
+	Assert(t, manager.GetNodeHealth(name)[0].RetryAt, Equal(current.Add(60*time.Second)))
+	manager.SetDownNode(name, "b")
+	Assert(t, manager.GetNodeHealth(name)[1].RetryAt, Equal(current.Add(config.DefaultBreakerCoolDown*time.Second)))
+	Assert(t, manager.TryAcquire(name, "a", breakerConfig), Equal(false))
+	Assert(t, manager.TryAcquire("unknown", "a", breakerConfig), Equal(true))
+
+	Assert(t, CreateManager().GetNodeHealth(name), NilVal())
+}
@@ -18,6 +18,7 @@
 package server
 
 import (
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -27,56 +28,176 @@ import (
 
 // ip -> server
 var (
-	ipMap      map[string]*Info
-	serverLock sync.Mutex
+	ipMap map[string]*Info
 	//next try connect period - 60 second
 	nextTryConnectPeriod int64 = 30
+	//defaultManager 全局默认的节点管理器
+	defaultManager *Manager
 )
 
 func init() {
 	ipMap = make(map[string]*Info)
+	defaultManager = &Manager{
+		ipMap: ipMap,
+	}
 }
 
 type Info struct {
 	//real servers ip
 	serverMap       map[string]*config.ServerInfo
 	nextTryConnTime int64
+	//HomepageURL -> 节点熔断器
+	breakers map[string]*circuitBreaker
+}
+
+//getBreaker 获取节点的熔断器，节点不在服务列表中时返回 nil
+func (s *Info) getBreaker(host string) *circuitBreaker {
+	if s == nil || s.serverMap[host] == nil {
+		return nil
+	}
+	if s.breakers == nil {
+		s.breakers = make(map[string]*circuitBreaker)
+	}
+	b := s.breakers[host]
+	if b == nil {
+		b = &circuitBreaker{}
+		s.breakers[host] = b
+	}
+	return b
+}
+
+//Manager 管理 config server 节点信息，每个客户端可以持有独立的实例
+type Manager struct {
+	ipMap      map[string]*Info
+	serverLock sync.Mutex
+}
+
+//CreateManager 创建节点管理器
+func CreateManager() *Manager {
+	return &Manager{
+		ipMap: make(map[string]*Info),
+	}
+}
+
+//GetDefaultManager 获取全局默认的节点管理器
+func GetDefaultManager() *Manager {
+	return defaultManager
 }
 
 //GetServersLen 获取服务器数组
 func GetServers(configIp string) map[string]*config.ServerInfo {
-	serverLock.Lock()
-	defer serverLock.Unlock()
-	if ipMap[configIp] == nil {
+	return defaultManager.GetServers(configIp)
+}
+
+//GetServersLen 获取服务器数组长度
+func GetServersLen(configIp string) int {
+	return defaultManager.GetServersLen(configIp)
+}
+
+func SetServers(configIp string, serverMap map[string]*config.ServerInfo) {
+	defaultManager.SetServers(configIp, serverMap)
+}
+
+//SetDownNode 设置失效节点，使用默认冷却时长
+func SetDownNode(configIp string, host string) {
+	defaultManager.SetDownNode(configIp, host)
+}
+
+//SetDownNodeWithConfig 设置失效节点，breakerConfig 为 nil 时使用默认冷却时长
+func SetDownNodeWithConfig(configIp string, host string, breakerConfig *config.CircuitBreakerConfig) {
+	defaultManager.SetDownNodeWithConfig(configIp, host, breakerConfig)
+}
+
+//GetAvailableServers 获取熔断未打开的服务器
+func GetAvailableServers(configIp string) map[string]*config.ServerInfo {
+	return defaultManager.GetAvailableServers(configIp)
+}
+
+//TryAcquire 占用节点的一次请求，熔断打开或半开探测中时返回 false
+func TryAcquire(configIp string, host string, breakerConfig *config.CircuitBreakerConfig) bool {
+	return defaultManager.TryAcquire(configIp, host, breakerConfig)
+}
+
+//MarkSuccess 记录节点请求成功
+func MarkSuccess(configIp string, host string, breakerConfig *config.CircuitBreakerConfig) {
+	defaultManager.MarkSuccess(configIp, host, breakerConfig)
+}
+
+//MarkFailure 记录节点请求失败
+func MarkFailure(configIp string, host string, breakerConfig *config.CircuitBreakerConfig) {
+	defaultManager.MarkFailure(configIp, host, breakerConfig)
+}
+
+//GetNodeHealth 获取所有节点的健康状态
+func GetNodeHealth(configIp string) []*NodeHealth {
+	return defaultManager.GetNodeHealth(configIp)
+}
+
+//IsConnectDirectly is connect by ip directly
+//false : yes
+//true : no
+func IsConnectDirectly(configIp string) bool {
+	return defaultManager.IsConnectDirectly(configIp)
+}
+
+//SetNextTryConnTime if this connect is fail will set this time
+func SetNextTryConnTime(configIp string, nextPeriod int64) {
+	defaultManager.SetNextTryConnTime(configIp, nextPeriod)
+}
+
+//GetServers 获取服务器数组
+func (m *Manager) GetServers(configIp string) map[string]*config.ServerInfo {
+	m.serverLock.Lock()
+	defer m.serverLock.Unlock()
+	if m.ipMap[configIp] == nil {
 		return nil
 	}
-	return ipMap[configIp].serverMap
+	return m.ipMap[configIp].serverMap
 }
 
 //GetServersLen 获取服务器数组长度
-func GetServersLen(configIp string) int {
-	serverLock.Lock()
-	defer serverLock.Unlock()
-	s := ipMap[configIp]
+func (m *Manager) GetServersLen(configIp string) int {
+	m.serverLock.Lock()
+	defer m.serverLock.Unlock()
+	s := m.ipMap[configIp]
 	if s == nil || len(s.serverMap) == 0 {
 		return 0
 	}
 	return len(s.serverMap)
 }
 
-func SetServers(configIp string, serverMap map[string]*config.ServerInfo) {
-	serverLock.Lock()
-	defer serverLock.Unlock()
-	ipMap[configIp] = &Info{
+//SetServers 设置服务器列表
+func (m *Manager) SetServers(configIp string, serverMap map[string]*config.ServerInfo) {
+	m.serverLock.Lock()
+	defer m.serverLock.Unlock()
+	info := &Info{
 		serverMap: serverMap,
 	}
+	//刷新服务列表时保留仍存在节点的熔断状态
+	if old := m.ipMap[configIp]; old != nil {
+		for host, b := range old.breakers {
+			if serverMap[host] == nil {
+				continue
+			}
+			if info.breakers == nil {
+				info.breakers = make(map[string]*circuitBreaker)
+			}
+			info.breakers[host] = b
+		}
+	}
+	m.ipMap[configIp] = info
 }
 
-//SetDownNode 设置失效节点
-func SetDownNode(configIp string, host string) {
-	serverLock.Lock()
-	defer serverLock.Unlock()
-	s := ipMap[configIp]
+//SetDownNode 设置失效节点，使用默认冷却时长
+func (m *Manager) SetDownNode(configIp string, host string) {
+	m.SetDownNodeWithConfig(configIp, host, nil)
+}
+
+//SetDownNodeWithConfig 设置失效节点，打开匹配节点的熔断，冷却结束后自动恢复
+func (m *Manager) SetDownNodeWithConfig(configIp string, host string, breakerConfig *config.CircuitBreakerConfig) {
+	m.serverLock.Lock()
+	defer m.serverLock.Unlock()
+	s := m.ipMap[configIp]
 	if host == "" || s == nil || len(s.serverMap) == 0 {
 		return
 	}
@@ -85,21 +206,95 @@ func SetDownNode(configIp string, host string) {
 		s.nextTryConnTime = nextTryConnectPeriod
 	}
 
-	for k, server := range s.serverMap {
+	for k := range s.serverMap {
 		// if some node has down then select next node
 		if strings.Index(k, host) > -1 {
-			server.IsDown = true
+			s.getBreaker(k).open(breakerConfig.GetCoolDown())
+		}
+	}
+}
+
+//GetAvailableServers 获取熔断未打开的服务器，冷却结束的节点转为半开状态重新参与负载均衡，已有探测请求的半开节点除外
+func (m *Manager) GetAvailableServers(configIp string) map[string]*config.ServerInfo {
+	m.serverLock.Lock()
+	defer m.serverLock.Unlock()
+	s := m.ipMap[configIp]
+	if s == nil || len(s.serverMap) == 0 {
+		return nil
+	}
+	servers := make(map[string]*config.ServerInfo, len(s.serverMap))
+	for host, server := range s.serverMap {
+		if b := s.breakers[host]; b != nil && !b.allow() {
+			continue
 		}
+		servers[host] = server
 	}
+	return servers
+}
+
+//TryAcquire 请求节点前占用一次请求，半开状态的节点同时只允许一个探测请求
+//探测结果通过 MarkSuccess/MarkFailure 返回，超过冷却时长未返回时允许再次探测
+func (m *Manager) TryAcquire(configIp string, host string, breakerConfig *config.CircuitBreakerConfig) bool {
+	m.serverLock.Lock()
+	defer m.serverLock.Unlock()
+	s := m.ipMap[configIp]
+	if s == nil {
+		return true
+	}
+	b := s.breakers[host]
+	if b == nil {
+		return true
+	}
+	return b.acquire(breakerConfig)
+}
+
+//MarkSuccess 记录节点请求成功，半开状态的节点恢复为关闭
+func (m *Manager) MarkSuccess(configIp string, host string, breakerConfig *config.CircuitBreakerConfig) {
+	m.serverLock.Lock()
+	defer m.serverLock.Unlock()
+	if b := m.ipMap[configIp].getBreaker(host); b != nil {
+		b.onSuccess(breakerConfig)
+	}
+}
+
+//MarkFailure 记录节点请求失败，失败率达到阈值时打开熔断
+func (m *Manager) MarkFailure(configIp string, host string, breakerConfig *config.CircuitBreakerConfig) {
+	m.serverLock.Lock()
+	defer m.serverLock.Unlock()
+	if b := m.ipMap[configIp].getBreaker(host); b != nil {
+		b.onFailure(breakerConfig)
+	}
+}
+
+//GetNodeHealth 获取所有节点的健康状态，按 HomepageURL 排序
+func (m *Manager) GetNodeHealth(configIp string) []*NodeHealth {
+	m.serverLock.Lock()
+	defer m.serverLock.Unlock()
+	s := m.ipMap[configIp]
+	if s == nil || len(s.serverMap) == 0 {
+		return nil
+	}
+	healths := make([]*NodeHealth, 0, len(s.serverMap))
+	for host := range s.serverMap {
+		b := s.breakers[host]
+		if b == nil {
+			b = &circuitBreaker{}
+		}
+		healths = append(healths, b.health(host))
+	}
+	sort.Slice(healths, func(i, j int) bool {
+		return healths[i].HomepageURL < healths[j].HomepageURL
+	})
+	return healths
 }
 
 //IsConnectDirectly is connect by ip directly
 //false : yes
 //true : no
-func IsConnectDirectly(configIp string) bool {
-	serverLock.Lock()
-	defer serverLock.Unlock()
-	s := ipMap[configIp]
+func (m *Manager) IsConnectDirectly(configIp string) bool {
+	m.serverLock.Lock()
+	defer m.serverLock.Unlock()
+	s := m.ipMap[configIp]
 	if s == nil || len(s.serverMap) == 0 {
 		return false
 	}
@@ -111,16 +306,16 @@ func IsConnectDirectly(configIp string) bool {
 }
 
 //SetNextTryConnTime if this connect is fail will set this time
-func SetNextTryConnTime(configIp string, nextPeriod int64) {
-	serverLock.Lock()
-	defer serverLock.Unlock()
-	s := ipMap[configIp]
+func (m *Manager) SetNextTryConnTime(configIp string, nextPeriod int64) {
+	m.serverLock.Lock()
+	defer m.serverLock.Unlock()
+	s := m.ipMap[configIp]
 	if s == nil || len(s.serverMap) == 0 {
 		s = &Info{
 			serverMap:       nil,
 			nextTryConnTime: 0,
 		}
-		ipMap[configIp] = s
+		m.ipMap[configIp] = s
 	}
 	tmp := nextPeriod
 	if tmp == 0 {
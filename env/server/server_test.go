@@ -54,3 +54,15 @@ func TestAppConfig_IsConnectDirectly(t *testing.T) {
 	isConnectDirectly = IsConnectDirectly(name)
 	Assert(t, isConnectDirectly, Equal(false))
 }
+
+func TestManager(t *testing.T) {
+	m := make(map[string]*config.ServerInfo, 2)
+	m["b"] = &config.ServerInfo{}
+	manager := CreateManager()
+	manager.SetServers(name, m)
+	Assert(t, manager.GetServersLen(name), Equal(1))
+
+	other := CreateManager()
+	Assert(t, other.GetServersLen(name), Equal(0))
+	Assert(t, other.GetServers(name), NilVal())
+}
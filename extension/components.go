@@ -0,0 +1,117 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package extension
+
+import (
+	"github.com/snailzed/agollo/v4/agcache"
+	"github.com/snailzed/agollo/v4/cluster"
+	"github.com/snailzed/agollo/v4/component/log"
+	"github.com/snailzed/agollo/v4/constant"
+	"github.com/snailzed/agollo/v4/env/file"
+	"github.com/snailzed/agollo/v4/env/server"
+	"github.com/snailzed/agollo/v4/protocol/auth"
+	"github.com/snailzed/agollo/v4/protocol/httpclient"
+	"github.com/snailzed/agollo/v4/utils/parse"
+)
+
+//Components 客户端级别的扩展组件
+//未设置的组件使用全局组件（SetCacheFactory、SetLoadBalance 等），nil 的 *Components 等价于全部使用全局组件
+type Components struct {
+	CacheFactory      agcache.CacheFactory
+	LoadBalance       cluster.LoadBalance
+	FileHandler       file.FileHandler
+	HTTPAuth          auth.HTTPAuth
+	Logger            log.LoggerInterface
+	FormatParsers     map[constant.ConfigFileFormat]parse.ContentParser
+	ServerManager     *server.Manager
+	HTTPClientFactory httpclient.ClientFactory
+}
+
+//GetCacheFactory 获取CacheFactory
+func (c *Components) GetCacheFactory() agcache.CacheFactory {
+	if c == nil || c.CacheFactory == nil {
+		return GetCacheFactory()
+	}
+	return c.CacheFactory
+}
+
+//GetLoadBalance 获取负载均衡器
+func (c *Components) GetLoadBalance() cluster.LoadBalance {
+	if c == nil || c.LoadBalance == nil {
+		return GetLoadBalance()
+	}
+	return c.LoadBalance
+}
+
+//GetFileHandler 获取备份文件处理
+func (c *Components) GetFileHandler() file.FileHandler {
+	if c == nil || c.FileHandler == nil {
+		return GetFileHandler()
+	}
+	return c.FileHandler
+}
+
+//GetHTTPAuth 获取HttpAuth
+func (c *Components) GetHTTPAuth() auth.HTTPAuth {
+	if c == nil || c.HTTPAuth == nil {
+		return GetHTTPAuth()
+	}
+	return c.HTTPAuth
+}
+
+//GetHTTPClientFactory 获取创建 http.Client 的工厂
+func (c *Components) GetHTTPClientFactory() httpclient.ClientFactory {
+	if c == nil || c.HTTPClientFactory == nil {
+		return GetHTTPClientFactory()
+	}
+	return c.HTTPClientFactory
+}
+
+//GetLogger 获取logger
+func (c *Components) GetLogger() log.LoggerInterface {
+	if c == nil || c.Logger == nil {
+		return log.Logger
+	}
+	return c.Logger
+}
+
+//GetFormatParser 获取 formatParser，客户端未设置时使用全局 formatParser
+func (c *Components) GetFormatParser(key constant.ConfigFileFormat) parse.ContentParser {
+	if c != nil && c.FormatParsers != nil {
+		if p := c.FormatParsers[key]; p != nil {
+			return p
+		}
+	}
+	return GetFormatParser(key)
+}
+
+//AddFormatParser 为客户端设置 formatParser
+func (c *Components) AddFormatParser(key constant.ConfigFileFormat, contentParser parse.ContentParser) {
+	if c.FormatParsers == nil {
+		c.FormatParsers = make(map[constant.ConfigFileFormat]parse.ContentParser)
+	}
+	c.FormatParsers[key] = contentParser
+}
+
+//GetServerManager 获取节点管理器
+func (c *Components) GetServerManager() *server.Manager {
+	if c == nil || c.ServerManager == nil {
+		return server.GetDefaultManager()
+	}
+	return c.ServerManager
+}
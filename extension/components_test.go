@@ -0,0 +1,72 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package extension
+
+import (
+	"testing"
+
+	"github.com/snailzed/agollo/v4/component/log"
+	"github.com/snailzed/agollo/v4/constant"
+	"github.com/snailzed/agollo/v4/env/server"
+	. "github.com/tevid/gohamcrest"
+)
+
+func TestNilComponents(t *testing.T) {
+	var c *Components
+	SetCacheFactory(&TestCacheFactory{})
+	SetLoadBalance(&TestLoadBalance{})
+	SetFileHandler(&TestFileHandler{})
+	SetHTTPAuth(&TestAuth{})
+	AddFormatParser(constant.DEFAULT, &TestParser{})
+
+	Assert(t, c.GetCacheFactory(), Equal(GetCacheFactory()))
+	Assert(t, c.GetLoadBalance(), Equal(GetLoadBalance()))
+	Assert(t, c.GetFileHandler(), Equal(GetFileHandler()))
+	Assert(t, c.GetHTTPAuth(), Equal(GetHTTPAuth()))
+	Assert(t, c.GetLogger(), Equal(log.Logger))
+	Assert(t, c.GetFormatParser(constant.DEFAULT), Equal(GetFormatParser(constant.DEFAULT)))
+	Assert(t, c.GetServerManager(), Equal(server.GetDefaultManager()))
+	Assert(t, c.GetHTTPClientFactory(), Equal(GetHTTPClientFactory()))
+}
+
+func TestComponents(t *testing.T) {
+	SetCacheFactory(&TestCacheFactory{})
+	AddFormatParser(constant.DEFAULT, &TestParser{})
+
+	cacheFactory := &DefaultCacheFactory{}
+	logger := &log.DefaultLogger{}
+	parser := &TestParser{}
+	serverManager := server.CreateManager()
+	httpClientFactory := &TestHTTPClientFactory{}
+	c := &Components{
+		CacheFactory:      cacheFactory,
+		Logger:            logger,
+		ServerManager:     serverManager,
+		HTTPClientFactory: httpClientFactory,
+	}
+	c.AddFormatParser(constant.YAML, parser)
+
+	Assert(t, c.GetCacheFactory(), Equal(cacheFactory))
+	Assert(t, c.GetLogger(), Equal(logger))
+	Assert(t, c.GetServerManager(), Equal(serverManager))
+	Assert(t, c.GetHTTPClientFactory(), Equal(httpClientFactory))
+	Assert(t, c.GetFormatParser(constant.YAML), Equal(parser))
+	//fallback to global
+	Assert(t, c.GetFormatParser(constant.DEFAULT), Equal(GetFormatParser(constant.DEFAULT)))
+	Assert(t, c.GetHTTPAuth(), Equal(GetHTTPAuth()))
+}
@@ -2,9 +2,10 @@ module github.com/snailzed/agollo/v4
 
 require (
 	github.com/goccy/go-json v0.10.0
-	github.com/spf13/viper v1.7.1
+	github.com/hashicorp/hcl v1.0.0
+	github.com/pelletier/go-toml v1.2.0
 	github.com/tevid/gohamcrest v1.1.1
-	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+	gopkg.in/yaml.v2 v2.2.4
 )
 
 go 1.13
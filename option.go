@@ -0,0 +1,114 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agollo
+
+import (
+	"fmt"
+
+	"github.com/snailzed/agollo/v4/agcache"
+	"github.com/snailzed/agollo/v4/cluster"
+	"github.com/snailzed/agollo/v4/component/log"
+	"github.com/snailzed/agollo/v4/constant"
+	"github.com/snailzed/agollo/v4/env/file"
+	jsonFile "github.com/snailzed/agollo/v4/env/file/json"
+	"github.com/snailzed/agollo/v4/extension"
+	"github.com/snailzed/agollo/v4/protocol/auth"
+	"github.com/snailzed/agollo/v4/protocol/httpclient"
+	"github.com/snailzed/agollo/v4/utils/parse"
+)
+
+//Option 客户端配置项，用于 New
+type Option func(*clientOptions)
+
+//clientOptions 客户端级别的配置
+type clientOptions struct {
+	components *extension.Components
+	rawBackup  bool
+}
+
+//WithSignature 设置当前客户端的 http 授权控件
+func WithSignature(auth auth.HTTPAuth) Option {
+	return func(o *clientOptions) {
+		o.components.HTTPAuth = auth
+	}
+}
+
+//WithBackupFileHandler 设置当前客户端的备份文件处理组件
+func WithBackupFileHandler(file file.FileHandler) Option {
+	return func(o *clientOptions) {
+		o.components.FileHandler = file
+	}
+}
+
+//WithRawBackup 当前客户端备份时同时保存非 properties 格式 namespace 的原始内容，加载备份时使用当前客户端的内容转换器重新解析
+//与 WithBackupFileHandler 同时使用时包装其设置的 json 备份文件读写，与设置顺序无关，其它类型的 FileHandler 创建客户端时返回错误
+func WithRawBackup() Option {
+	return func(o *clientOptions) {
+		o.rawBackup = true
+	}
+}
+
+//initRawBackup 在当前客户端的备份文件读写上启用原始内容备份
+func (o *clientOptions) initRawBackup() error {
+	switch handler := o.components.FileHandler.(type) {
+	case nil:
+		o.components.FileHandler = jsonFile.CreateRawFileHandler(o.components.GetFormatParser)
+	case *jsonFile.FileHandler:
+		o.components.FileHandler = jsonFile.WrapRawFileHandler(handler, o.components.GetFormatParser)
+	default:
+		return fmt.Errorf("WithRawBackup only supports json FileHandler, got %T", handler)
+	}
+	return nil
+}
+
+//WithHTTPClientFactory 设置当前客户端创建 http.Client 的工厂
+func WithHTTPClientFactory(factory httpclient.ClientFactory) Option {
+	return func(o *clientOptions) {
+		o.components.HTTPClientFactory = factory
+	}
+}
+
+//WithLoadBalance 设置当前客户端的负载均衡组件
+func WithLoadBalance(loadBalance cluster.LoadBalance) Option {
+	return func(o *clientOptions) {
+		o.components.LoadBalance = loadBalance
+	}
+}
+
+//WithLogger 设置当前客户端的logger组件
+func WithLogger(loggerInterface log.LoggerInterface) Option {
+	return func(o *clientOptions) {
+		o.components.Logger = loggerInterface
+	}
+}
+
+//WithCache 设置当前客户端的cache组件
+func WithCache(cacheFactory agcache.CacheFactory) Option {
+	return func(o *clientOptions) {
+		o.components.CacheFactory = cacheFactory
+	}
+}
+
+//WithFormatParser 设置当前客户端的内容转换器
+func WithFormatParser(format constant.ConfigFileFormat, contentParser parse.ContentParser) Option {
+	return func(o *clientOptions) {
+		if contentParser != nil {
+			o.components.AddFormatParser(format, contentParser)
+		}
+	}
+}
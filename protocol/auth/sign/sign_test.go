@@ -18,8 +18,11 @@
 package sign
 
 import (
-	. "github.com/tevid/gohamcrest"
+	"fmt"
 	"testing"
+
+	"github.com/snailzed/agollo/v4/agollotest/conformance"
+	. "github.com/tevid/gohamcrest"
 )
 
 const (
@@ -47,3 +50,17 @@ func TestHttpHeaders(t *testing.T) {
 	Assert(t, headers, HasMapValue("Authorization"))
 	Assert(t, headers, HasMapValue("Timestamp"))
 }
+
+func TestAuthSignatureConformance(t *testing.T) {
+	conformance.TestHTTPAuth(t, &AuthSignature{}, func(url string, appID string, secret string, headers map[string][]string) error {
+		timestamps := headers[httpHeaderTimestamp]
+		if len(timestamps) != 1 {
+			return fmt.Errorf("want one %s header, got %v", httpHeaderTimestamp, timestamps)
+		}
+		want := fmt.Sprintf(authorizationFormat, appID, signString(timestamps[0]+delimiter+url2PathWithQuery(url), secret))
+		if authorizations := headers[httpHeaderAuthorization]; len(authorizations) != 1 || authorizations[0] != want {
+			return fmt.Errorf("want %s header %s, got %v", httpHeaderAuthorization, want, authorizations)
+		}
+		return nil
+	})
+}
@@ -18,65 +18,68 @@
 package http
 
 import (
-	"crypto/tls"
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"net"
 	"net/http"
 	url2 "net/url"
 	"strings"
-	"sync"
 	"time"
 
-	"github.com/snailzed/agollo/v4/env/server"
-
-	"github.com/snailzed/agollo/v4/component/log"
 	"github.com/snailzed/agollo/v4/env"
 	"github.com/snailzed/agollo/v4/env/config"
 	"github.com/snailzed/agollo/v4/extension"
+	"github.com/snailzed/agollo/v4/protocol/httpclient"
 	"github.com/snailzed/agollo/v4/utils"
 )
 
 var (
-	//for on error retry
-	onErrorRetryInterval = 2 * time.Second //2s
-
 	connectTimeout = 1 * time.Second //1s
 
-	//max retries connect apollo
-	maxRetries = 5
-
-	//defaultMaxConnsPerHost defines the maximum number of concurrent connections
-	defaultMaxConnsPerHost = 512
-	//defaultTimeoutBySecond defines the default timeout for http connections
-	defaultTimeoutBySecond = 1 * time.Second
-	//defaultKeepAliveSecond defines the connection time
-	defaultKeepAliveSecond = 60 * time.Second
-	// once for single http.Transport
-	once sync.Once
-	// defaultTransport http.Transport
-	defaultTransport *http.Transport
+	// defaultClientFactory 未设置 http.Client 工厂时使用
+	defaultClientFactory = &httpclient.DefaultClientFactory{}
+
+	// errOverMaxRetry 连接失败或返回可重试状态码且重试次数用尽
+	errOverMaxRetry = errors.New("over Max Retry Still Error")
 )
 
-func getDefaultTransport(insecureSkipVerify bool) *http.Transport {
-	once.Do(func() {
-		defaultTransport = &http.Transport{
-			Proxy:               http.ProxyFromEnvironment,
-			MaxIdleConns:        defaultMaxConnsPerHost,
-			MaxIdleConnsPerHost: defaultMaxConnsPerHost,
-			DialContext: (&net.Dialer{
-				KeepAlive: defaultKeepAliveSecond,
-				Timeout:   defaultTimeoutBySecond,
-			}).DialContext,
-		}
-		if insecureSkipVerify {
-			defaultTransport.TLSClientConfig = &tls.Config{
-				InsecureSkipVerify: insecureSkipVerify,
-			}
+//StatusError apollo 服务返回了不可重试的状态码
+type StatusError struct {
+	StatusCode int
+}
+
+//Error 错误信息
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("apollo server returns status code %d", e.StatusCode)
+}
+
+//createClient 使用扩展组件中的工厂创建 http.Client
+func createClient(connectionConfig *env.ConnectConfig) (*http.Client, error) {
+	options := &httpclient.Options{
+		Timeout: connectTimeout,
+	}
+	//如有设置自定义超时时间即使用
+	if connectionConfig != nil {
+		if connectionConfig.Timeout != 0 {
+			options.Timeout = connectionConfig.Timeout
 		}
-	})
-	return defaultTransport
+		options.Type = connectionConfig.RequestType
+		options.TLS = connectionConfig.TLS
+	}
+
+	factory := connectionConfig.GetComponents().GetHTTPClientFactory()
+	if factory == nil {
+		factory = defaultClientFactory
+	}
+	client, err := factory.Create(options)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, errors.New("http client factory returns nil client")
+	}
+	return client, nil
 }
 
 //CallBack 请求回调函数
@@ -89,29 +92,32 @@ type CallBack struct {
 
 //Request 建立网络请求
 func Request(requestURL string, headers map[string]string, connectionConfig *env.ConnectConfig, callBack *CallBack) (interface{}, error) {
-	client := &http.Client{}
-	//如有设置自定义超时时间即使用
-	if connectionConfig != nil && connectionConfig.Timeout != 0 {
-		client.Timeout = connectionConfig.Timeout
-	} else {
-		client.Timeout = connectTimeout
-	}
+	return RequestWithContext(context.Background(), requestURL, headers, connectionConfig, callBack)
+}
+
+//RequestWithContext 建立网络请求，ctx 取消时中断请求及重试等待
+func RequestWithContext(ctx context.Context, requestURL string, headers map[string]string, connectionConfig *env.ConnectConfig, callBack *CallBack) (interface{}, error) {
+	components := connectionConfig.GetComponents()
+	logger := components.GetLogger()
 	var err error
 	url, err := url2.Parse(requestURL)
 	if err != nil {
-		log.Errorf("request Apollo Server url:%s, is invalid %s", requestURL, err)
+		logger.Errorf("request Apollo Server url:%s, is invalid %s", requestURL, err)
 		return nil, err
 	}
-	var insecureSkipVerify bool
-	if strings.HasPrefix(url.Scheme, "https") {
-		insecureSkipVerify = true
+	client, err := createClient(connectionConfig)
+	if err != nil {
+		logger.Errorf("create http client for url:%s fail, error:%s", url, err)
+		return nil, err
 	}
-	client.Transport = getDefaultTransport(insecureSkipVerify)
-	retry := 0
-	var retries = maxRetries
-	if connectionConfig != nil && !connectionConfig.IsRetry {
+	policy := connectionConfig.GetRetryPolicy()
+	retries := policy.GetMaxAttempts()
+	//未设置重试策略时与之前一致，IsRetry 为 false 的请求不重试
+	//长轮询由调用方按 backoff 重新发起，单次请求内不重试，避免挂起时间成倍增加
+	if connectionConfig != nil && (policy == nil && !connectionConfig.IsRetry || connectionConfig.RequestType == httpclient.LongPoll) {
 		retries = 1
 	}
+	retry := 0
 	for {
 
 		retry++
@@ -119,14 +125,23 @@ func Request(requestURL string, headers map[string]string, connectionConfig *env
 		if retry > retries {
 			break
 		}
-		req, err := http.NewRequest("GET", requestURL, nil)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		//失败后按退避策略等待，最后一次失败后不再等待
+		if retry > 1 {
+			if e := sleepWithContext(ctx, policy.Backoff(retry-1)); e != nil {
+				return nil, e
+			}
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
 		if req == nil || err != nil {
-			log.Errorf("Generate connect Apollo request Fail,url: %s,Error: %s", requestURL, err)
+			logger.Errorf("Generate connect Apollo request Fail,url: %s,Error: %s", requestURL, err)
 			// if error then sleep
 			return nil, errors.New("generate connect Apollo request fail")
 		}
 		//增加header选项
-		httpAuth := extension.GetHTTPAuth()
+		httpAuth := components.GetHTTPAuth()
 		if httpAuth != nil {
 			headers := httpAuth.HTTPHeaders(requestURL, connectionConfig.AppID, connectionConfig.Secret)
 			if len(headers) > 0 {
@@ -143,9 +158,7 @@ func Request(requestURL string, headers map[string]string, connectionConfig *env
 		}
 		res, err := client.Do(req)
 		if res == nil || err != nil {
-			log.Errorf("Connect Apollo Server Fail,url:%s,Error:%s", requestURL, err)
-			// if error then sleep
-			time.Sleep(onErrorRetryInterval)
+			logger.Errorf("Connect Apollo Server Fail,url:%s,Error:%s", requestURL, err)
 			continue
 		}
 
@@ -155,9 +168,7 @@ func Request(requestURL string, headers map[string]string, connectionConfig *env
 			responseBody, err := ioutil.ReadAll(res.Body)
 			_ = res.Body.Close()
 			if err != nil {
-				log.Errorf("Connect Apollo Server Fail,url : %s ,Error: %s ", requestURL, err)
-				// if error then sleep
-				time.Sleep(onErrorRetryInterval)
+				logger.Errorf("Connect Apollo Server Fail,url : %s ,Error: %s ", requestURL, err)
 				continue
 			}
 
@@ -167,58 +178,114 @@ func Request(requestURL string, headers map[string]string, connectionConfig *env
 			return nil, nil
 		case http.StatusNotModified:
 			_ = res.Body.Close()
-			log.Debug("Config Not Modified")
+			logger.Debug("Config Not Modified")
 			if callBack != nil && callBack.NotModifyCallBack != nil {
 				return nil, callBack.NotModifyCallBack()
 			}
 			return nil, nil
 		default:
 			_ = res.Body.Close()
-			log.Errorf("Connect Apollo Server Fail,url: %s, StatusCode: %d", requestURL, res.StatusCode)
-			// if error then sleep
-			time.Sleep(onErrorRetryInterval)
+			logger.Errorf("Connect Apollo Server Fail,url: %s, StatusCode: %d", requestURL, res.StatusCode)
+			if !policy.IsRetryableStatus(res.StatusCode) {
+				return nil, &StatusError{StatusCode: res.StatusCode}
+			}
 			continue
 		}
 	}
 	if retry > retries {
-		err = errors.New("over Max Retry Still Error")
+		err = errOverMaxRetry
 	}
 	return nil, err
 }
 
+// sleepWithContext 等待 d，ctx 取消时立即返回 ctx.Err()
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 //RequestRecovery 可以恢复的请求
 func RequestRecovery(appConfig config.AppConfig,
+	connectConfig *env.ConnectConfig,
+	callBack *CallBack) (interface{}, error) {
+	return RequestRecoveryWithContext(context.Background(), appConfig, connectConfig, callBack)
+}
+
+//RequestRecoveryWithContext 可以恢复的请求，ctx 取消时不再切换节点重试
+//每个节点最多请求一次，连接失败、可重试状态码及 5xx 计入节点的熔断器
+func RequestRecoveryWithContext(ctx context.Context, appConfig config.AppConfig,
 	connectConfig *env.ConnectConfig,
 	callBack *CallBack) (interface{}, error) {
 	format := "%s%s"
 	var err error
 	var response interface{}
+	components := connectConfig.GetComponents()
+	serverManager := components.GetServerManager()
+	tried := make(map[string]bool)
 
 	for {
-		host := loadBalance(appConfig)
+		host := loadBalance(appConfig, components, tried)
 		if host == "" {
+			if err == nil {
+				err = errors.New("no available config server")
+			}
 			return nil, err
 		}
 
+		// 半开节点已有探测请求时换一个节点
+		if !serverManager.TryAcquire(appConfig.GetHost(), host, appConfig.CircuitBreaker) {
+			tried[host] = true
+			continue
+		}
+
 		requestURL := fmt.Sprintf(format, host, connectConfig.URI)
-		response, err = Request(requestURL, appConfig.GetHeader(), connectConfig, callBack)
+		response, err = RequestWithContext(ctx, requestURL, appConfig.GetHeader(), connectConfig, callBack)
+		// 主动取消不代表节点失效，回调及 4xx 等错误与节点无关，按成功计入熔断器
+		if ctx.Err() == nil {
+			if err != nil && isNodeFailure(err, connectConfig.GetRetryPolicy()) {
+				serverManager.MarkFailure(appConfig.GetHost(), host, appConfig.CircuitBreaker)
+			} else {
+				serverManager.MarkSuccess(appConfig.GetHost(), host, appConfig.CircuitBreaker)
+			}
+		}
 		if err == nil {
 			return response, nil
 		}
-
-		if host == appConfig.GetHost() {
+		if ctx.Err() != nil || host == appConfig.GetHost() {
 			return response, err
 		}
+		tried[host] = true
+	}
+}
 
-		server.SetDownNode(host, appConfig.GetHost())
+//isNodeFailure 错误是否说明节点不可用：连接失败、可重试状态码及 5xx
+func isNodeFailure(err error, policy *config.RetryPolicy) bool {
+	if err == errOverMaxRetry {
+		return true
+	}
+	if statusErr, ok := err.(*StatusError); ok {
+		return statusErr.StatusCode >= http.StatusInternalServerError || policy.IsRetryableStatus(statusErr.StatusCode)
 	}
+	return false
 }
 
-func loadBalance(appConfig config.AppConfig) string {
-	if !server.IsConnectDirectly(appConfig.GetHost()) {
+//loadBalance 从熔断未打开且本次未请求过的节点中选择
+func loadBalance(appConfig config.AppConfig, components *extension.Components, tried map[string]bool) string {
+	serverManager := components.GetServerManager()
+	if !serverManager.IsConnectDirectly(appConfig.GetHost()) {
 		return appConfig.GetHost()
 	}
-	serverInfo := extension.GetLoadBalance().Load(server.GetServers(appConfig.GetHost()))
+	servers := serverManager.GetAvailableServers(appConfig.GetHost())
+	for host := range tried {
+		delete(servers, host)
+	}
+	serverInfo := components.GetLoadBalance().Load(servers)
 	if serverInfo == nil {
 		return utils.Empty
 	}
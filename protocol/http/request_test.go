@@ -18,7 +18,11 @@
 package http
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	gohttp "net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 	"time"
@@ -27,6 +31,7 @@ import (
 	"github.com/snailzed/agollo/v4/component/log"
 	"github.com/snailzed/agollo/v4/env/server"
 	"github.com/snailzed/agollo/v4/extension"
+	"github.com/snailzed/agollo/v4/protocol/httpclient"
 
 	"github.com/snailzed/agollo/v4/env"
 	"github.com/snailzed/agollo/v4/env/config"
@@ -74,6 +79,7 @@ func TestHttpsRequestRecovery(t *testing.T) {
 	o, err := RequestRecovery(*appConfig, &env.ConnectConfig{
 		URI:     urlSuffix,
 		IsRetry: true,
+		TLS:     &config.TLSConfig{CAPEM: certificatePEM(server.Certificate())},
 	}, &CallBack{
 		SuccessCallBack: nil,
 	})
@@ -189,3 +195,220 @@ func SyncServerIPListSuccessCallBack(responseBody []byte, callback CallBack) (o
 	server.SetServers(c.GetHost(), m)
 	return
 }
+
+func TestRequestWithContextCancel(t *testing.T) {
+	server := runLongTimeResponse()
+	appConfig := getTestAppConfig()
+	appConfig.IP = server.URL
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	startTime := time.Now()
+	o, err := RequestWithContext(ctx, appConfig.GetHost(), nil, &env.ConnectConfig{
+		Timeout: 11 * time.Second,
+		IsRetry: true,
+	}, &CallBack{
+		SuccessCallBack: nil,
+	})
+
+	Assert(t, o, NilVal())
+	Assert(t, err, Equal(context.DeadlineExceeded))
+	Assert(t, time.Since(startTime) < 5*time.Second, Equal(true))
+}
+
+type testRoundTripper struct{}
+
+func (r *testRoundTripper) RoundTrip(req *gohttp.Request) (*gohttp.Response, error) {
+	req.Header.Set("X-Test-Transport", "true")
+	return gohttp.DefaultTransport.RoundTrip(req)
+}
+
+type testClientFactory struct {
+	options []httpclient.Options
+}
+
+func (f *testClientFactory) Create(options *httpclient.Options) (*gohttp.Client, error) {
+	f.options = append(f.options, *options)
+	return &gohttp.Client{
+		Transport: &testRoundTripper{},
+		Timeout:   options.Timeout,
+	}, nil
+}
+
+func TestRequestWithHTTPClientFactory(t *testing.T) {
+	header := ""
+	server := httptest.NewServer(gohttp.HandlerFunc(func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		header = r.Header.Get("X-Test-Transport")
+		w.WriteHeader(gohttp.StatusOK)
+	}))
+	defer server.Close()
+
+	factory := &testClientFactory{}
+	_, err := Request(server.URL, nil, &env.ConnectConfig{
+		Timeout:     90 * time.Second,
+		RequestType: httpclient.LongPoll,
+		Components:  &extension.Components{HTTPClientFactory: factory},
+	}, nil)
+	Assert(t, err, NilVal())
+	Assert(t, header, Equal("true"))
+	Assert(t, len(factory.options), Equal(1))
+	Assert(t, factory.options[0].Type, Equal(httpclient.LongPoll))
+	Assert(t, factory.options[0].Timeout, Equal(90*time.Second))
+
+	_, err = Request(server.URL, nil, &env.ConnectConfig{
+		Components: &extension.Components{HTTPClientFactory: factory},
+	}, nil)
+	Assert(t, err, NilVal())
+	Assert(t, len(factory.options), Equal(2))
+	Assert(t, factory.options[1].Type, Equal(httpclient.Normal))
+	Assert(t, factory.options[1].Timeout, Equal(connectTimeout))
+}
+
+func TestRequestRetryPolicy(t *testing.T) {
+	count := 0
+	statusCode := gohttp.StatusServiceUnavailable
+	server := httptest.NewServer(gohttp.HandlerFunc(func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		count++
+		if count < 3 {
+			w.WriteHeader(statusCode)
+			return
+		}
+		w.WriteHeader(gohttp.StatusOK)
+	}))
+	defer server.Close()
+
+	policy := &config.RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   1,
+		MaxDelay:    5,
+	}
+	//可重试的状态码重试后成功
+	_, err := Request(server.URL, nil, &env.ConnectConfig{RetryPolicy: policy}, nil)
+	Assert(t, err, NilVal())
+	Assert(t, count, Equal(3))
+
+	//超过最大尝试次数
+	count = 0
+	policy.MaxAttempts = 2
+	_, err = Request(server.URL, nil, &env.ConnectConfig{RetryPolicy: policy}, nil)
+	Assert(t, err, NotNilVal())
+	Assert(t, count, Equal(2))
+
+	//不可重试的状态码直接返回
+	count = 0
+	statusCode = gohttp.StatusNotFound
+	_, err = Request(server.URL, nil, &env.ConnectConfig{RetryPolicy: policy}, nil)
+	Assert(t, err, NotNilVal())
+	Assert(t, count, Equal(1))
+
+	//未设置重试策略时不重试
+	count = 0
+	statusCode = gohttp.StatusServiceUnavailable
+	_, err = Request(server.URL, nil, &env.ConnectConfig{}, nil)
+	Assert(t, err, NotNilVal())
+	Assert(t, count, Equal(1))
+
+	//长轮询请求不重试
+	count = 0
+	_, err = Request(server.URL, nil, &env.ConnectConfig{RetryPolicy: policy, IsRetry: true, RequestType: httpclient.LongPoll}, nil)
+	Assert(t, err, NotNilVal())
+	Assert(t, count, Equal(1))
+}
+
+func TestRequestRecoveryCircuitBreaker(t *testing.T) {
+	badCount := 0
+	bad := httptest.NewServer(gohttp.HandlerFunc(func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		badCount++
+		w.WriteHeader(gohttp.StatusServiceUnavailable)
+	}))
+	defer bad.Close()
+	good := httptest.NewServer(gohttp.HandlerFunc(func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		w.WriteHeader(gohttp.StatusOK)
+	}))
+	defer good.Close()
+
+	appConfig := getTestAppConfig()
+	appConfig.CircuitBreaker = &config.CircuitBreakerConfig{
+		MinRequests:          1,
+		FailureRateThreshold: 1,
+	}
+	badHost := bad.URL + "/"
+	goodHost := good.URL + "/"
+	manager := server.CreateManager()
+	manager.SetServers(appConfig.GetHost(), map[string]*config.ServerInfo{
+		badHost:  {HomepageURL: badHost},
+		goodHost: {HomepageURL: goodHost},
+	})
+	manager.SetNextTryConnTime(appConfig.GetHost(), 30)
+	connectConfig := &env.ConnectConfig{
+		Components: &extension.Components{ServerManager: manager},
+	}
+
+	//失败的节点打开熔断后不再请求
+	for i := 0; i < 5; i++ {
+		_, err := RequestRecovery(*appConfig, connectConfig, nil)
+		Assert(t, err, NilVal())
+	}
+	Assert(t, badCount <= 1, Equal(true))
+
+	healths := manager.GetNodeHealth(appConfig.GetHost())
+	Assert(t, len(healths), Equal(2))
+	for _, health := range healths {
+		if health.HomepageURL == goodHost {
+			Assert(t, health.State, Equal(server.StateClosed))
+		} else if badCount == 1 {
+			Assert(t, health.State, Equal(server.StateOpen))
+		}
+	}
+
+	//没有可用节点
+	manager.SetDownNodeWithConfig(appConfig.GetHost(), goodHost, appConfig.CircuitBreaker)
+	manager.SetDownNodeWithConfig(appConfig.GetHost(), badHost, appConfig.CircuitBreaker)
+	_, err := RequestRecovery(*appConfig, connectConfig, nil)
+	Assert(t, err, NotNilVal())
+}
+
+func TestRequestRecoveryNotNodeFailure(t *testing.T) {
+	statusCode := gohttp.StatusNotFound
+	node := httptest.NewServer(gohttp.HandlerFunc(func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		w.WriteHeader(statusCode)
+	}))
+	defer node.Close()
+
+	appConfig := getTestAppConfig()
+	appConfig.CircuitBreaker = &config.CircuitBreakerConfig{
+		MinRequests:          1,
+		FailureRateThreshold: 0.3,
+	}
+	host := node.URL + "/"
+	manager := server.CreateManager()
+	manager.SetServers(appConfig.GetHost(), map[string]*config.ServerInfo{
+		host: {HomepageURL: host},
+	})
+	manager.SetNextTryConnTime(appConfig.GetHost(), 30)
+	connectConfig := &env.ConnectConfig{
+		Components: &extension.Components{ServerManager: manager},
+	}
+
+	//4xx 不计入节点失败
+	_, err := RequestRecovery(*appConfig, connectConfig, nil)
+	Assert(t, err, NotNilVal())
+	Assert(t, manager.GetNodeHealth(appConfig.GetHost())[0].State, Equal(server.StateClosed))
+
+	//回调错误不计入节点失败
+	statusCode = gohttp.StatusOK
+	_, err = RequestRecovery(*appConfig, connectConfig, &CallBack{
+		SuccessCallBack: func(b []byte, callBack CallBack) (interface{}, error) {
+			return nil, errors.New("invalid content")
+		},
+	})
+	Assert(t, err, NotNilVal())
+	Assert(t, manager.GetNodeHealth(appConfig.GetHost())[0].State, Equal(server.StateClosed))
+
+	//5xx 计入节点失败
+	statusCode = gohttp.StatusInternalServerError
+	_, err = RequestRecovery(*appConfig, connectConfig, nil)
+	Assert(t, err, NotNilVal())
+	Assert(t, manager.GetNodeHealth(appConfig.GetHost())[0].State, Equal(server.StateOpen))
+}
@@ -0,0 +1,117 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/snailzed/agollo/v4/env"
+	"github.com/snailzed/agollo/v4/env/config"
+	. "github.com/tevid/gohamcrest"
+)
+
+func certificatePEM(cert *x509.Certificate) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+}
+
+// createClientCertificate 生成自签名的客户端证书，返回证书及私钥的 PEM 内容
+func createClientCertificate(t *testing.T) (string, string, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Assert(t, err, NilVal())
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "agollo-client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Assert(t, err, NilVal())
+	cert, err := x509.ParseCertificate(der)
+	Assert(t, err, NilVal())
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Assert(t, err, NilVal())
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certificatePEM(cert), string(keyPEM), cert
+}
+
+func requestTLS(url string, tlsConfig *config.TLSConfig) error {
+	_, err := Request(url, nil, &env.ConnectConfig{TLS: tlsConfig}, nil)
+	return err
+}
+
+func TestRequestTLSVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	//默认校验服务端证书
+	Assert(t, requestTLS(server.URL, nil), NotNilVal())
+	//显式开启才跳过校验
+	Assert(t, requestTLS(server.URL, &config.TLSConfig{InsecureSkipVerify: true}), NilVal())
+	Assert(t, requestTLS(server.URL, &config.TLSConfig{CAPEM: certificatePEM(server.Certificate())}), NilVal())
+	//httptest 证书的域名为 example.com
+	Assert(t, requestTLS(server.URL, &config.TLSConfig{
+		CAPEM:      certificatePEM(server.Certificate()),
+		ServerName: "example.com",
+	}), NilVal())
+	Assert(t, requestTLS(server.URL, &config.TLSConfig{
+		CAPEM:      certificatePEM(server.Certificate()),
+		ServerName: "other.com",
+	}), NotNilVal())
+	Assert(t, requestTLS(server.URL, &config.TLSConfig{MinVersion: "2.0"}), NotNilVal())
+}
+
+func TestRequestMutualTLS(t *testing.T) {
+	certPEM, keyPEM, cert := createClientCertificate(t)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+	}
+	server.StartTLS()
+	defer server.Close()
+	caPEM := certificatePEM(server.Certificate())
+
+	Assert(t, requestTLS(server.URL, &config.TLSConfig{CAPEM: caPEM}), NotNilVal())
+	Assert(t, requestTLS(server.URL, &config.TLSConfig{
+		CAPEM:   caPEM,
+		CertPEM: certPEM,
+		KeyPEM:  keyPEM,
+	}), NilVal())
+}
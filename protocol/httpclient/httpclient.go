@@ -0,0 +1,179 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package httpclient
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/snailzed/agollo/v4/env/config"
+)
+
+const (
+	//defaultMaxConnsPerHost defines the maximum number of concurrent connections
+	defaultMaxConnsPerHost = 512
+	//defaultTimeoutBySecond defines the default timeout for http connections
+	defaultTimeoutBySecond = 1 * time.Second
+	//defaultKeepAliveSecond defines the connection time
+	defaultKeepAliveSecond = 60 * time.Second
+	//defaultCertCheckInterval 默认检查证书文件是否修改的间隔
+	defaultCertCheckInterval = time.Minute
+)
+
+//RequestType 请求类型
+type RequestType int
+
+const (
+	//Normal 拉取配置、同步服务列表等普通请求
+	Normal RequestType = iota
+	//LongPoll 长轮询通知请求，服务端最多挂起 60s，超时时间应大于普通请求
+	LongPoll
+)
+
+//Options 创建 http.Client 的参数
+type Options struct {
+	Type RequestType
+	//Timeout 请求超时时间
+	Timeout time.Duration
+	//TLS AppConfig 中的 TLS 配置，为空时使用系统证书校验服务端
+	TLS *config.TLSConfig
+}
+
+//ClientFactory 创建访问 apollo 使用的 *http.Client，用于替换 http.RoundTripper（代理、链路追踪、连接数限制等）
+type ClientFactory interface {
+	Create(options *Options) (*http.Client, error)
+}
+
+//IdleConnectionsCloser ClientFactory 的可选接口，客户端 Close 时调用以释放空闲连接
+type IdleConnectionsCloser interface {
+	CloseIdleConnections()
+}
+
+//DefaultClientFactory 默认的 http.Client 工厂，相同请求类型及同一个 TLS 配置对象的请求共用 http.Transport
+//长轮询会长时间占用连接，与普通请求使用不同的 Transport；定期检查证书文件，修改后重新创建 Transport
+type DefaultClientFactory struct {
+	//CertCheckInterval 检查证书文件是否修改的间隔，为0时使用 1 分钟
+	CertCheckInterval time.Duration
+
+	lock       sync.Mutex
+	transports map[transportKey]*transportEntry
+}
+
+type transportKey struct {
+	typ RequestType
+	tls *config.TLSConfig
+}
+
+type transportEntry struct {
+	transport *http.Transport
+	//modTimes 创建 Transport 时证书文件的修改时间
+	modTimes string
+	//checkedAt 上次检查证书文件的时间
+	checkedAt time.Time
+}
+
+//Create 创建 http.Client
+func (f *DefaultClientFactory) Create(options *Options) (*http.Client, error) {
+	transport, err := f.getTransport(options.Type, options.TLS)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   options.Timeout,
+	}, nil
+}
+
+//CloseIdleConnections 关闭所有 Transport 的空闲连接并清空缓存
+func (f *DefaultClientFactory) CloseIdleConnections() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, entry := range f.transports {
+		entry.transport.CloseIdleConnections()
+	}
+	f.transports = nil
+}
+
+func (f *DefaultClientFactory) certCheckInterval() time.Duration {
+	if f.CertCheckInterval > 0 {
+		return f.CertCheckInterval
+	}
+	return defaultCertCheckInterval
+}
+
+func (f *DefaultClientFactory) getTransport(typ RequestType, tlsConfig *config.TLSConfig) (*http.Transport, error) {
+	key := transportKey{typ: typ, tls: tlsConfig}
+	now := time.Now()
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	entry := f.transports[key]
+	if entry != nil && (tlsConfig == nil || now.Sub(entry.checkedAt) < f.certCheckInterval()) {
+		return entry.transport, nil
+	}
+	modTimes := getModTimes(tlsConfig)
+	if entry != nil && entry.modTimes == modTimes {
+		entry.checkedAt = now
+		return entry.transport, nil
+	}
+
+	clientConfig, err := tlsConfig.Build()
+	if err != nil {
+		return nil, err
+	}
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		MaxIdleConns:        defaultMaxConnsPerHost,
+		MaxIdleConnsPerHost: defaultMaxConnsPerHost,
+		DialContext: (&net.Dialer{
+			KeepAlive: defaultKeepAliveSecond,
+			Timeout:   defaultTimeoutBySecond,
+		}).DialContext,
+		TLSClientConfig: clientConfig,
+	}
+	//证书文件已修改，旧 Transport 上的请求结束后连接随之释放
+	if entry != nil {
+		entry.transport.CloseIdleConnections()
+	}
+	if f.transports == nil {
+		f.transports = make(map[transportKey]*transportEntry)
+	}
+	f.transports[key] = &transportEntry{transport: transport, modTimes: modTimes, checkedAt: now}
+	return transport, nil
+}
+
+//getModTimes 证书文件的修改时间及大小，文件不存在时为空
+func getModTimes(tlsConfig *config.TLSConfig) string {
+	if tlsConfig == nil {
+		return ""
+	}
+	modTimes := ""
+	for _, fileName := range []string{tlsConfig.CAFile, tlsConfig.CertFile, tlsConfig.KeyFile} {
+		if fileName == "" {
+			continue
+		}
+		if info, err := os.Stat(fileName); err == nil {
+			modTimes += fmt.Sprintf("%s:%d:%d;", fileName, info.ModTime().UnixNano(), info.Size())
+		}
+	}
+	return modTimes
+}
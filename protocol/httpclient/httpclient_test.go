@@ -0,0 +1,106 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package httpclient
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/snailzed/agollo/v4/env/config"
+	. "github.com/tevid/gohamcrest"
+)
+
+func TestDefaultClientFactory(t *testing.T) {
+	f := &DefaultClientFactory{}
+	tlsConfig := &config.TLSConfig{ServerName: "a"}
+	a, err := f.Create(&Options{Timeout: time.Second, TLS: tlsConfig})
+	Assert(t, err, NilVal())
+	Assert(t, a.Timeout, Equal(time.Second))
+	b, err := f.Create(&Options{Timeout: time.Minute, TLS: tlsConfig})
+	Assert(t, err, NilVal())
+	Assert(t, b.Timeout, Equal(time.Minute))
+	//同一个 TLS 配置共用 Transport
+	Assert(t, a.Transport == b.Transport, Equal(true))
+
+	//长轮询使用单独的 Transport
+	poll, err := f.Create(&Options{Type: LongPoll, TLS: tlsConfig})
+	Assert(t, err, NilVal())
+	Assert(t, a.Transport == poll.Transport, Equal(false))
+
+	c, err := f.Create(&Options{TLS: &config.TLSConfig{ServerName: "c"}})
+	Assert(t, err, NilVal())
+	Assert(t, a.Transport == c.Transport, Equal(false))
+	Assert(t, c.Transport.(*http.Transport).TLSClientConfig.ServerName, Equal("c"))
+
+	d, err := f.Create(&Options{})
+	Assert(t, err, NilVal())
+	Assert(t, d.Transport.(*http.Transport).TLSClientConfig == nil, Equal(true))
+	e, err := f.Create(&Options{})
+	Assert(t, err, NilVal())
+	Assert(t, d.Transport == e.Transport, Equal(true))
+
+	_, err = f.Create(&Options{TLS: &config.TLSConfig{MinVersion: "0.9"}})
+	Assert(t, err, NotNilVal())
+}
+
+func TestDefaultClientFactoryReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agollo-httpclient")
+	Assert(t, err, NilVal())
+	defer os.RemoveAll(dir)
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	caFile := filepath.Join(dir, "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	Assert(t, ioutil.WriteFile(caFile, ca, 0644), NilVal())
+
+	f := &DefaultClientFactory{CertCheckInterval: 50 * time.Millisecond}
+	tlsConfig := &config.TLSConfig{CAFile: caFile}
+	a, err := f.Create(&Options{TLS: tlsConfig})
+	Assert(t, err, NilVal())
+
+	//检查间隔内不重新检查证书文件
+	modTime := time.Now().Add(time.Minute)
+	Assert(t, os.Chtimes(caFile, modTime, modTime), NilVal())
+	b, err := f.Create(&Options{TLS: tlsConfig})
+	Assert(t, err, NilVal())
+	Assert(t, a.Transport == b.Transport, Equal(true))
+
+	//超过检查间隔后发现证书文件修改，重新创建 Transport
+	time.Sleep(60 * time.Millisecond)
+	c, err := f.Create(&Options{TLS: tlsConfig})
+	Assert(t, err, NilVal())
+	Assert(t, a.Transport == c.Transport, Equal(false))
+
+	//证书文件未修改时继续使用原来的 Transport
+	time.Sleep(60 * time.Millisecond)
+	e, err := f.Create(&Options{TLS: tlsConfig})
+	Assert(t, err, NilVal())
+	Assert(t, c.Transport == e.Transport, Equal(true))
+
+	//关闭后清空缓存
+	f.CloseIdleConnections()
+	d, err := f.Create(&Options{TLS: tlsConfig})
+	Assert(t, err, NilVal())
+	Assert(t, c.Transport == d.Transport, Equal(false))
+}
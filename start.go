@@ -24,6 +24,7 @@ import (
 	"github.com/snailzed/agollo/v4/env/file"
 	"github.com/snailzed/agollo/v4/extension"
 	"github.com/snailzed/agollo/v4/protocol/auth"
+	"github.com/snailzed/agollo/v4/protocol/httpclient"
 )
 
 //SetSignature 设置自定义 http 授权控件
@@ -40,6 +41,13 @@ func SetBackupFileHandler(file file.FileHandler) {
 	}
 }
 
+//SetHTTPClientFactory 设置自定义 http.Client 工厂，用于替换代理、链路追踪等 http.RoundTripper
+func SetHTTPClientFactory(factory httpclient.ClientFactory) {
+	if factory != nil {
+		extension.SetHTTPClientFactory(factory)
+	}
+}
+
 //SetLoadBalance 设置自定义负载均衡组件
 func SetLoadBalance(loadBalance cluster.LoadBalance) {
 	if loadBalance != nil {
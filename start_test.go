@@ -18,18 +18,24 @@
 package agollo
 
 import (
+	"context"
 	"encoding/json"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/snailzed/agollo/v4/agcache"
 	"github.com/snailzed/agollo/v4/agcache/memory"
 	"github.com/snailzed/agollo/v4/component/log"
 	"github.com/snailzed/agollo/v4/env"
 	"github.com/snailzed/agollo/v4/env/config"
 	jsonFile "github.com/snailzed/agollo/v4/env/config/json"
+	"github.com/snailzed/agollo/v4/env/file"
+	jsonFileHandler "github.com/snailzed/agollo/v4/env/file/json"
 	"github.com/snailzed/agollo/v4/extension"
+	"github.com/snailzed/agollo/v4/protocol/httpclient"
 	. "github.com/tevid/gohamcrest"
 )
 
@@ -275,3 +281,124 @@ func TestStartWithConfigMustReadFromRemote(t *testing.T) {
 	Assert(t, handler, NotNilVal())
 
 }
+
+func TestClose(t *testing.T) {
+	c := appConfig
+	handlerMap := make(map[string]func(http.ResponseWriter, *http.Request), 1)
+	handlerMap["application"] = onlyNormalConfigResponse
+	server := runMockConfigFilesServer(handlerMap, nil, c)
+	c.IP = server.URL
+
+	client, err := StartWithConfig(func() (*config.AppConfig, error) {
+		return c, nil
+	})
+	Assert(t, err, NilVal())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = client.Close(ctx)
+	Assert(t, err, NilVal())
+
+	//close again
+	err = client.Close(ctx)
+	Assert(t, err, NilVal())
+}
+
+type testCacheFactory struct {
+	count int
+}
+
+func (f *testCacheFactory) Create() agcache.CacheInterface {
+	f.count++
+	return &memory.DefaultCache{}
+}
+
+func TestNewMultiClient(t *testing.T) {
+	handlerMap := make(map[string]func(http.ResponseWriter, *http.Request), 1)
+	handlerMap["application"] = onlyNormalConfigResponse
+	c1 := &config.AppConfig{
+		AppID:         "test1",
+		Cluster:       "dev",
+		NamespaceName: "application",
+	}
+	server1 := runMockConfigFilesServer(handlerMap, nil, c1)
+	c1.IP = server1.URL
+
+	handlerMap2 := make(map[string]func(http.ResponseWriter, *http.Request), 1)
+	handlerMap2["application"] = onlyNormalSecondConfigResponse
+	c2 := &config.AppConfig{
+		AppID:         "test2",
+		Cluster:       "dev",
+		NamespaceName: "application",
+	}
+	server2 := runMockConfigFilesServer(handlerMap2, nil, c2)
+	c2.IP = server2.URL
+
+	cacheFactory := &testCacheFactory{}
+	logger := &log.DefaultLogger{}
+	client1, err := New(c1, WithCache(cacheFactory), WithLogger(logger))
+	Assert(t, err, NilVal())
+	client2, err := New(c2)
+	Assert(t, err, NilVal())
+
+	Assert(t, client1.GetValue("key1"), Equal("value1"))
+	Assert(t, client1.GetValue("key1-1"), Equal(""))
+	Assert(t, client2.GetValue("key1-1"), Equal("value1-1"))
+	Assert(t, client2.GetValue("key1"), Equal(""))
+	Assert(t, cacheFactory.count, Equal(1))
+
+	internal1 := client1.(*internalClient)
+	internal2 := client2.(*internalClient)
+	Assert(t, internal1.components.GetLogger(), Equal(log.LoggerInterface(logger)))
+	Assert(t, internal1.components.GetServerManager() != internal2.components.GetServerManager(), Equal(true))
+	//未设置 http.Client 工厂时每个客户端使用独立的连接池
+	_, ok := internal1.components.GetHTTPClientFactory().(*httpclient.DefaultClientFactory)
+	Assert(t, ok, Equal(true))
+	Assert(t, internal1.components.GetHTTPClientFactory() != internal2.components.GetHTTPClientFactory(), Equal(true))
+
+	Assert(t, client1.Close(context.Background()), NilVal())
+	Assert(t, client2.Close(context.Background()), NilVal())
+}
+
+func TestWithRawBackup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agollo-raw-option")
+	Assert(t, err, NilVal())
+	defer os.RemoveAll(dir)
+
+	apolloConfig := &config.ApolloConfig{}
+	apolloConfig.AppID = "option"
+	apolloConfig.NamespaceName = "application.yaml"
+	apolloConfig.ReleaseKey = "release"
+	apolloConfig.Content = "a: b\n"
+
+	//包装 WithBackupFileHandler 设置的 json 备份文件读写，与设置顺序无关
+	handler := &jsonFileHandler.FileHandler{}
+	for _, options := range [][]Option{
+		{WithBackupFileHandler(handler), WithRawBackup()},
+		{WithRawBackup(), WithBackupFileHandler(handler)},
+	} {
+		o := &clientOptions{components: &extension.Components{}}
+		for _, option := range options {
+			option(o)
+		}
+		Assert(t, o.initRawBackup(), NilVal())
+		Assert(t, o.components.FileHandler != file.FileHandler(handler), Equal(true))
+
+		Assert(t, o.components.FileHandler.WriteConfigFile(apolloConfig, dir), NilVal())
+		_, err = os.Stat(jsonFileHandler.GetRawFile(dir, "option", "application.yaml"))
+		Assert(t, err, NilVal())
+		Assert(t, os.Remove(jsonFileHandler.GetRawFile(dir, "option", "application.yaml")), NilVal())
+	}
+
+	//无法包装其它类型的备份文件读写
+	o := &clientOptions{components: &extension.Components{}}
+	WithBackupFileHandler(jsonFileHandler.GetRawFileHandler())(o)
+	WithRawBackup()(o)
+	Assert(t, o.initRawBackup(), NotNilVal())
+}
+
+func TestNewNilConfig(t *testing.T) {
+	client, err := New(nil)
+	Assert(t, client, Equal(nil))
+	Assert(t, err, NotNilVal())
+}
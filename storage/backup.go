@@ -0,0 +1,99 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"sync"
+
+	"github.com/snailzed/agollo/v4/env/config"
+	"github.com/snailzed/agollo/v4/env/file"
+)
+
+//backupTask 待写入的备份
+type backupTask struct {
+	apolloConfig *config.ApolloConfig
+	configPath   string
+}
+
+//backupWriter 按 namespace 异步写备份文件
+//同一 namespace 同时只有一个写操作，写入期间的多次更新中只有最新的一次写入当前备份文件，
+//其余更新在 FileHandler 支持时只写入历史版本，保证每个版本都有历史记录且最终写入的是最新配置
+type backupWriter struct {
+	lock    sync.Mutex
+	pending map[string][]*backupTask
+	running map[string]bool
+	wg      sync.WaitGroup
+}
+
+func (c *Cache) writeBackup(apolloConfig *config.ApolloConfig, configPath string) {
+	w := &c.backup
+	namespace := apolloConfig.NamespaceName
+
+	w.lock.Lock()
+	if w.pending == nil {
+		w.pending = make(map[string][]*backupTask)
+		w.running = make(map[string]bool)
+	}
+	w.pending[namespace] = append(w.pending[namespace], &backupTask{apolloConfig: apolloConfig, configPath: configPath})
+	if w.running[namespace] {
+		w.lock.Unlock()
+		return
+	}
+	w.running[namespace] = true
+	w.wg.Add(1)
+	w.lock.Unlock()
+
+	go func() {
+		defer w.wg.Done()
+		for {
+			w.lock.Lock()
+			tasks := w.pending[namespace]
+			if len(tasks) == 0 {
+				delete(w.running, namespace)
+				w.lock.Unlock()
+				return
+			}
+			delete(w.pending, namespace)
+			w.lock.Unlock()
+
+			c.flushBackup(namespace, tasks)
+		}
+	}()
+}
+
+//flushBackup 按顺序写入 namespace 积压的备份，只有最后一次写入当前备份文件
+func (c *Cache) flushBackup(namespace string, tasks []*backupTask) {
+	fileHandler := c.components.GetFileHandler()
+	historyWriter, ok := fileHandler.(file.HistoryWriter)
+	for i, task := range tasks {
+		var err error
+		if ok && i < len(tasks)-1 {
+			err = historyWriter.WriteHistory(task.apolloConfig, task.configPath)
+		} else {
+			err = fileHandler.WriteConfigFile(task.apolloConfig, task.configPath)
+		}
+		if err != nil {
+			c.logger().Errorf("write backup file fail, namespace:%s, releaseKey:%s, error:%s", namespace, task.apolloConfig.ReleaseKey, err)
+		}
+	}
+}
+
+//WaitBackup 等待已提交的备份文件及历史版本写入完成
+func (c *Cache) WaitBackup() {
+	c.backup.wg.Wait()
+}
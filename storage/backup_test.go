@@ -0,0 +1,113 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/snailzed/agollo/v4/env/config"
+	"github.com/snailzed/agollo/v4/extension"
+	. "github.com/tevid/gohamcrest"
+)
+
+//slowFileHandler 记录写入的 releaseKey，第一次写入时阻塞直到 release 关闭
+type slowFileHandler struct {
+	lock    sync.Mutex
+	writes  []string
+	release chan struct{}
+}
+
+func (h *slowFileHandler) WriteConfigFile(c *config.ApolloConfig, configPath string) error {
+	return h.write(c.ReleaseKey)
+}
+
+func (h *slowFileHandler) write(name string) error {
+	h.lock.Lock()
+	first := len(h.writes) == 0
+	h.writes = append(h.writes, name)
+	h.lock.Unlock()
+	if first {
+		<-h.release
+	}
+	return nil
+}
+
+//historyFileHandler 支持单独写入历史版本
+type historyFileHandler struct {
+	slowFileHandler
+}
+
+func (h *historyFileHandler) WriteHistory(c *config.ApolloConfig, configDir string) error {
+	return h.write("history-" + c.ReleaseKey)
+}
+
+func (h *slowFileHandler) GetConfigFile(configDir string, appID string, namespace string) string {
+	return ""
+}
+
+func (h *slowFileHandler) LoadConfigFile(configDir string, appID string, namespace string) (*config.ApolloConfig, error) {
+	return nil, nil
+}
+
+func writeBackupWhileBlocked(c *Cache, handler *slowFileHandler) {
+	apolloConfig := func(releaseKey string) *config.ApolloConfig {
+		a := &config.ApolloConfig{}
+		a.NamespaceName = "backup"
+		a.ReleaseKey = releaseKey
+		return a
+	}
+	c.writeBackup(apolloConfig("1"), "")
+	// 等待第一次写入开始后再提交后续的更新
+	for {
+		handler.lock.Lock()
+		started := len(handler.writes) == 1
+		handler.lock.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	c.writeBackup(apolloConfig("2"), "")
+	c.writeBackup(apolloConfig("3"), "")
+	close(handler.release)
+	c.WaitBackup()
+}
+
+func TestWriteBackupHistory(t *testing.T) {
+	handler := &historyFileHandler{slowFileHandler{release: make(chan struct{})}}
+	c := CreateNamespaceConfigWithComponents("backup", &extension.Components{FileHandler: handler})
+	writeBackupWhileBlocked(c, &handler.slowFileHandler)
+
+	// 积压的版本只写入历史，最新的版本写入当前备份文件
+	handler.lock.Lock()
+	defer handler.lock.Unlock()
+	Assert(t, handler.writes, Equal([]string{"1", "history-2", "3"}))
+}
+
+func TestWriteBackupWithoutHistory(t *testing.T) {
+	handler := &slowFileHandler{release: make(chan struct{})}
+	c := CreateNamespaceConfigWithComponents("backup", &extension.Components{FileHandler: handler})
+	writeBackupWhileBlocked(c, handler)
+
+	// 不支持单独写入历史版本时按顺序写入每个版本
+	handler.lock.Lock()
+	defer handler.lock.Unlock()
+	Assert(t, handler.writes, Equal([]string{"1", "2", "3"}))
+}
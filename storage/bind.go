@@ -0,0 +1,571 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/snailzed/agollo/v4/utils"
+)
+
+const (
+	bindTagName      = "apollo"
+	bindTagDefault   = "default="
+	bindTagSeparator = ","
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// FieldError 单个字段绑定失败的原因
+type FieldError struct {
+	Field string
+	Key   string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("field %s (key %s): %s", e.Field, e.Key, e.Err)
+}
+
+// BindError 绑定失败的字段汇总
+type BindError struct {
+	Namespace string
+	Errors    []*FieldError
+}
+
+func (e *BindError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "bind namespace %s fail, %d field(s) failed:", e.Namespace, len(e.Errors))
+	for _, err := range e.Errors {
+		b.WriteString("\n\t")
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+// Bind 将整个namespace的配置绑定到结构体指针 v 上
+// 字段通过 `apollo:"db.host,default=localhost"` 指定 key 与默认值，未指定时使用字段名，`apollo:"-"` 表示忽略该字段
+// 嵌套结构体的 key 以 . 拼接，切片支持 a[0] 形式的 key、JSON 数组或逗号分隔的字符串，map 使用 a.<key> 形式的 key
+// 支持 time.Duration、指针以及实现了 encoding.TextUnmarshaler 的类型
+// 部分字段转换失败时其余字段依然会被绑定，并返回列出所有失败字段的 *BindError
+func (c *Config) Bind(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind fail ! target must be a non-nil pointer to struct, got %T", v)
+	}
+
+	if !c.GetIsInit() && c.mustWait {
+		c.waitInit.Wait()
+	}
+	if c.cache == nil {
+		return fmt.Errorf("bind fail ! namespace:%s is not exist", c.namespace)
+	}
+
+	values := make(map[string]interface{})
+	c.updateLock.RLock()
+	c.cache.Range(func(key, value interface{}) bool {
+		if k, ok := key.(string); ok {
+			values[k] = value
+		}
+		return true
+	})
+	for key, value := range c.overrides.getNamespace(c.namespace) {
+		values[key] = value
+	}
+	c.updateLock.RUnlock()
+
+	b := &binder{values: values}
+	b.bindStruct(rv.Elem(), utils.Empty, rv.Elem().Type().Name())
+	if len(b.errs) > 0 {
+		return &BindError{Namespace: c.namespace, Errors: b.errs}
+	}
+	return nil
+}
+
+type binder struct {
+	values map[string]interface{}
+	errs   []*FieldError
+}
+
+func (b *binder) addError(field, key string, err error) {
+	b.errs = append(b.errs, &FieldError{Field: field, Key: key, Err: err})
+}
+
+func (b *binder) bindStruct(v reflect.Value, prefix string, field string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get(bindTagName)
+		if tag == "-" {
+			continue
+		}
+		name, defaultValue, hasDefault := parseBindTag(tag)
+
+		fv := v.Field(i)
+		if sf.Anonymous && name == utils.Empty {
+			// 未指定 key 的内嵌结构体，字段与外层共用前缀
+			if sf.Type.Kind() == reflect.Struct && !isTextUnmarshaler(sf.Type) {
+				b.bindStruct(fv, prefix, field)
+				continue
+			}
+		}
+		if sf.PkgPath != utils.Empty {
+			continue
+		}
+		if name == utils.Empty {
+			name = sf.Name
+		}
+		b.bindValue(fv, joinKey(prefix, name), field+"."+sf.Name, defaultValue, hasDefault)
+	}
+}
+
+func (b *binder) bindValue(v reflect.Value, key string, field string, defaultValue string, hasDefault bool) {
+	raw, ok := b.values[key]
+	if !ok && hasDefault {
+		raw, ok = defaultValue, true
+	}
+
+	t := v.Type()
+	switch {
+	case t.Kind() == reflect.Ptr:
+		if !ok && !b.hasChildren(key) {
+			return
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		b.bindValue(v.Elem(), key, field, defaultValue, hasDefault)
+	case isTextUnmarshaler(t) || t == durationType:
+		if ok {
+			b.assign(v, raw, key, field)
+		}
+	case t.Kind() == reflect.Struct:
+		// 没有 a.b 形式的子配置时，使用 key 本身的值（JSON 字符串或子树）
+		if ok && !b.hasChildren(key) {
+			b.assign(v, raw, key, field)
+			return
+		}
+		b.bindStruct(v, key, field)
+	case t.Kind() == reflect.Slice:
+		if n := b.sliceLen(key); n > 0 {
+			s := reflect.MakeSlice(t, n, n)
+			for i := 0; i < n; i++ {
+				b.bindValue(s.Index(i), fmt.Sprintf("%s[%d]", key, i), fmt.Sprintf("%s[%d]", field, i), utils.Empty, false)
+			}
+			v.Set(s)
+			return
+		}
+		if ok {
+			b.assign(v, raw, key, field)
+		}
+	case t.Kind() == reflect.Map:
+		if b.hasChildren(key) {
+			b.bindMap(v, key, field)
+			return
+		}
+		if ok {
+			b.assign(v, raw, key, field)
+		}
+	default:
+		if ok {
+			b.assign(v, raw, key, field)
+		}
+	}
+}
+
+func (b *binder) bindMap(v reflect.Value, prefix string, field string) {
+	t := v.Type()
+	if t.Key().Kind() != reflect.String {
+		b.addError(field, prefix, fmt.Errorf("unsupported map key type %s", t.Key()))
+		return
+	}
+
+	elem := t.Elem()
+	for elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	// 元素为复合类型时只取 key 的第一段，其余部分交给元素绑定
+	composite := !isTextUnmarshaler(elem) && elem != durationType &&
+		(elem.Kind() == reflect.Struct || elem.Kind() == reflect.Map || elem.Kind() == reflect.Slice)
+
+	children := make(map[string]struct{})
+	for key := range b.values {
+		rest, ok := trimKeyPrefix(key, prefix)
+		if !ok || rest == utils.Empty {
+			continue
+		}
+		if composite {
+			if i := strings.IndexAny(rest, ".["); i > 0 {
+				rest = rest[:i]
+			}
+		}
+		children[rest] = struct{}{}
+	}
+	if len(children) == 0 {
+		return
+	}
+
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(t, len(children)))
+	}
+	for child := range children {
+		ev := reflect.New(t.Elem()).Elem()
+		b.bindValue(ev, joinKey(prefix, child), fmt.Sprintf("%s[%s]", field, child), utils.Empty, false)
+		v.SetMapIndex(reflect.ValueOf(child).Convert(t.Key()), ev)
+	}
+}
+
+// assign 将已存在的原始值转换后写入 v
+func (b *binder) assign(v reflect.Value, raw interface{}, key string, field string) {
+	if raw == nil {
+		return
+	}
+	t := v.Type()
+	if t.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		b.assign(v.Elem(), raw, key, field)
+		return
+	}
+
+	if reflect.TypeOf(raw).AssignableTo(t) {
+		v.Set(reflect.ValueOf(raw))
+		return
+	}
+
+	if isTextUnmarshaler(t) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(toString(raw))); err != nil {
+			b.addError(field, key, err)
+		}
+		return
+	}
+
+	if t == durationType {
+		d, err := toDuration(raw)
+		if err != nil {
+			b.addError(field, key, err)
+			return
+		}
+		v.SetInt(int64(d))
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		switch r := raw.(type) {
+		case map[string]interface{}:
+			sub := &binder{values: make(map[string]interface{})}
+			flattenValues(sub.values, utils.Empty, r)
+			if t.Kind() == reflect.Struct {
+				sub.bindStruct(v, utils.Empty, field)
+			} else {
+				sub.bindMap(v, utils.Empty, field)
+			}
+			for _, err := range sub.errs {
+				err.Key = joinKey(key, err.Key)
+				b.errs = append(b.errs, err)
+			}
+		case string:
+			if err := json.Unmarshal([]byte(r), v.Addr().Interface()); err != nil {
+				b.addError(field, key, err)
+			}
+		default:
+			b.addError(field, key, fmt.Errorf("cannot convert %T to %s", raw, t))
+		}
+	case reflect.Slice:
+		switch r := raw.(type) {
+		case []interface{}:
+			s := reflect.MakeSlice(t, len(r), len(r))
+			for i, item := range r {
+				b.assign(s.Index(i), item, fmt.Sprintf("%s[%d]", key, i), fmt.Sprintf("%s[%d]", field, i))
+			}
+			v.Set(s)
+		case string:
+			b.assignSliceString(v, r, key, field)
+		default:
+			b.addError(field, key, fmt.Errorf("cannot convert %T to %s", raw, t))
+		}
+	default:
+		if err := convertScalar(v, raw); err != nil {
+			b.addError(field, key, err)
+		}
+	}
+}
+
+// assignSliceString 字符串形式的切片，[ 开头按JSON数组解析，否则按逗号分隔
+func (b *binder) assignSliceString(v reflect.Value, value string, key string, field string) {
+	t := v.Type()
+	value = strings.TrimSpace(value)
+	if t.Elem().Kind() == reflect.Uint8 {
+		v.SetBytes([]byte(value))
+		return
+	}
+	if strings.HasPrefix(value, "[") {
+		if err := json.Unmarshal([]byte(value), v.Addr().Interface()); err != nil {
+			b.addError(field, key, err)
+		}
+		return
+	}
+
+	var items []string
+	if value != utils.Empty {
+		items = strings.Split(value, bindTagSeparator)
+	}
+	s := reflect.MakeSlice(t, len(items), len(items))
+	for i, item := range items {
+		b.assign(s.Index(i), strings.TrimSpace(item), fmt.Sprintf("%s[%d]", key, i), fmt.Sprintf("%s[%d]", field, i))
+	}
+	v.Set(s)
+}
+
+// hasChildren 是否存在以 key 为前缀的子配置
+func (b *binder) hasChildren(key string) bool {
+	for k := range b.values {
+		if rest, ok := trimKeyPrefix(k, key); ok && rest != utils.Empty {
+			return true
+		}
+		if strings.HasPrefix(k, key+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// sliceLen 根据 key[0]、key[1] 形式的子配置计算切片长度
+func (b *binder) sliceLen(key string) int {
+	n := 0
+	prefix := key + "["
+	for k := range b.values {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		rest := k[len(prefix):]
+		end := strings.IndexByte(rest, ']')
+		if end <= 0 {
+			continue
+		}
+		i, err := strconv.Atoi(rest[:end])
+		if err != nil || i < 0 {
+			continue
+		}
+		if next := rest[end+1:]; next != utils.Empty && next[0] != '.' && next[0] != '[' {
+			continue
+		}
+		if i+1 > n {
+			n = i + 1
+		}
+	}
+	return n
+}
+
+func parseBindTag(tag string) (name string, defaultValue string, hasDefault bool) {
+	parts := strings.SplitN(tag, bindTagSeparator, 2)
+	name = strings.TrimSpace(parts[0])
+	if len(parts) < 2 {
+		return name, utils.Empty, false
+	}
+
+	// default 之后的内容全部作为默认值，允许默认值中包含逗号
+	options := parts[1]
+	for options != utils.Empty {
+		if strings.HasPrefix(options, bindTagDefault) {
+			return name, options[len(bindTagDefault):], true
+		}
+		i := strings.Index(options, bindTagSeparator)
+		if i < 0 {
+			break
+		}
+		options = options[i+1:]
+	}
+	return name, utils.Empty, false
+}
+
+func joinKey(prefix string, name string) string {
+	if prefix == utils.Empty {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func trimKeyPrefix(key string, prefix string) (string, bool) {
+	if prefix == utils.Empty {
+		return key, true
+	}
+	if !strings.HasPrefix(key, prefix+".") {
+		return utils.Empty, false
+	}
+	return key[len(prefix)+1:], true
+}
+
+func flattenValues(m map[string]interface{}, prefix string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			flattenValues(m, joinKey(prefix, key), child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			flattenValues(m, fmt.Sprintf("%s[%d]", prefix, i), child)
+		}
+	default:
+		m[prefix] = v
+	}
+}
+
+func isTextUnmarshaler(t reflect.Type) bool {
+	return t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+func toString(raw interface{}) string {
+	switch v := raw.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func toDuration(raw interface{}) (time.Duration, error) {
+	switch v := raw.(type) {
+	case string:
+		return time.ParseDuration(strings.TrimSpace(v))
+	case []byte:
+		return time.ParseDuration(strings.TrimSpace(string(v)))
+	}
+	rv := reflect.ValueOf(raw)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return time.Duration(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return time.Duration(rv.Uint()), nil
+	}
+	return 0, fmt.Errorf("cannot convert %T to time.Duration", raw)
+}
+
+// convertScalar 将原始值转换为 bool、数字或字符串
+func convertScalar(v reflect.Value, raw interface{}) error {
+	rv := reflect.ValueOf(raw)
+	t := v.Type()
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(toString(raw))
+		return nil
+	case reflect.Bool:
+		if rv.Kind() == reflect.Bool {
+			v.SetBool(rv.Bool())
+			return nil
+		}
+		b, err := strconv.ParseBool(strings.TrimSpace(toString(raw)))
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i = rv.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if rv.Uint() > uint64(1<<63-1) {
+				return fmt.Errorf("value %v overflows %s", raw, t)
+			}
+			i = int64(rv.Uint())
+		case reflect.Float32, reflect.Float64:
+			f := rv.Float()
+			if f != float64(int64(f)) {
+				return fmt.Errorf("cannot convert %v to %s", raw, t)
+			}
+			i = int64(f)
+		default:
+			parsed, err := strconv.ParseInt(strings.TrimSpace(toString(raw)), 0, t.Bits())
+			if err != nil {
+				return err
+			}
+			i = parsed
+		}
+		if v.OverflowInt(i) {
+			return fmt.Errorf("value %v overflows %s", raw, t)
+		}
+		v.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if rv.Int() < 0 {
+				return fmt.Errorf("value %v overflows %s", raw, t)
+			}
+			u = uint64(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			u = rv.Uint()
+		case reflect.Float32, reflect.Float64:
+			f := rv.Float()
+			if f < 0 || f != float64(uint64(f)) {
+				return fmt.Errorf("cannot convert %v to %s", raw, t)
+			}
+			u = uint64(f)
+		default:
+			parsed, err := strconv.ParseUint(strings.TrimSpace(toString(raw)), 0, t.Bits())
+			if err != nil {
+				return err
+			}
+			u = parsed
+		}
+		if v.OverflowUint(u) {
+			return fmt.Errorf("value %v overflows %s", raw, t)
+		}
+		v.SetUint(u)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			f = float64(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			f = float64(rv.Uint())
+		case reflect.Float32, reflect.Float64:
+			f = rv.Float()
+		default:
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(toString(raw)), t.Bits())
+			if err != nil {
+				return err
+			}
+			f = parsed
+		}
+		if v.OverflowFloat(f) {
+			return fmt.Errorf("value %v overflows %s", raw, t)
+		}
+		v.SetFloat(f)
+		return nil
+	}
+	return errors.New("unsupported type " + t.String())
+}
@@ -0,0 +1,218 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/snailzed/agollo/v4/env"
+	. "github.com/tevid/gohamcrest"
+)
+
+type bindDB struct {
+	Host    string        `apollo:"host,default=localhost"`
+	Port    int           `apollo:"port,default=3306"`
+	Timeout time.Duration `apollo:"timeout,default=1s"`
+}
+
+type bindNode struct {
+	Host   string `apollo:"host"`
+	Weight int    `apollo:"weight,default=1"`
+}
+
+type bindBase struct {
+	Name string `apollo:"name"`
+}
+
+type bindConfig struct {
+	bindBase
+	DB       bindDB              `apollo:"db"`
+	Replica  *bindDB             `apollo:"replica"`
+	Missing  *bindDB             `apollo:"missing"`
+	Tags     []string            `apollo:"tags,default=a,b"`
+	Ports    []int               `apollo:"ports"`
+	Nodes    []bindNode          `apollo:"nodes"`
+	Labels   map[string]string   `apollo:"labels"`
+	Groups   map[string]bindNode `apollo:"groups"`
+	IP       net.IP              `apollo:"ip"`
+	Enable   *bool               `apollo:"enable"`
+	Ratio    float64             `apollo:"ratio"`
+	Ignored  string              `apollo:"-"`
+	Untagged string
+	secret   string
+}
+
+func TestBind(t *testing.T) {
+	c := creatTestApolloConfig(map[string]interface{}{
+		"name":             "agollo",
+		"db.host":          "127.0.0.1",
+		"db.timeout":       "3s",
+		"replica.port":     3307,
+		"ports":            "[8080, 8081]",
+		"nodes[0].host":    "10.0.0.1",
+		"nodes[1].host":    "10.0.0.2",
+		"nodes[1].weight":  "5",
+		"labels.env":       "dev",
+		"labels.zone.name": "cn",
+		"groups.a.host":    "10.0.1.1",
+		"groups.b.weight":  2,
+		"ip":               "192.168.1.1",
+		"enable":           "true",
+		"ratio":            0.5,
+		"Ignored":          "x",
+		"Untagged":         "untagged",
+		"secret":           "secret",
+	}, "bind")
+
+	cfg := &bindConfig{}
+	err := c.GetConfig("bind").Bind(cfg)
+	Assert(t, err, NilVal())
+
+	Assert(t, cfg.Name, Equal("agollo"))
+	Assert(t, cfg.DB.Host, Equal("127.0.0.1"))
+	Assert(t, cfg.DB.Port, Equal(3306))
+	Assert(t, cfg.DB.Timeout, Equal(3*time.Second))
+	Assert(t, cfg.Replica, NotNilVal())
+	Assert(t, cfg.Replica.Host, Equal("localhost"))
+	Assert(t, cfg.Replica.Port, Equal(3307))
+	Assert(t, cfg.Missing == nil, Equal(true))
+	Assert(t, cfg.Tags, Equal([]string{"a", "b"}))
+	Assert(t, cfg.Ports, Equal([]int{8080, 8081}))
+	Assert(t, cfg.Nodes, Equal([]bindNode{{Host: "10.0.0.1", Weight: 1}, {Host: "10.0.0.2", Weight: 5}}))
+	Assert(t, cfg.Labels, Equal(map[string]string{"env": "dev", "zone.name": "cn"}))
+	Assert(t, cfg.Groups, Equal(map[string]bindNode{"a": {Host: "10.0.1.1", Weight: 1}, "b": {Weight: 2}}))
+	Assert(t, cfg.IP.String(), Equal("192.168.1.1"))
+	Assert(t, *cfg.Enable, Equal(true))
+	Assert(t, cfg.Ratio, Equal(0.5))
+	Assert(t, cfg.Ignored, Equal(""))
+	Assert(t, cfg.Untagged, Equal("untagged"))
+	Assert(t, cfg.secret, Equal(""))
+}
+
+func TestBindSubtree(t *testing.T) {
+	c := creatTestApolloConfig(map[string]interface{}{
+		"db": map[string]interface{}{
+			"host": "127.0.0.2",
+			"port": 3308,
+		},
+		"nodes": []interface{}{
+			map[string]interface{}{"host": "10.0.0.1"},
+		},
+		"tags": []interface{}{"x", "y"},
+	}, "bindSubtree")
+
+	cfg := &struct {
+		DB    bindDB     `apollo:"db"`
+		Nodes []bindNode `apollo:"nodes"`
+		Tags  []string   `apollo:"tags"`
+	}{}
+	err := c.GetConfig("bindSubtree").Bind(cfg)
+	Assert(t, err, NilVal())
+
+	Assert(t, cfg.DB.Host, Equal("127.0.0.2"))
+	Assert(t, cfg.DB.Port, Equal(3308))
+	Assert(t, cfg.DB.Timeout, Equal(time.Second))
+	Assert(t, cfg.Nodes, Equal([]bindNode{{Host: "10.0.0.1", Weight: 1}}))
+	Assert(t, cfg.Tags, Equal([]string{"x", "y"}))
+}
+
+func TestBindError(t *testing.T) {
+	c := creatTestApolloConfig(map[string]interface{}{
+		"db.port":    "abc",
+		"db.timeout": "3",
+		"ip":         "not-ip",
+		"ratio":      "0.5",
+	}, "bindError")
+
+	cfg := &bindConfig{}
+	err := c.GetConfig("bindError").Bind(cfg)
+	Assert(t, err, NotNilVal())
+
+	bindErr, ok := err.(*BindError)
+	Assert(t, ok, Equal(true))
+	Assert(t, bindErr.Namespace, Equal("bindError"))
+	Assert(t, len(bindErr.Errors), Equal(3))
+	Assert(t, strings.Contains(err.Error(), "bindConfig.DB.Port"), Equal(true))
+	Assert(t, strings.Contains(err.Error(), "bindConfig.DB.Timeout"), Equal(true))
+	Assert(t, strings.Contains(err.Error(), "bindConfig.IP"), Equal(true))
+
+	// 失败的字段不影响其他字段
+	Assert(t, cfg.Ratio, Equal(0.5))
+	Assert(t, cfg.DB.Host, Equal("localhost"))
+
+	err = c.GetConfig("bindError").Bind(bindConfig{})
+	Assert(t, err, NotNilVal())
+	_, ok = err.(*BindError)
+	Assert(t, ok, Equal(false))
+
+	err = c.GetConfig("bindError").Bind(nil)
+	Assert(t, err, NotNilVal())
+}
+
+func TestBindConsistentWithUpdate(t *testing.T) {
+	c := creatTestApolloConfig(map[string]interface{}{"host": "0", "port": 0}, "bindUpdate")
+	appConfig := env.InitFileConfig()
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			c.UpdateApolloConfigCache(map[string]interface{}{"host": strconv.Itoa(i), "port": i}, configCacheExpireTime, "bindUpdate", *appConfig)
+		}
+	}()
+
+	// 绑定结果中的字段总是来自同一次更新
+	for i := 0; i < 2000; i++ {
+		cfg := &bindDB{}
+		Assert(t, c.GetConfig("bindUpdate").Bind(cfg), NilVal())
+		Assert(t, cfg.Host, Equal(strconv.Itoa(cfg.Port)))
+	}
+	close(stop)
+	<-done
+}
+
+func TestParseBindTag(t *testing.T) {
+	name, defaultValue, hasDefault := parseBindTag("db.host,default=localhost")
+	Assert(t, name, Equal("db.host"))
+	Assert(t, defaultValue, Equal("localhost"))
+	Assert(t, hasDefault, Equal(true))
+
+	name, defaultValue, hasDefault = parseBindTag("tags,default=a,b")
+	Assert(t, name, Equal("tags"))
+	Assert(t, defaultValue, Equal("a,b"))
+	Assert(t, hasDefault, Equal(true))
+
+	name, defaultValue, hasDefault = parseBindTag(",default=")
+	Assert(t, name, Equal(""))
+	Assert(t, defaultValue, Equal(""))
+	Assert(t, hasDefault, Equal(true))
+
+	name, _, hasDefault = parseBindTag("port")
+	Assert(t, name, Equal("port"))
+	Assert(t, hasDefault, Equal(false))
+}
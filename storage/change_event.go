@@ -51,6 +51,8 @@ type ConfigChange struct {
 	OldValue   interface{}
 	NewValue   interface{}
 	ChangeType ConfigChangeType
+	//Source 触发变更的来源，本地覆盖配置变化时为 OverrideSource
+	Source ConfigSource
 }
 
 // all config change event
@@ -0,0 +1,266 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/goccy/go-json"
+)
+
+//OverrideEnvPrefix 本地覆盖配置的环境变量前缀，格式为 APOLLO_OVERRIDE_{namespace}_{key}
+const OverrideEnvPrefix = "APOLLO_OVERRIDE_"
+
+//ConfigSource 配置值来源
+type ConfigSource int
+
+const (
+	//RemoteSource 来自 apollo 的配置
+	RemoteSource ConfigSource = iota
+	//OverrideSource 来自本地覆盖文件或环境变量的配置
+	OverrideSource
+)
+
+//EffectiveValue 配置项的生效值与 apollo 上的值
+type EffectiveValue struct {
+	Key string
+	//Value 生效值
+	Value interface{}
+	//RemoteValue apollo 上的值，不存在时为 nil
+	RemoteValue interface{}
+	Source      ConfigSource
+}
+
+//overrideStore 按 namespace 保存本地覆盖配置
+type overrideStore struct {
+	lock   sync.RWMutex
+	values map[string]map[string]string
+}
+
+func (o *overrideStore) get(namespace string, key string) (string, bool) {
+	if o == nil {
+		return "", false
+	}
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	value, ok := o.values[namespace][key]
+	return value, ok
+}
+
+func (o *overrideStore) getNamespace(namespace string) map[string]string {
+	if o == nil {
+		return nil
+	}
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	values := make(map[string]string, len(o.values[namespace]))
+	for key, value := range o.values[namespace] {
+		values[key] = value
+	}
+	return values
+}
+
+//SetOverrides 替换本地覆盖配置，覆盖配置优先于 apollo 上的值
+//生效值发生变化的 key 会以 OverrideSource 来源触发变更事件
+func (c *Cache) SetOverrides(overrides map[string]map[string]string) {
+	values := make(map[string]map[string]string, len(overrides))
+	for namespace, kv := range overrides {
+		values[namespace] = make(map[string]string, len(kv))
+		for key, value := range kv {
+			values[namespace][key] = value
+		}
+	}
+
+	c.overrides.lock.Lock()
+	old := c.overrides.values
+	c.overrides.values = values
+	c.overrides.lock.Unlock()
+
+	namespaces := make(map[string]bool, len(old)+len(values))
+	for namespace := range old {
+		namespaces[namespace] = true
+	}
+	for namespace := range values {
+		namespaces[namespace] = true
+	}
+	for namespace := range namespaces {
+		changes := c.overrideChanges(namespace, old[namespace], values[namespace])
+		if len(changes) > 0 {
+			c.pushChangeEvent(createConfigChangeEvent(changes, namespace, 0))
+		}
+	}
+}
+
+//overrideChanges 计算覆盖配置变化前后生效值的变化
+func (c *Cache) overrideChanges(namespace string, old map[string]string, values map[string]string) map[string]*ConfigChange {
+	config := c.GetConfig(namespace)
+	remote := func(key string) (interface{}, bool) {
+		if config == nil || config.cache == nil {
+			return nil, false
+		}
+		value, err := config.cache.Get(key)
+		return value, err == nil
+	}
+	effective := func(overrides map[string]string, key string) (interface{}, bool) {
+		if value, ok := overrides[key]; ok {
+			return value, true
+		}
+		return remote(key)
+	}
+
+	keys := make(map[string]bool, len(old)+len(values))
+	for key := range old {
+		keys[key] = true
+	}
+	for key := range values {
+		keys[key] = true
+	}
+
+	changes := make(map[string]*ConfigChange)
+	for key := range keys {
+		before, hasBefore := effective(old, key)
+		after, hasAfter := effective(values, key)
+		var change *ConfigChange
+		switch {
+		case hasBefore && hasAfter:
+			if reflect.DeepEqual(before, after) {
+				continue
+			}
+			change = createModifyConfigChange(before, after)
+		case hasAfter:
+			change = createAddConfigChange(after)
+		case hasBefore:
+			change = createDeletedConfigChange(before)
+		default:
+			continue
+		}
+		change.Source = OverrideSource
+		changes[key] = change
+	}
+	return changes
+}
+
+//GetEffectiveValues 列出 namespace 所有配置项的生效值及 apollo 上的值，按 key 排序
+func (c *Config) GetEffectiveValues() []*EffectiveValue {
+	values := make(map[string]*EffectiveValue)
+	if c.cache != nil {
+		c.cache.Range(func(key, value interface{}) bool {
+			if k, ok := key.(string); ok {
+				values[k] = &EffectiveValue{Key: k, Value: value, RemoteValue: value, Source: RemoteSource}
+			}
+			return true
+		})
+	}
+	for key, value := range c.overrides.getNamespace(c.namespace) {
+		v, ok := values[key]
+		if !ok {
+			v = &EffectiveValue{Key: key}
+			values[key] = v
+		}
+		v.Value = value
+		v.Source = OverrideSource
+	}
+
+	result := make([]*EffectiveValue, 0, len(values))
+	for _, v := range values {
+		result = append(result, v)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+//LoadOverrides 读取本地覆盖配置
+//fileName 为 {"namespace": {"key": "value"}} 格式的 json 文件，为空时不读取文件
+//环境变量 APOLLO_OVERRIDE_{namespace}_{key} 优先于文件，namespace 与 key 中的 "." 写作 "_"，"_" 写作 "__"
+//例如 APOLLO_OVERRIDE_application_db_host 覆盖 application 中的 db.host
+func LoadOverrides(fileName string, namespaces string, environ []string) (map[string]map[string]string, error) {
+	overrides := make(map[string]map[string]string)
+	set := func(namespace string, key string, value string) {
+		if overrides[namespace] == nil {
+			overrides[namespace] = make(map[string]string)
+		}
+		overrides[namespace][key] = value
+	}
+
+	if fileName != "" {
+		b, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			return nil, err
+		}
+		file := make(map[string]map[string]interface{})
+		if err := json.Unmarshal(b, &file); err != nil {
+			return nil, fmt.Errorf("parse override file %s fail: %s", fileName, err)
+		}
+		for namespace, kv := range file {
+			for key, value := range kv {
+				if s, ok := value.(string); ok {
+					set(namespace, key, s)
+				} else {
+					set(namespace, key, fmt.Sprint(value))
+				}
+			}
+		}
+	}
+
+	names := strings.Split(namespaces, ",")
+	for _, env := range environ {
+		i := strings.Index(env, "=")
+		if i < 0 || !strings.HasPrefix(env[:i], OverrideEnvPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(env[:i], OverrideEnvPrefix)
+		namespace, key := matchOverrideEnv(names, name)
+		if namespace == "" {
+			continue
+		}
+		set(namespace, key, env[i+1:])
+	}
+	return overrides, nil
+}
+
+//matchOverrideEnv 匹配最长的 namespace，剩余部分解码为 key
+func matchOverrideEnv(namespaces []string, name string) (string, string) {
+	namespace, key := "", ""
+	for _, n := range namespaces {
+		prefix := encodeOverrideName(n) + "_"
+		if len(n) <= len(namespace) || !strings.HasPrefix(name, prefix) || len(name) == len(prefix) {
+			continue
+		}
+		namespace, key = n, decodeOverrideName(name[len(prefix):])
+	}
+	return namespace, key
+}
+
+func encodeOverrideName(name string) string {
+	return strings.Replace(strings.Replace(name, "_", "__", -1), ".", "_", -1)
+}
+
+func decodeOverrideName(name string) string {
+	parts := strings.Split(name, "__")
+	for i := range parts {
+		parts[i] = strings.Replace(parts[i], "_", ".", -1)
+	}
+	return strings.Join(parts, "_")
+}
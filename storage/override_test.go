@@ -0,0 +1,142 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/snailzed/agollo/v4/env/config"
+	. "github.com/tevid/gohamcrest"
+)
+
+type overrideListener struct {
+	changes chan *ChangeEvent
+}
+
+func (l *overrideListener) OnChange(event *ChangeEvent) {
+	l.changes <- event
+}
+
+func (l *overrideListener) OnNewestChange(event *FullChangeEvent) {
+}
+
+func waitChangeEvent(t *testing.T, l *overrideListener) *ChangeEvent {
+	select {
+	case event := <-l.changes:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("change event must be received")
+	}
+	return nil
+}
+
+func TestLoadOverrides(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agollo-override")
+	Assert(t, err, NilVal())
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "overrides.json")
+	Assert(t, ioutil.WriteFile(fileName, []byte(`{"application":{"db.host":"file","port":8080},"db.yaml":{"a":"b"}}`), 0644), NilVal())
+
+	overrides, err := LoadOverrides(fileName, "application,application_x,db.yaml", []string{
+		"APOLLO_OVERRIDE_application_db_host=env",
+		"APOLLO_OVERRIDE_application__x_timeout=1s",
+		"APOLLO_OVERRIDE_db_yaml_max__idle=10",
+		"APOLLO_OVERRIDE_unknown_key=ignored",
+		"APOLLO_OVERRIDE_application_=ignored",
+		"OTHER=ignored",
+	})
+	Assert(t, err, NilVal())
+	Assert(t, overrides["application"]["db.host"], Equal("env"))
+	Assert(t, overrides["application"]["port"], Equal("8080"))
+	Assert(t, overrides["application_x"]["timeout"], Equal("1s"))
+	Assert(t, overrides["db.yaml"]["a"], Equal("b"))
+	Assert(t, overrides["db.yaml"]["max_idle"], Equal("10"))
+	Assert(t, len(overrides), Equal(3))
+	Assert(t, len(overrides["application"]), Equal(2))
+
+	_, err = LoadOverrides(filepath.Join(dir, "missing.json"), "application", nil)
+	Assert(t, err, NotNilVal())
+	Assert(t, ioutil.WriteFile(fileName, []byte(`["a"]`), 0644), NilVal())
+	_, err = LoadOverrides(fileName, "application", nil)
+	Assert(t, err, NotNilVal())
+}
+
+func TestSetOverrides(t *testing.T) {
+	cache := CreateNamespaceConfig("override")
+	appConfig := config.AppConfig{NamespaceName: "override"}
+	appConfig.Init()
+	cache.UpdateApolloConfigCache(map[string]interface{}{"host": "remote", "port": "80"}, configCacheExpireTime, "override", appConfig)
+	l := &overrideListener{changes: make(chan *ChangeEvent, 1)}
+	cache.AddChangeListener(l)
+
+	cache.SetOverrides(map[string]map[string]string{"override": {"host": "local", "debug": "true"}})
+	event := waitChangeEvent(t, l)
+	Assert(t, event.Namespace, Equal("override"))
+	Assert(t, len(event.Changes), Equal(2))
+	Assert(t, event.Changes["host"].ChangeType, Equal(MODIFIED))
+	Assert(t, event.Changes["host"].OldValue, Equal("remote"))
+	Assert(t, event.Changes["host"].NewValue, Equal("local"))
+	Assert(t, event.Changes["host"].Source, Equal(OverrideSource))
+	Assert(t, event.Changes["debug"].ChangeType, Equal(ADDED))
+
+	config := cache.GetConfig("override")
+	Assert(t, config.GetValue("host"), Equal("local"))
+	Assert(t, config.GetBoolValue("debug", false), Equal(true))
+	Assert(t, config.GetIntValue("port", 0), Equal(80))
+
+	values := config.GetEffectiveValues()
+	Assert(t, len(values), Equal(3))
+	Assert(t, *values[0], Equal(EffectiveValue{Key: "debug", Value: "true", Source: OverrideSource}))
+	Assert(t, *values[1], Equal(EffectiveValue{Key: "host", Value: "local", RemoteValue: "remote", Source: OverrideSource}))
+	Assert(t, *values[2], Equal(EffectiveValue{Key: "port", Value: "80", RemoteValue: "80", Source: RemoteSource}))
+
+	bound := &struct {
+		Host string `apollo:"host"`
+	}{}
+	Assert(t, config.Bind(bound), NilVal())
+	Assert(t, bound.Host, Equal("local"))
+
+	//远端变更不影响覆盖后的值，不触发变更事件
+	changes := cache.UpdateApolloConfigCache(map[string]interface{}{"host": "remote2", "port": "81"}, configCacheExpireTime, "override", appConfig)
+	Assert(t, len(changes), Equal(1))
+	Assert(t, changes["host"], NilVal())
+	Assert(t, changes["port"].Source, Equal(RemoteSource))
+	Assert(t, config.GetValue("port"), Equal("81"))
+	Assert(t, config.GetValue("host"), Equal("local"))
+
+	//移除覆盖后恢复为远端的值
+	cache.SetOverrides(nil)
+	event = waitChangeEvent(t, l)
+	Assert(t, event.Changes["host"].ChangeType, Equal(MODIFIED))
+	Assert(t, event.Changes["host"].OldValue, Equal("local"))
+	Assert(t, event.Changes["host"].NewValue, Equal("remote2"))
+	Assert(t, event.Changes["debug"].ChangeType, Equal(DELETED))
+	Assert(t, config.GetValue("host"), Equal("remote2"))
+
+	//生效值不变时不触发事件
+	cache.SetOverrides(map[string]map[string]string{"override": {"port": "81"}})
+	select {
+	case event := <-l.changes:
+		t.Fatalf("unexpected change event %+v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -35,8 +35,8 @@ import (
 )
 
 const (
-	// 1 minute
-	configCacheExpireTime = 120
+	// 配置项在下次发布前一直有效，不设置过期时间
+	configCacheExpireTime = 0
 
 	defaultNamespace = "application"
 
@@ -48,6 +48,9 @@ type Cache struct {
 	apolloConfigCache sync.Map
 	changeListeners   *list.List
 	rw                sync.RWMutex
+	components        *extension.Components
+	backup            backupWriter
+	overrides         overrideStore
 }
 
 // GetConfig 根据namespace获取apollo配置
@@ -67,23 +70,27 @@ func (c *Cache) GetConfig(namespace string) *Config {
 
 // CreateNamespaceConfig 根据namespace初始化agollo内润配置
 func CreateNamespaceConfig(namespace string, mustWait ...bool) *Cache {
+	return CreateNamespaceConfigWithComponents(namespace, nil, mustWait...)
+}
+
+// CreateNamespaceConfigWithComponents 根据namespace初始化agollo内润配置，使用客户端级别的扩展组件
+func CreateNamespaceConfigWithComponents(namespace string, components *extension.Components, mustWait ...bool) *Cache {
 	// config from apollo
-	var apolloConfigCache sync.Map
+	cache := &Cache{
+		changeListeners: list.New(),
+		components:      components,
+	}
 	config.SplitNamespaces(namespace, func(namespace string) {
-		if _, ok := apolloConfigCache.Load(namespace); ok {
+		if _, ok := cache.apolloConfigCache.Load(namespace); ok {
 			return
 		}
 		var wait bool
 		if len(mustWait) > 0 {
 			wait = mustWait[0]
 		}
-		c := initConfig(namespace, extension.GetCacheFactory(), wait)
-		apolloConfigCache.Store(namespace, c)
+		cache.apolloConfigCache.Store(namespace, cache.newConfig(namespace, wait))
 	})
-	return &Cache{
-		apolloConfigCache: apolloConfigCache,
-		changeListeners:   list.New(),
-	}
+	return cache
 }
 
 func initConfig(namespace string, factory agcache.CacheFactory, mustWait bool) *Config {
@@ -97,13 +104,28 @@ func initConfig(namespace string, factory agcache.CacheFactory, mustWait bool) *
 	return c
 }
 
+func (c *Cache) newConfig(namespace string, mustWait bool) *Config {
+	config := initConfig(namespace, c.components.GetCacheFactory(), mustWait)
+	config.components = c.components
+	config.overrides = &c.overrides
+	return config
+}
+
+func (c *Cache) logger() log.LoggerInterface {
+	return c.components.GetLogger()
+}
+
 // Config apollo配置项
 type Config struct {
-	namespace string
-	cache     agcache.CacheInterface
-	isInit    atomic.Value
-	mustWait  bool
-	waitInit  sync.WaitGroup
+	namespace  string
+	cache      agcache.CacheInterface
+	isInit     atomic.Value
+	mustWait   bool
+	waitInit   sync.WaitGroup
+	components *extension.Components
+	overrides  *overrideStore
+	//updateLock 更新配置时加写锁，Bind 等需要完整配置的读取加读锁，避免读到更新了一半的配置
+	updateLock sync.RWMutex
 }
 
 // GetIsInit 获取标志
@@ -116,35 +138,64 @@ func (c *Config) GetWaitInit() *sync.WaitGroup {
 	return &c.waitInit
 }
 
+func (c *Config) logger() log.LoggerInterface {
+	return c.components.GetLogger()
+}
+
 // GetCache 获取cache
 func (c *Config) GetCache() agcache.CacheInterface {
 	return c.cache
 }
 
 // getConfigValue 获取配置值
+//本地覆盖配置优先于 apollo 上的值，且不需要等待初始化
 func (c *Config) getConfigValue(key string, waitInit bool) interface{} {
+	if value, ok := c.overrides.get(c.namespace, key); ok {
+		return value
+	}
 	b := c.GetIsInit()
 	if !b {
 		if !waitInit {
-			log.Errorf("getConfigValue fail, init not done, namespace:%s key:%s", c.namespace, key)
+			c.logger().Errorf("getConfigValue fail, init not done, namespace:%s key:%s", c.namespace, key)
 			return nil
 		}
 		c.waitInit.Wait()
 	}
 	if c.cache == nil {
-		log.Errorf("get config value fail!namespace:%s is not exist!", c.namespace)
+		c.logger().Errorf("get config value fail!namespace:%s is not exist!", c.namespace)
 		return nil
 	}
 
 	value, err := c.cache.Get(key)
 	if err != nil {
-		log.Debugf("get config value fail!key:%s,err:%s", key, err)
+		c.logger().Debugf("get config value fail!key:%s,err:%s", key, err)
 		return nil
 	}
 
 	return value
 }
 
+// HasKey 配置项是否存在，包括本地覆盖配置，mustWait 时等待初始化完成
+func (c *Config) HasKey(key string) bool {
+	if c == nil {
+		return false
+	}
+	if _, ok := c.overrides.get(c.namespace, key); ok {
+		return true
+	}
+	if !c.GetIsInit() {
+		if !c.mustWait {
+			return false
+		}
+		c.waitInit.Wait()
+	}
+	if c.cache == nil {
+		return false
+	}
+	_, err := c.cache.Get(key)
+	return err == nil
+}
+
 // GetValueImmediately 获取配置值（string），立即返回，初始化未完成直接返回错误
 func (c *Config) GetValueImmediately(key string) string {
 	value := c.getConfigValue(key, false)
@@ -154,7 +205,7 @@ func (c *Config) GetValueImmediately(key string) string {
 
 	v, ok := value.(string)
 	if !ok {
-		log.Debugf("convert to string fail ! source type:%T", value)
+		c.logger().Debugf("convert to string fail ! source type:%T", value)
 		return utils.Empty
 	}
 	return v
@@ -183,12 +234,12 @@ func (c *Config) GetStringSliceValueImmediately(key string, defaultValue []strin
 	case string:
 		err := json.Unmarshal([]byte(value.(string)), &v)
 		if err != nil {
-			log.Debugf("Unmarshal to []string fail ! source type:%T", value)
+			c.logger().Debugf("Unmarshal to []string fail ! source type:%T", value)
 			return defaultValue
 		}
 		return v
 	}
-	log.Debugf("convert to []string fail ! source type:%T", value)
+	c.logger().Debugf("convert to []string fail ! source type:%T", value)
 	return defaultValue
 }
 
@@ -205,12 +256,12 @@ func (c *Config) GetIntSliceValueImmediately(key string, defaultValue []int) (v
 	case string:
 		err := json.Unmarshal([]byte(value.(string)), &v)
 		if err != nil {
-			log.Debugf("Unmarshal to []int fail ! source type:%T", value)
+			c.logger().Debugf("Unmarshal to []int fail ! source type:%T", value)
 			return defaultValue
 		}
 		return v
 	}
-	log.Debugf("convert to []int fail ! source type:%T", value)
+	c.logger().Debugf("convert to []int fail ! source type:%T", value)
 	return defaultValue
 }
 
@@ -227,12 +278,12 @@ func (c *Config) GetSliceValueImmediately(key string, defaultValue []interface{}
 	case string:
 		err := json.Unmarshal([]byte(value.(string)), &v)
 		if err != nil {
-			log.Debugf("Unmarshal to []interface{} fail ! source type:%T", value)
+			c.logger().Debugf("Unmarshal to []interface{} fail ! source type:%T", value)
 			return defaultValue
 		}
 		return v
 	}
-	log.Debug("convert to []interface{} fail ! source type:%T", value)
+	c.logger().Debug("convert to []interface{} fail ! source type:%T", value)
 	return defaultValue
 }
 
@@ -248,12 +299,12 @@ func (c *Config) GetIntValueImmediately(key string, defaultValue int) int {
 	case string:
 		v, err := strconv.Atoi(value.(string))
 		if err != nil {
-			log.Debugf("convert to int fail ! source type:%T", value)
+			c.logger().Debugf("convert to int fail ! source type:%T", value)
 			return defaultValue
 		}
 		return v
 	}
-	log.Debug("convert to int fail ! source type:%T", value)
+	c.logger().Debug("convert to int fail ! source type:%T", value)
 	return defaultValue
 }
 
@@ -269,12 +320,12 @@ func (c *Config) GetFloatValueImmediately(key string, defaultValue float64) floa
 	case string:
 		v, err := strconv.ParseFloat(value.(string), 64)
 		if err != nil {
-			log.Debug("convert to float64 fail ! source type:%T", value)
+			c.logger().Debug("convert to float64 fail ! source type:%T", value)
 			return defaultValue
 		}
 		return v
 	}
-	log.Debugf("convert to float64 fail ! source type:%T", value)
+	c.logger().Debugf("convert to float64 fail ! source type:%T", value)
 	return defaultValue
 }
 
@@ -290,12 +341,12 @@ func (c *Config) GetBoolValueImmediately(key string, defaultValue bool) (v bool)
 	case string:
 		v, err := strconv.ParseBool(value.(string))
 		if err != nil {
-			log.Debugf("convert to bool fail ! source type:%T", value)
+			c.logger().Debugf("convert to bool fail ! source type:%T", value)
 			return defaultValue
 		}
 		return v
 	}
-	log.Debugf("convert to bool fail ! source type:%T", value)
+	c.logger().Debugf("convert to bool fail ! source type:%T", value)
 	return defaultValue
 }
 
@@ -308,7 +359,7 @@ func (c *Config) GetValue(key string) string {
 
 	v, ok := value.(string)
 	if !ok {
-		log.Debugf("convert to string fail ! source type:%T", value)
+		c.logger().Debugf("convert to string fail ! source type:%T", value)
 		return utils.Empty
 	}
 	return v
@@ -337,12 +388,12 @@ func (c *Config) GetStringSliceValue(key string, defaultValue []string) (v []str
 	case string:
 		err := json.Unmarshal([]byte(value.(string)), &v)
 		if err != nil {
-			log.Debugf("Unmarshal to []string fail ! source type:%T", value)
+			c.logger().Debugf("Unmarshal to []string fail ! source type:%T", value)
 			return defaultValue
 		}
 		return v
 	}
-	log.Debugf("convert to []string fail ! source type:%T", value)
+	c.logger().Debugf("convert to []string fail ! source type:%T", value)
 	return defaultValue
 }
 
@@ -359,12 +410,12 @@ func (c *Config) GetIntSliceValue(key string, defaultValue []int) (v []int) {
 	case string:
 		err := json.Unmarshal([]byte(value.(string)), &v)
 		if err != nil {
-			log.Debugf("Unmarshal to []int fail ! source type:%T", value)
+			c.logger().Debugf("Unmarshal to []int fail ! source type:%T", value)
 			return defaultValue
 		}
 		return v
 	}
-	log.Debugf("convert to []int fail ! source type:%T", value)
+	c.logger().Debugf("convert to []int fail ! source type:%T", value)
 	return defaultValue
 }
 
@@ -381,12 +432,12 @@ func (c *Config) GetSliceValue(key string, defaultValue []interface{}) (v []inte
 	case string:
 		err := json.Unmarshal([]byte(value.(string)), &v)
 		if err != nil {
-			log.Debugf("Unmarshal to []interface{} fail ! source type:%T", value)
+			c.logger().Debugf("Unmarshal to []interface{} fail ! source type:%T", value)
 			return defaultValue
 		}
 		return v
 	}
-	log.Debug("convert to []interface{} fail ! source type:%T", value)
+	c.logger().Debug("convert to []interface{} fail ! source type:%T", value)
 	return defaultValue
 }
 
@@ -403,12 +454,12 @@ func (c *Config) GetIntValue(key string, defaultValue int) int {
 	case string:
 		v, err := strconv.Atoi(value.(string))
 		if err != nil {
-			log.Debugf("convert to int fail ! source type:%T", value)
+			c.logger().Debugf("convert to int fail ! source type:%T", value)
 			return defaultValue
 		}
 		return v
 	}
-	log.Debug("convert to int fail ! source type:%T", value)
+	c.logger().Debug("convert to int fail ! source type:%T", value)
 	return defaultValue
 }
 
@@ -424,12 +475,12 @@ func (c *Config) GetFloatValue(key string, defaultValue float64) float64 {
 	case string:
 		v, err := strconv.ParseFloat(value.(string), 64)
 		if err != nil {
-			log.Debug("convert to float64 fail ! source type:%T", value)
+			c.logger().Debug("convert to float64 fail ! source type:%T", value)
 			return defaultValue
 		}
 		return v
 	}
-	log.Debug("convert to float64 fail ! source type:%T", value)
+	c.logger().Debug("convert to float64 fail ! source type:%T", value)
 	return defaultValue
 }
 
@@ -445,12 +496,12 @@ func (c *Config) GetBoolValue(key string, defaultValue bool) bool {
 	case string:
 		v, err := strconv.ParseBool(value.(string))
 		if err != nil {
-			log.Debug("convert to bool fail ! source type:%T", value)
+			c.logger().Debug("convert to bool fail ! source type:%T", value)
 			return defaultValue
 		}
 		return v
 	}
-	log.Debug("convert to bool fail ! source type:%T", value)
+	c.logger().Debug("convert to bool fail ! source type:%T", value)
 	return defaultValue
 }
 
@@ -498,7 +549,7 @@ func (c *Config) UnmarshalImmediately(key string, defaultValue interface{}) erro
 // 并判断是否需要写备份文件
 func (c *Cache) UpdateApolloConfig(apolloConfig *config.ApolloConfig, appConfigFunc func() config.AppConfig) {
 	if apolloConfig == nil {
-		log.Error("apolloConfig is null,can't update!")
+		c.logger().Error("apolloConfig is null,can't update!")
 		return
 	}
 
@@ -525,7 +576,7 @@ func (c *Cache) UpdateApolloConfig(apolloConfig *config.ApolloConfig, appConfigF
 	if appConfig.GetIsBackupConfig() {
 		// write config file async
 		apolloConfig.AppID = appConfig.AppID
-		go extension.GetFileHandler().WriteConfigFile(apolloConfig, appConfig.GetBackupConfigPath())
+		c.writeBackup(apolloConfig, appConfig.GetBackupConfigPath())
 	}
 }
 
@@ -533,7 +584,7 @@ func (c *Cache) UpdateApolloConfig(apolloConfig *config.ApolloConfig, appConfigF
 func (c *Cache) UpdateApolloConfigCache(configurations map[string]interface{}, expireTime int, namespace string, appConfig config.AppConfig) map[string]*ConfigChange {
 	config := c.GetConfig(namespace)
 	if config == nil {
-		config = initConfig(namespace, extension.GetCacheFactory(), appConfig.MustStart)
+		config = c.newConfig(namespace, appConfig.MustStart)
 		c.apolloConfigCache.Store(namespace, config)
 	}
 
@@ -558,6 +609,9 @@ func (c *Cache) UpdateApolloConfigCache(configurations map[string]interface{}, e
 		return nil
 	}
 
+	config.updateLock.Lock()
+	defer config.updateLock.Unlock()
+
 	// get old keys
 	mp := map[string]bool{}
 	config.cache.Range(func(key, value interface{}) bool {
@@ -584,7 +638,7 @@ func (c *Cache) UpdateApolloConfigCache(configurations map[string]interface{}, e
 			}
 
 			if err := config.cache.Set(key, value, expireTime); err != nil {
-				log.Errorf("set key %s to cache error %s", key, err)
+				c.logger().Errorf("set key %s to cache error %s", key, err)
 			}
 			delete(mp, key)
 		}
@@ -598,6 +652,10 @@ func (c *Cache) UpdateApolloConfigCache(configurations map[string]interface{}, e
 
 		config.cache.Del(key)
 	}
+	//被本地覆盖的 key 生效值不变，不触发变更事件
+	for key := range c.overrides.getNamespace(namespace) {
+		delete(changes, key)
+	}
 	isInit = true
 
 	return changes
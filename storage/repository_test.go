@@ -228,7 +228,7 @@ func TestDispatchInRepository(t *testing.T) {
 }
 
 func TestGetValueImmediately(t *testing.T) {
-	c := initConfig("namespace", extension.GetCacheFactory())
+	c := initConfig("namespace", extension.GetCacheFactory(), false)
 
 	res := c.GetValueImmediately("namespace")
 	Assert(t, res, Equal(utils.Empty))
@@ -0,0 +1,65 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parse
+
+import (
+	"fmt"
+)
+
+//Flatten 将嵌套结构展开到 m 中，与 yaml 转换器一致：
+//映射展开为 a.b.c 形式的 key，列表展开为 a[0] 形式的 key，key 保留原始大小写，整数统一转换为 int
+func Flatten(m map[string]interface{}, prefix string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if prefix == "" {
+				Flatten(m, key, child)
+			} else {
+				Flatten(m, prefix+"."+key, child)
+			}
+		}
+	case []map[string]interface{}:
+		for i, child := range v {
+			Flatten(m, fmt.Sprintf("%s[%d]", prefix, i), child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			Flatten(m, fmt.Sprintf("%s[%d]", prefix, i), child)
+		}
+	default:
+		m[prefix] = normalizeInt(v)
+	}
+}
+
+//normalizeInt 将 int64 等整数转换为 int，使 Config.GetIntValue 可以直接使用
+func normalizeInt(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int64:
+		//32 位平台上超出 int 范围的值保留 int64
+		if int64(int(v)) == v {
+			return int(v)
+		}
+	case int32:
+		return int(v)
+	case int16:
+		return int(v)
+	case int8:
+		return int(v)
+	}
+	return value
+}
@@ -0,0 +1,43 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parse
+
+import (
+	"testing"
+
+	. "github.com/tevid/gohamcrest"
+)
+
+func TestFlatten(t *testing.T) {
+	m := make(map[string]interface{})
+	Flatten(m, "", map[string]interface{}{
+		"Port": int64(8080),
+		"server": map[string]interface{}{
+			"hosts": []interface{}{"a", "b"},
+		},
+		"node": []map[string]interface{}{
+			{"weight": int32(1)},
+		},
+	})
+
+	Assert(t, len(m), Equal(4))
+	Assert(t, m["Port"], Equal(8080))
+	Assert(t, m["server.hosts[0]"], Equal("a"))
+	Assert(t, m["server.hosts[1]"], Equal("b"))
+	Assert(t, m["node[0].weight"], Equal(1))
+}
@@ -0,0 +1,91 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hcl
+
+import (
+	"github.com/hashicorp/hcl"
+	"github.com/snailzed/agollo/v4/utils"
+	"github.com/snailzed/agollo/v4/utils/parse"
+)
+
+// Parser hcl转换器
+type Parser struct {
+}
+
+// Parse 内存内容=>hcl文件转换器
+// block 展开为 a.b 形式的 key，重复的 block 及列表展开为 a[0] 形式的 key，key 保留原始大小写
+func (d *Parser) Parse(configContent interface{}) (map[string]interface{}, error) {
+	content, ok := configContent.(string)
+	if !ok {
+		return nil, nil
+	}
+	if utils.Empty == content {
+		return nil, nil
+	}
+
+	tree := make(map[string]interface{})
+	if err := hcl.Unmarshal([]byte(content), &tree); err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]interface{})
+	parse.Flatten(m, utils.Empty, normalize(tree))
+	return m, nil
+}
+
+//normalize hcl的block会被解析为[]map[string]interface{}，
+//key不冲突时(单个block或带不同label的block)合并为映射，重复的block保留为列表
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []map[string]interface{}:
+		if merged, ok := merge(v); ok {
+			return normalize(merged)
+		}
+		l := make([]interface{}, len(v))
+		for i, block := range v {
+			l[i] = normalize(block)
+		}
+		return l
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			m[key] = normalize(child)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, child := range v {
+			l[i] = normalize(child)
+		}
+		return l
+	}
+	return value
+}
+
+func merge(blocks []map[string]interface{}) (map[string]interface{}, bool) {
+	merged := make(map[string]interface{})
+	for _, block := range blocks {
+		for k, v := range block {
+			if _, ok := merged[k]; ok {
+				return nil, false
+			}
+			merged[k] = v
+		}
+	}
+	return merged, true
+}
@@ -0,0 +1,101 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hcl
+
+import (
+	"testing"
+
+	"github.com/snailzed/agollo/v4/agollotest/conformance"
+	"github.com/snailzed/agollo/v4/utils"
+	"github.com/snailzed/agollo/v4/utils/parse"
+	. "github.com/tevid/gohamcrest"
+)
+
+var (
+	hclParser parse.ContentParser = &Parser{}
+)
+
+func TestHCLParser(t *testing.T) {
+	s, err := hclParser.Parse(`
+name = "agollo"
+port = 8080
+ports = [8080, 8081]
+
+server {
+  Host = "127.0.0.1"
+  tls {
+    enable = true
+  }
+}
+
+node "a" {
+  weight = 1
+}
+
+node "b" {
+  weight = 2
+}
+`)
+	Assert(t, err, NilVal())
+
+	Assert(t, s["name"], Equal("agollo"))
+	Assert(t, s["port"], Equal(8080))
+	Assert(t, s["ports[0]"], Equal(8080))
+	Assert(t, s["ports[1]"], Equal(8081))
+	//key 保留原始大小写
+	Assert(t, s["server.Host"], Equal("127.0.0.1"))
+	Assert(t, s["server.tls.enable"], Equal(true))
+	Assert(t, s["node.a.weight"], Equal(1))
+	Assert(t, s["node.b.weight"], Equal(2))
+}
+
+func TestHCLParserRepeatedBlock(t *testing.T) {
+	s, err := hclParser.Parse(`
+node {
+  weight = 1
+}
+
+node {
+  weight = 2
+}
+`)
+	Assert(t, err, NilVal())
+
+	Assert(t, s["node[0].weight"], Equal(1))
+	Assert(t, s["node[1].weight"], Equal(2))
+}
+
+func TestHCLParserOnException(t *testing.T) {
+	s, err := hclParser.Parse(utils.Empty)
+	Assert(t, err, NilVal())
+	Assert(t, s, NilVal())
+	s, err = hclParser.Parse(0)
+	Assert(t, err, NilVal())
+	Assert(t, s, NilVal())
+
+	s, err = hclParser.Parse(`server {`)
+	Assert(t, err, NotNilVal())
+	Assert(t, s, NilVal())
+}
+
+func TestHCLParserConformance(t *testing.T) {
+	conformance.TestContentParser(t, hclParser, []conformance.ParserCase{
+		{Name: "Nested", Content: "a {\n  b = \"c\"\n}", Want: map[string]interface{}{"a.b": "c"}},
+		{Name: "Malformed", Content: "a {", WantErr: true},
+	})
+}
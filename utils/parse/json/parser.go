@@ -0,0 +1,119 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/snailzed/agollo/v4/utils"
+)
+
+// Parser json转换器
+type Parser struct {
+	// KeepSubtree 为 true 时同时保留对象、数组节点本身（如 db、servers），值为原始类型的子树
+	KeepSubtree bool
+}
+
+// Parse 内存内容=>json文件转换器
+// 嵌套对象展开为 a.b.c 形式的 key，数组展开为 a[0] 形式的 key
+// 整数转换为 int，其余数字转换为 float64
+func (d *Parser) Parse(configContent interface{}) (map[string]interface{}, error) {
+	content, ok := configContent.(string)
+	if !ok {
+		return nil, nil
+	}
+	if utils.Empty == content {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewBufferString(content))
+	decoder.UseNumber()
+	var root interface{}
+	if err := decoder.Decode(&root); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("invalid json content: unexpected data after top-level value")
+	}
+
+	switch root.(type) {
+	case map[string]interface{}, []interface{}:
+	default:
+		return nil, fmt.Errorf("invalid json content: top-level value must be an object or array, got %T", root)
+	}
+
+	m := make(map[string]interface{})
+	d.flatten(m, utils.Empty, convertNumber(root))
+	return m, nil
+}
+
+func (d *Parser) flatten(m map[string]interface{}, prefix string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if d.KeepSubtree && prefix != utils.Empty {
+			m[prefix] = v
+		}
+		for key, child := range v {
+			if prefix == utils.Empty {
+				d.flatten(m, key, child)
+			} else {
+				d.flatten(m, prefix+"."+key, child)
+			}
+		}
+	case []interface{}:
+		if d.KeepSubtree && prefix != utils.Empty {
+			m[prefix] = v
+		}
+		for i, child := range v {
+			d.flatten(m, fmt.Sprintf("%s[%d]", prefix, i), child)
+		}
+	default:
+		m[prefix] = v
+	}
+}
+
+// convertNumber 将 json.Number 转换为 int 或 float64
+func convertNumber(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			v[key] = convertNumber(child)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = convertNumber(child)
+		}
+		return v
+	case json.Number:
+		if i, err := v.Int64(); err == nil && int64(int(i)) == i {
+			return int(i)
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return v.String()
+		}
+		return f
+	default:
+		return v
+	}
+}
@@ -0,0 +1,114 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package json
+
+import (
+	"testing"
+
+	"github.com/snailzed/agollo/v4/agollotest/conformance"
+	"github.com/snailzed/agollo/v4/utils"
+	"github.com/snailzed/agollo/v4/utils/parse"
+	. "github.com/tevid/gohamcrest"
+)
+
+var (
+	jsonParser parse.ContentParser = &Parser{}
+)
+
+const testJSON = `{
+  "name": "agollo",
+  "enable": true,
+  "ratio": 0.75,
+  "db": {
+    "port": 3306,
+    "master": {
+      "host": "127.0.0.1"
+    }
+  },
+  "servers": ["a", {"host": "b", "port": 8080}],
+  "nothing": null
+}`
+
+func TestJSONParser(t *testing.T) {
+	s, err := jsonParser.Parse(testJSON)
+	Assert(t, err, NilVal())
+
+	Assert(t, s["name"], Equal("agollo"))
+	Assert(t, s["enable"], Equal(true))
+	Assert(t, s["ratio"], Equal(0.75))
+	Assert(t, s["db.port"], Equal(3306))
+	Assert(t, s["db.master.host"], Equal("127.0.0.1"))
+	Assert(t, s["servers[0]"], Equal("a"))
+	Assert(t, s["servers[1].host"], Equal("b"))
+	Assert(t, s["servers[1].port"], Equal(8080))
+	v, ok := s["nothing"]
+	Assert(t, ok, Equal(true))
+	Assert(t, v, NilVal())
+
+	_, ok = s["db"]
+	Assert(t, ok, Equal(false))
+}
+
+func TestJSONParserKeepSubtree(t *testing.T) {
+	p := &Parser{KeepSubtree: true}
+	s, err := p.Parse(testJSON)
+	Assert(t, err, NilVal())
+
+	Assert(t, s["db.port"], Equal(3306))
+	db := s["db"].(map[string]interface{})
+	Assert(t, db["port"], Equal(3306))
+	servers := s["servers"].([]interface{})
+	Assert(t, len(servers), Equal(2))
+	Assert(t, servers[0], Equal("a"))
+}
+
+func TestJSONParserTopLevelArray(t *testing.T) {
+	s, err := jsonParser.Parse(`[1, {"a": 2.5}]`)
+	Assert(t, err, NilVal())
+	Assert(t, s["[0]"], Equal(1))
+	Assert(t, s["[1].a"], Equal(2.5))
+}
+
+func TestJSONParserOnException(t *testing.T) {
+	s, err := jsonParser.Parse(utils.Empty)
+	Assert(t, err, NilVal())
+	Assert(t, s, NilVal())
+
+	s, err = jsonParser.Parse(0)
+	Assert(t, err, NilVal())
+	Assert(t, s, NilVal())
+
+	s, err = jsonParser.Parse(`{"a":`)
+	Assert(t, err, NotNilVal())
+	Assert(t, s, NilVal())
+
+	s, err = jsonParser.Parse(`"abc"`)
+	Assert(t, err, NotNilVal())
+	Assert(t, s, NilVal())
+
+	s, err = jsonParser.Parse(`{"a":1} {"b":2}`)
+	Assert(t, err, NotNilVal())
+	Assert(t, s, NilVal())
+}
+
+func TestJSONParserConformance(t *testing.T) {
+	conformance.TestContentParser(t, jsonParser, []conformance.ParserCase{
+		{Name: "Nested", Content: `{"a":{"b":1},"c":[true]}`, Want: map[string]interface{}{"a.b": 1, "c[0]": true}},
+		{Name: "Malformed", Content: `{"a":`, WantErr: true},
+	})
+}
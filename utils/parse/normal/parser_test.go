@@ -20,6 +20,7 @@ package normal
 import (
 	"testing"
 
+	"github.com/snailzed/agollo/v4/agollotest/conformance"
 	. "github.com/tevid/gohamcrest"
 )
 
@@ -32,3 +33,7 @@ func TestDefaultParser(t *testing.T) {
 	Assert(t, err, NilVal())
 	Assert(t, s, NilVal())
 }
+
+func TestDefaultParserConformance(t *testing.T) {
+	conformance.TestContentParser(t, defaultParser, nil)
+}
@@ -17,11 +17,180 @@
 
 package properties
 
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/snailzed/agollo/v4/utils"
+)
+
+var (
+	//ErrMalformedUnicode \uXXXX 转义格式错误
+	ErrMalformedUnicode = errors.New("malformed \\uxxxx encoding")
+)
+
 // Parser properties转换器
 type Parser struct {
 }
 
 // Parse 内存内容=>properties文件转换器
+// 按 java.util.Properties 的规则解析：
+// 支持 # ! 注释，= : 空白分隔符，行尾 \ 续行，\uXXXX 及 \t \n \r \f 等转义
 func (d *Parser) Parse(configContent interface{}) (map[string]interface{}, error) {
-	return nil, nil
+	content, ok := configContent.(string)
+	if !ok {
+		return nil, nil
+	}
+	if utils.Empty == content {
+		return nil, nil
+	}
+
+	m := make(map[string]interface{})
+	for _, line := range readLogicalLines(content) {
+		key, value, err := parseLine(line)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = value
+	}
+	return m, nil
+}
+
+func isWhitespace(c rune) bool {
+	return c == ' ' || c == '\t' || c == '\f'
+}
+
+// readLogicalLines 将内容切分为逻辑行，去除空行和注释行，并合并续行
+func readLogicalLines(content string) []string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.ReplaceAll(content, "\r", "\n")
+
+	lines := make([]string, 0)
+	var logical strings.Builder
+	continued := false
+	for _, natural := range strings.Split(content, "\n") {
+		natural = strings.TrimLeftFunc(natural, isWhitespace)
+		if !continued {
+			if natural == utils.Empty || natural[0] == '#' || natural[0] == '!' {
+				continue
+			}
+		}
+
+		// 行尾奇数个 \ 表示续行
+		backslashes := len(natural) - len(strings.TrimRight(natural, "\\"))
+		continued = backslashes%2 == 1
+		if continued {
+			natural = natural[:len(natural)-1]
+		}
+		logical.WriteString(natural)
+
+		if !continued {
+			lines = append(lines, logical.String())
+			logical.Reset()
+		}
+	}
+	if continued {
+		lines = append(lines, logical.String())
+	}
+	return lines
+}
+
+// parseLine 解析逻辑行中的 key 和 value
+func parseLine(line string) (string, string, error) {
+	runes := []rune(line)
+	keyLen := 0
+	valueStart := len(runes)
+	hasSep := false
+	precedingBackslash := false
+	for keyLen < len(runes) {
+		c := runes[keyLen]
+		if (c == '=' || c == ':') && !precedingBackslash {
+			valueStart = keyLen + 1
+			hasSep = true
+			break
+		}
+		if isWhitespace(c) && !precedingBackslash {
+			valueStart = keyLen + 1
+			break
+		}
+		if c == '\\' {
+			precedingBackslash = !precedingBackslash
+		} else {
+			precedingBackslash = false
+		}
+		keyLen++
+	}
+
+	for valueStart < len(runes) {
+		c := runes[valueStart]
+		if !isWhitespace(c) {
+			if hasSep || (c != '=' && c != ':') {
+				break
+			}
+			hasSep = true
+		}
+		valueStart++
+	}
+
+	key, err := unescape(runes[:keyLen])
+	if err != nil {
+		return "", "", err
+	}
+	value, err := unescape(runes[valueStart:])
+	if err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+// unescape 处理转义字符
+func unescape(runes []rune) (string, error) {
+	result := make([]rune, 0, len(runes))
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		if c != '\\' {
+			result = append(result, c)
+			continue
+		}
+		i++
+		if i >= len(runes) {
+			break
+		}
+		c = runes[i]
+		switch c {
+		case 'u':
+			if i+4 >= len(runes) {
+				return "", ErrMalformedUnicode
+			}
+			code, err := strconv.ParseUint(string(runes[i+1:i+5]), 16, 16)
+			if err != nil {
+				return "", ErrMalformedUnicode
+			}
+			r := rune(code)
+			// \uXXXX 为 UTF-16 编码，合并代理对
+			if last := len(result) - 1; last >= 0 && utf16.IsSurrogate(result[last]) {
+				if combined := utf16.DecodeRune(result[last], r); combined != unicode.ReplacementChar {
+					result[last] = combined
+					i += 4
+					continue
+				}
+			}
+			result = append(result, r)
+			i += 4
+		case 't':
+			result = append(result, '\t')
+		case 'r':
+			result = append(result, '\r')
+		case 'n':
+			result = append(result, '\n')
+		case 'f':
+			result = append(result, '\f')
+		default:
+			result = append(result, c)
+		}
+	}
+	return string(result), nil
 }
@@ -20,6 +20,7 @@ package properties
 import (
 	"testing"
 
+	"github.com/snailzed/agollo/v4/agollotest/conformance"
 	. "github.com/tevid/gohamcrest"
 )
 
@@ -30,5 +31,71 @@ var (
 func TestPropertiesParser(t *testing.T) {
 	s, err := propertiesParser.Parse(`aaaa`)
 	Assert(t, err, NilVal())
+	Assert(t, s["aaaa"], Equal(""))
+
+	s, err = propertiesParser.Parse(`# comment
+! another comment
+  a=1
+b : 2
+c    3
+d=
+e
+f==x
+g\=h=i
+j\ k = l \
+      m \
+  n
+p=\u4f60\u597d\t\uD83D\uDE00
+q=a\\
+r=s
+#t=u
+   ! v=w
+`)
+	Assert(t, err, NilVal())
+	Assert(t, len(s), Equal(11))
+	Assert(t, s["a"], Equal("1"))
+	Assert(t, s["b"], Equal("2"))
+	Assert(t, s["c"], Equal("3"))
+	Assert(t, s["d"], Equal(""))
+	Assert(t, s["e"], Equal(""))
+	Assert(t, s["f"], Equal("=x"))
+	Assert(t, s["g=h"], Equal("i"))
+	Assert(t, s["j k"], Equal("l m n"))
+	Assert(t, s["p"], Equal("你好\t😀"))
+	Assert(t, s["q"], Equal("a\\"))
+	Assert(t, s["r"], Equal("s"))
+}
+
+func TestPropertiesParserLineEnding(t *testing.T) {
+	s, err := propertiesParser.Parse("a=1\r\nb=2\rc=3\\\r\n  4")
+	Assert(t, err, NilVal())
+	Assert(t, s["a"], Equal("1"))
+	Assert(t, s["b"], Equal("2"))
+	Assert(t, s["c"], Equal("34"))
+}
+
+func TestPropertiesParserOnException(t *testing.T) {
+	s, err := propertiesParser.Parse("")
+	Assert(t, err, NilVal())
 	Assert(t, s, NilVal())
+
+	s, err = propertiesParser.Parse(0)
+	Assert(t, err, NilVal())
+	Assert(t, s, NilVal())
+
+	s, err = propertiesParser.Parse(`a=\u12`)
+	Assert(t, err, Equal(ErrMalformedUnicode))
+	Assert(t, s, NilVal())
+
+	s, err = propertiesParser.Parse(`a=\uzzzz`)
+	Assert(t, err, Equal(ErrMalformedUnicode))
+	Assert(t, s, NilVal())
+}
+
+func TestPropertiesParserConformance(t *testing.T) {
+	conformance.TestContentParser(t, propertiesParser, []conformance.ParserCase{
+		{Name: "KeyValue", Content: "a=1\nb : 2", Want: map[string]interface{}{"a": "1", "b": "2"}},
+		{Name: "Unicode", Content: "c=\\u4f60\\u597d", Want: map[string]interface{}{"c": "你好"}},
+		{Name: "Malformed", Content: "a=\\u12", WantErr: true},
+	})
 }
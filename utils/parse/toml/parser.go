@@ -0,0 +1,49 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package toml
+
+import (
+	"github.com/pelletier/go-toml"
+	"github.com/snailzed/agollo/v4/utils"
+	"github.com/snailzed/agollo/v4/utils/parse"
+)
+
+// Parser toml转换器
+type Parser struct {
+}
+
+// Parse 内存内容=>toml文件转换器
+// table 展开为 a.b 形式的 key，数组及 [[array table]] 展开为 a[0] 形式的 key，key 保留原始大小写
+func (d *Parser) Parse(configContent interface{}) (map[string]interface{}, error) {
+	content, ok := configContent.(string)
+	if !ok {
+		return nil, nil
+	}
+	if utils.Empty == content {
+		return nil, nil
+	}
+
+	tree, err := toml.Load(content)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]interface{})
+	parse.Flatten(m, utils.Empty, tree.ToMap())
+	return m, nil
+}
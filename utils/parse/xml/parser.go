@@ -0,0 +1,142 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/snailzed/agollo/v4/utils"
+)
+
+// Parser xml转换器
+type Parser struct {
+}
+
+// node xml 元素
+type node struct {
+	name     string
+	attrs    []xml.Attr
+	children []*node
+	text     strings.Builder
+}
+
+// Parse 内存内容=>xml文件转换器
+// 元素展开为 a.b.c 形式的 key，属性展开为 a.b@attr 形式的 key，
+// 同名的兄弟元素展开为 a.b[0]、a.b[1] 形式的 key
+func (d *Parser) Parse(configContent interface{}) (map[string]interface{}, error) {
+	content, ok := configContent.(string)
+	if !ok {
+		return nil, nil
+	}
+	if utils.Empty == content {
+		return nil, nil
+	}
+
+	root, err := parseTree(content)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]interface{})
+	flatten(m, root.name, root)
+	return m, nil
+}
+
+// parseTree 解析 xml 为元素树，返回根元素
+func parseTree(content string) (*node, error) {
+	decoder := xml.NewDecoder(bytes.NewBufferString(content))
+	var root *node
+	stack := make([]*node, 0)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			n := &node{
+				name:  t.Name.Local,
+				attrs: t.Attr,
+			}
+			if len(stack) == 0 {
+				if root != nil {
+					return nil, errors.New("invalid xml content: multiple root elements")
+				}
+				root = n
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, errors.New("invalid xml content: no root element")
+	}
+	return root, nil
+}
+
+func flatten(m map[string]interface{}, path string, n *node) {
+	for _, attr := range n.attrs {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			continue
+		}
+		m[path+"@"+attr.Name.Local] = attr.Value
+	}
+
+	text := strings.TrimSpace(n.text.String())
+	if len(n.children) == 0 {
+		if text != utils.Empty || len(n.attrs) == 0 {
+			m[path] = text
+		}
+		return
+	}
+	if text != utils.Empty {
+		m[path] = text
+	}
+
+	counts := make(map[string]int, len(n.children))
+	for _, child := range n.children {
+		counts[child.name]++
+	}
+	indexes := make(map[string]int, len(counts))
+	for _, child := range n.children {
+		childPath := path + "." + child.name
+		if counts[child.name] > 1 {
+			childPath = fmt.Sprintf("%s[%d]", childPath, indexes[child.name])
+			indexes[child.name]++
+		}
+		flatten(m, childPath, child)
+	}
+}
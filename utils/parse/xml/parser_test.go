@@ -0,0 +1,94 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xml
+
+import (
+	"testing"
+
+	"github.com/snailzed/agollo/v4/agollotest/conformance"
+	"github.com/snailzed/agollo/v4/utils"
+	"github.com/snailzed/agollo/v4/utils/parse"
+	. "github.com/tevid/gohamcrest"
+)
+
+var (
+	xmlParser parse.ContentParser = &Parser{}
+)
+
+func TestXMLParser(t *testing.T) {
+	s, err := xmlParser.Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!-- comment -->
+<server host="127.0.0.1" xmlns="http://example.com">
+    <port>8080</port>
+    <name>  agollo  </name>
+    <empty/>
+    <tls enable="true"/>
+    <node id="1">a</node>
+    <node id="2">
+        <weight>10</weight>
+    </node>
+    <node>c</node>
+</server>`)
+	Assert(t, err, NilVal())
+
+	Assert(t, s["server@host"], Equal("127.0.0.1"))
+	Assert(t, s["server.port"], Equal("8080"))
+	Assert(t, s["server.name"], Equal("agollo"))
+	Assert(t, s["server.empty"], Equal(""))
+	Assert(t, s["server.tls@enable"], Equal("true"))
+	Assert(t, s["server.node[0]"], Equal("a"))
+	Assert(t, s["server.node[0]@id"], Equal("1"))
+	Assert(t, s["server.node[1]@id"], Equal("2"))
+	Assert(t, s["server.node[1].weight"], Equal("10"))
+	Assert(t, s["server.node[2]"], Equal("c"))
+
+	_, ok := s["server@xmlns"]
+	Assert(t, ok, Equal(false))
+	_, ok = s["server.tls"]
+	Assert(t, ok, Equal(false))
+	Assert(t, len(s), Equal(10))
+}
+
+func TestXMLParserOnException(t *testing.T) {
+	s, err := xmlParser.Parse(utils.Empty)
+	Assert(t, err, NilVal())
+	Assert(t, s, NilVal())
+
+	s, err = xmlParser.Parse(0)
+	Assert(t, err, NilVal())
+	Assert(t, s, NilVal())
+
+	s, err = xmlParser.Parse(`<a><b></a>`)
+	Assert(t, err, NotNilVal())
+	Assert(t, s, NilVal())
+
+	s, err = xmlParser.Parse(`<a/><b/>`)
+	Assert(t, err, NotNilVal())
+	Assert(t, s, NilVal())
+
+	s, err = xmlParser.Parse(`just text`)
+	Assert(t, err, NotNilVal())
+	Assert(t, s, NilVal())
+}
+
+func TestXMLParserConformance(t *testing.T) {
+	conformance.TestContentParser(t, xmlParser, []conformance.ParserCase{
+		{Name: "Nested", Content: `<a><b>1</b><c d="2"/></a>`, Want: map[string]interface{}{"a.b": "1", "a.c@d": "2"}},
+		{Name: "Malformed", Content: `<a><b></a>`, WantErr: true},
+	})
+}
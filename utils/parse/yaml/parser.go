@@ -19,22 +19,39 @@ package yaml
 
 import (
 	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
 	"github.com/snailzed/agollo/v4/utils"
-	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
 )
 
-var vp = viper.New()
-
-func init() {
-	vp.SetConfigType("yaml")
-}
-
-// Parser properties转换器
+// Parser yaml转换器，可并发使用
 type Parser struct {
+	// KeepSubtree 为 true 时同时保留映射、列表节点本身（如 db、servers），值为原始类型的子树
+	KeepSubtree bool
 }
 
-// Parse 内存内容=>yml文件转换器
+// Parse 内存内容=>yaml文件转换器
+// 嵌套映射展开为 a.b.c 形式的 key，列表展开为 a[0] 形式的 key，key 保留原始大小写
+// 多文档（---）按顺序合并，后面的文档覆盖前面的同名 key
 func (d *Parser) Parse(configContent interface{}) (map[string]interface{}, error) {
+	tree, err := d.ParseTree(configContent)
+	if err != nil || tree == nil {
+		return nil, err
+	}
+
+	m := make(map[string]interface{})
+	d.flatten(m, utils.Empty, tree)
+	return m, nil
+}
+
+// ParseTree 内存内容=>yaml嵌套结构，保留 key 在文件中的顺序
+// 映射转换为 key 为 string 的 yaml.MapSlice，列表转换为 []interface{}，标量保留 int、float64、bool、time.Time 等类型
+// 多文档合并时已有的 key 保持原来的位置，新的 key 追加在后面
+func (d *Parser) ParseTree(configContent interface{}) (yaml.MapSlice, error) {
 	content, ok := configContent.(string)
 	if !ok {
 		return nil, nil
@@ -43,24 +60,184 @@ func (d *Parser) Parse(configContent interface{}) (map[string]interface{}, error
 		return nil, nil
 	}
 
-	buffer := bytes.NewBufferString(content)
-	// 使用viper解析
-	err := vp.ReadConfig(buffer)
-	if err != nil {
-		return nil, err
+	tree := yaml.MapSlice{}
+	decoder := yaml.NewDecoder(bytes.NewBufferString(content))
+	for i := 0; ; i++ {
+		var n node
+		err := decoder.Decode(&n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch v := n.value.(type) {
+		case nil:
+		case yaml.MapSlice:
+			tree = merge(tree, v)
+		default:
+			return nil, fmt.Errorf("invalid yaml content: document %d must be a mapping, got %T", i, v)
+		}
 	}
+	return tree, nil
+}
 
-	return convertToMap(vp), nil
+// ToMap 将 ParseTree 返回的有序结构转换为 map[string]interface{}，列表中的映射同样转换
+func ToMap(tree yaml.MapSlice) map[string]interface{} {
+	m := make(map[string]interface{}, len(tree))
+	for _, item := range tree {
+		m[item.Key.(string)] = toValue(item.Value)
+	}
+	return m
 }
 
-func convertToMap(vp *viper.Viper) map[string]interface{} {
-	if vp == nil {
-		return nil
+func toValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		return ToMap(v)
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, child := range v {
+			l[i] = toValue(child)
+		}
+		return l
 	}
+	return value
+}
 
-	m := make(map[string]interface{})
-	for _, key := range vp.AllKeys() {
-		m[key] = vp.Get(key)
+func (d *Parser) flatten(m map[string]interface{}, prefix string, value interface{}) {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		if d.KeepSubtree && prefix != utils.Empty {
+			m[prefix] = ToMap(v)
+		}
+		for _, item := range v {
+			key := item.Key.(string)
+			if prefix == utils.Empty {
+				d.flatten(m, key, item.Value)
+			} else {
+				d.flatten(m, prefix+"."+key, item.Value)
+			}
+		}
+	case []interface{}:
+		if d.KeepSubtree && prefix != utils.Empty {
+			m[prefix] = toValue(v)
+		}
+		for i, child := range v {
+			d.flatten(m, fmt.Sprintf("%s[%d]", prefix, i), child)
+		}
+	default:
+		m[prefix] = v
 	}
-	return m
+}
+
+// merge 将 src 深度合并到 dst 并返回合并结果，同名的映射递归合并，其余类型直接覆盖
+func merge(dst, src yaml.MapSlice) yaml.MapSlice {
+	for _, item := range src {
+		i := indexOf(dst, item.Key)
+		if i < 0 {
+			dst = append(dst, item)
+			continue
+		}
+		srcMap, srcOk := item.Value.(yaml.MapSlice)
+		dstMap, dstOk := dst[i].Value.(yaml.MapSlice)
+		if srcOk && dstOk {
+			dst[i].Value = merge(dstMap, srcMap)
+			continue
+		}
+		dst[i].Value = item.Value
+	}
+	return dst
+}
+
+func indexOf(tree yaml.MapSlice, key interface{}) int {
+	for i, item := range tree {
+		if item.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// node yaml节点，解码时保留标量类型及映射 key 的顺序，并将映射的 key 统一转换为 string
+type node struct {
+	value interface{}
+}
+
+// UnmarshalYAML 实现 yaml.Unmarshaler
+func (n *node) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw interface{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	switch v := raw.(type) {
+	case map[interface{}]interface{}:
+		var children map[interface{}]*node
+		if err := unmarshal(&children); err != nil {
+			return err
+		}
+		// MapSlice 只用于获取 key 的顺序，值以解码为 node 的结果为准
+		// yaml.v2 解码 MapSlice 时会丢失 << 合并进来的 key，这些 key 按名称排序放在最前面
+		var keys yaml.MapSlice
+		if err := unmarshal(&keys); err != nil {
+			return err
+		}
+		ordered := make([]interface{}, 0, len(children))
+		seen := make(map[interface{}]bool, len(keys))
+		for _, item := range keys {
+			if _, ok := children[item.Key]; ok && !seen[item.Key] {
+				seen[item.Key] = true
+				ordered = append(ordered, item.Key)
+			}
+		}
+		merged := make([]interface{}, 0, len(children)-len(ordered))
+		for key := range children {
+			if !seen[key] {
+				merged = append(merged, key)
+			}
+		}
+		sort.Slice(merged, func(i, j int) bool {
+			return fmt.Sprint(merged[i]) < fmt.Sprint(merged[j])
+		})
+
+		m := make(yaml.MapSlice, 0, len(children))
+		for _, key := range append(merged, ordered...) {
+			name := fmt.Sprint(key)
+			if indexOf(m, name) >= 0 {
+				continue
+			}
+			m = append(m, yaml.MapItem{Key: name, Value: children[key].get()})
+		}
+		n.value = m
+	case []interface{}:
+		var children []*node
+		if err := unmarshal(&children); err != nil {
+			return err
+		}
+		l := make([]interface{}, len(children))
+		for i, child := range children {
+			l[i] = child.get()
+		}
+		n.value = l
+	case string:
+		// yaml.v2 为了兼容会把时间解析为 string，未加引号的时间在此还原为 time.Time
+		var t time.Time
+		if err := unmarshal(&t); err == nil {
+			n.value = t
+			return nil
+		}
+		n.value = v
+	default:
+		n.value = v
+	}
+	return nil
+}
+
+func (n *node) get() interface{} {
+	if n == nil {
+		return nil
+	}
+	return n.value
 }
@@ -18,11 +18,17 @@
 package yaml
 
 import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/snailzed/agollo/v4/agollotest/conformance"
 	"github.com/snailzed/agollo/v4/utils"
 	"github.com/snailzed/agollo/v4/utils/parse"
-	"testing"
 
 	. "github.com/tevid/gohamcrest"
+	"gopkg.in/yaml.v2"
 )
 
 var (
@@ -59,6 +65,134 @@ func TestYAMLParserOnException(t *testing.T) {
 	Assert(t, err, NilVal())
 	Assert(t, s, NilVal())
 
-	m := convertToMap(nil)
-	Assert(t, m, NilVal())
+	s, err = yamlParser.Parse("a: [b")
+	Assert(t, err, NotNilVal())
+	Assert(t, s, NilVal())
+}
+
+func TestYAMLParserTypes(t *testing.T) {
+	s, err := yamlParser.Parse(`
+Server:
+    Port: 8080
+    Ratio: 0.5
+    Enable: true
+    Name: agollo
+    Quoted: "2001-12-14"
+    Created: 2001-12-14T21:59:43.10Z
+    Empty:
+Nodes:
+    - 127.0.0.1
+    - host: 127.0.0.2
+      weight: 2
+`)
+	Assert(t, err, NilVal())
+
+	Assert(t, s["Server.Port"], Equal(8080))
+	Assert(t, s["Server.Ratio"], Equal(0.5))
+	Assert(t, s["Server.Enable"], Equal(true))
+	Assert(t, s["Server.Name"], Equal("agollo"))
+	Assert(t, s["Server.Quoted"], Equal("2001-12-14"))
+	Assert(t, s["Server.Created"], Equal(time.Date(2001, 12, 14, 21, 59, 43, 100000000, time.UTC)))
+	Assert(t, s["Server.Empty"], NilVal())
+	Assert(t, s["Nodes[0]"], Equal("127.0.0.1"))
+	Assert(t, s["Nodes[1].host"], Equal("127.0.0.2"))
+	Assert(t, s["Nodes[1].weight"], Equal(2))
+
+	_, ok := s["server.port"]
+	Assert(t, ok, Equal(false))
+	_, ok = s["Server"]
+	Assert(t, ok, Equal(false))
+}
+
+func TestYAMLParserAnchorAndMultiDocument(t *testing.T) {
+	s, err := yamlParser.Parse(`
+base: &base
+    timeout: 3
+    retry: 1
+db:
+    <<: *base
+    retry: 5
+alias: *base
+---
+db:
+    host: 127.0.0.1
+extra: 1
+`)
+	Assert(t, err, NilVal())
+
+	Assert(t, s["db.timeout"], Equal(3))
+	Assert(t, s["db.retry"], Equal(5))
+	Assert(t, s["db.host"], Equal("127.0.0.1"))
+	Assert(t, s["alias.timeout"], Equal(3))
+	Assert(t, s["extra"], Equal(1))
+
+	s, err = yamlParser.Parse("a: 1\n---\n- b\n")
+	Assert(t, err, NotNilVal())
+	Assert(t, s, NilVal())
+}
+
+func TestYAMLParserKeepSubtree(t *testing.T) {
+	parser := &Parser{KeepSubtree: true}
+	s, err := parser.Parse(`
+db:
+    host: 127.0.0.1
+    ports: [1, 2]
+`)
+	Assert(t, err, NilVal())
+
+	db, ok := s["db"].(map[string]interface{})
+	Assert(t, ok, Equal(true))
+	Assert(t, db["host"], Equal("127.0.0.1"))
+	Assert(t, s["db.ports"], Equal([]interface{}{1, 2}))
+	Assert(t, s["db.ports[1]"], Equal(2))
+}
+
+func TestYAMLParserParseTree(t *testing.T) {
+	tree, err := (&Parser{}).ParseTree(`
+zeta: 1
+db:
+    port: 3306
+    host: 127.0.0.1
+alpha: [{b: 1, a: 2}]
+---
+db:
+    host: localhost
+    user: root
+beta: true
+`)
+	Assert(t, err, NilVal())
+	Assert(t, tree, Equal(yaml.MapSlice{
+		{Key: "zeta", Value: 1},
+		{Key: "db", Value: yaml.MapSlice{
+			{Key: "port", Value: 3306},
+			{Key: "host", Value: "localhost"},
+			{Key: "user", Value: "root"},
+		}},
+		{Key: "alpha", Value: []interface{}{yaml.MapSlice{{Key: "b", Value: 1}, {Key: "a", Value: 2}}}},
+		{Key: "beta", Value: true},
+	}))
+
+	Assert(t, ToMap(tree)["alpha"], Equal([]interface{}{map[string]interface{}{"a": 2, "b": 1}}))
+}
+
+func TestYAMLParserConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s, err := yamlParser.Parse(fmt.Sprintf("key%d: %d", i, i))
+			Assert(t, err, NilVal())
+			Assert(t, len(s), Equal(1))
+			Assert(t, s[fmt.Sprintf("key%d", i)], Equal(i))
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestYAMLParserConformance(t *testing.T) {
+	conformance.TestContentParser(t, yamlParser, []conformance.ParserCase{
+		{Name: "Nested", Content: "a:\n  b: 1\nc: [true]", Want: map[string]interface{}{"a.b": 1, "c[0]": true}},
+		{Name: "Malformed", Content: "a: [b", WantErr: true},
+	})
 }
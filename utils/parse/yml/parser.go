@@ -18,49 +18,8 @@
 package yml
 
 import (
-	"bytes"
-	"github.com/snailzed/agollo/v4/utils"
-	"github.com/spf13/viper"
+	"github.com/snailzed/agollo/v4/utils/parse/yaml"
 )
 
-var vp = viper.New()
-
-func init() {
-	vp.SetConfigType("yml")
-}
-
-// Parser properties转换器
-type Parser struct {
-}
-
-// Parse 内存内容=>yml文件转换器
-func (d *Parser) Parse(configContent interface{}) (map[string]interface{}, error) {
-	content, ok := configContent.(string)
-	if !ok {
-		return nil, nil
-	}
-	if utils.Empty == content {
-		return nil, nil
-	}
-
-	buffer := bytes.NewBufferString(content)
-	// 使用viper解析
-	err := vp.ReadConfig(buffer)
-	if err != nil {
-		return nil, err
-	}
-
-	return convertToMap(vp), nil
-}
-
-func convertToMap(vp *viper.Viper) map[string]interface{} {
-	if vp == nil {
-		return nil
-	}
-
-	m := make(map[string]interface{})
-	for _, key := range vp.AllKeys() {
-		m[key] = vp.Get(key)
-	}
-	return m
-}
+// Parser yml转换器，与 yaml 转换器一致
+type Parser = yaml.Parser
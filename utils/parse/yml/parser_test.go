@@ -18,6 +18,7 @@
 package yml
 
 import (
+	"github.com/snailzed/agollo/v4/agollotest/conformance"
 	"github.com/snailzed/agollo/v4/utils"
 	"github.com/snailzed/agollo/v4/utils/parse"
 	"testing"
@@ -58,6 +59,14 @@ func TestYMLParserOnException(t *testing.T) {
 	Assert(t, err, NilVal())
 	Assert(t, s, NilVal())
 
-	m := convertToMap(nil)
-	Assert(t, m, NilVal())
+	s, err = ymlParser.Parse("a: [b")
+	Assert(t, err, NotNilVal())
+	Assert(t, s, NilVal())
+}
+
+func TestYMLParserConformance(t *testing.T) {
+	conformance.TestContentParser(t, ymlParser, []conformance.ParserCase{
+		{Name: "Nested", Content: "a:\n  b: 1", Want: map[string]interface{}{"a.b": 1}},
+		{Name: "Malformed", Content: "a: [b", WantErr: true},
+	})
 }
@@ -0,0 +1,117 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agollo
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/snailzed/agollo/v4/storage"
+)
+
+//Snapshot Watch 返回的结构体快照，namespace 变更时重新绑定并原子替换
+type Snapshot struct {
+	client    *internalClient
+	namespace string
+	typ       reflect.Type
+	value     atomic.Value
+	lock      sync.Mutex
+	listener  *snapshotListener
+}
+
+//Load 获取最新一次绑定成功的结构体指针，类型与 Watch 传入的 target 一致
+//返回值只读，多次 Load 之间可能返回不同的实例，同一实例内的字段总是来自同一次变更
+func (s *Snapshot) Load() interface{} {
+	return s.value.Load()
+}
+
+//Stop 停止监听 namespace 变更，Load 继续返回最后一次绑定的结果
+func (s *Snapshot) Stop() {
+	s.client.RemoveChangeListener(s.listener)
+}
+
+//rebind 绑定到新的实例，成功后替换快照，失败时保留旧的快照
+//Bind 在配置的更新锁内复制全部配置，不会读到只更新了部分 key 的中间状态
+func (s *Snapshot) rebind() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	config := s.client.GetConfig(s.namespace)
+	if config == nil {
+		return fmt.Errorf("bind fail ! namespace:%s is not exist", s.namespace)
+	}
+	target := reflect.New(s.typ)
+	if err := config.Bind(target.Interface()); err != nil {
+		return err
+	}
+	s.value.Store(target.Interface())
+	return nil
+}
+
+//snapshotListener 监听 namespace 的 ChangeEvent 并重新绑定快照
+type snapshotListener struct {
+	snapshot *Snapshot
+}
+
+//OnChange 增加变更监控
+func (l *snapshotListener) OnChange(event *storage.ChangeEvent) {
+	if event == nil || event.Namespace != l.snapshot.namespace {
+		return
+	}
+	if err := l.snapshot.rebind(); err != nil {
+		l.snapshot.client.logger().Errorf("rebind namespace %s fail, keep the previous snapshot, error:%v", l.snapshot.namespace, err)
+	}
+}
+
+//OnNewestChange 监控最新变更
+func (l *snapshotListener) OnNewestChange(event *storage.FullChangeEvent) {
+}
+
+//Watch 将 namespace 绑定到 target 并在配置变更时自动重新绑定，tag 规则见 storage.Config.Bind
+//target 必须为结构体指针，仅用于首次绑定及确定类型，之后的变更通过返回的 Snapshot.Load 获取
+func (c *internalClient) Watch(namespace string, target interface{}) (*Snapshot, error) {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("watch fail ! target must be a non-nil pointer to struct, got %T", target)
+	}
+
+	config := c.GetConfig(namespace)
+	if config == nil {
+		return nil, fmt.Errorf("watch fail ! namespace:%s is not exist", namespace)
+	}
+
+	s := &Snapshot{
+		client:    c,
+		namespace: namespace,
+		typ:       rv.Elem().Type(),
+	}
+	s.listener = &snapshotListener{snapshot: s}
+	// 先注册监听再首次绑定，避免遗漏两者之间发生的变更
+	c.AddChangeListener(s.listener)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := config.Bind(target); err != nil {
+		c.RemoveChangeListener(s.listener)
+		return nil, err
+	}
+	s.value.Store(target)
+	return s, nil
+}